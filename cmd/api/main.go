@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	handler "metertronik/internal/handler/api"
+	wsHandler "metertronik/internal/handler/ws"
 	"metertronik/internal/middleware"
 	httpRouter "metertronik/internal/router/http"
 	wsRouter "metertronik/internal/router/websocket"
+	aggregate "metertronik/internal/service"
 	service "metertronik/internal/service/http"
 	"metertronik/pkg/config"
 	"metertronik/pkg/database"
 	redisDB "metertronik/pkg/database/redis"
+	"metertronik/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,24 +37,67 @@ func main() {
 	redisAuthRepo, cleanupRedisAuth := redisDB.SetupRedisAuth(cfg)
 	defer cleanupRedisAuth()
 
-	api := service.NewApiService(postgresRepo, redisBatchRepo)
+	electricitySubscriber, cleanupSubscriber := redisDB.SetupRedisElectricitySubscriber(cfg)
+	defer cleanupSubscriber()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var hub *wsHandler.Hub
+	if electricitySubscriber != nil {
+		hub = wsHandler.NewHub(electricitySubscriber)
+		go hub.Run(ctx)
+	}
+
+	influxRepo, cleanupInflux := database.SetupInfluxReader(cfg)
+	defer cleanupInflux()
+
+	deviceRepo := database.SetupDeviceRepo(cfg)
+	deviceCache := aggregate.NewDeviceCache(deviceRepo, 0, cfg.DefaultTimezone)
+
+	api := service.NewApiService(postgresRepo, redisBatchRepo, influxRepo, redisRealtimeRepo, deviceCache, cfg.WSHistoryWindow, cfg.RealtimeHistoryRetention)
 	apiHandler := handler.NewApiHandler(api)
 
 	authService := service.NewAuthService(usersRepo, redisAuthRepo)
 	authHandler := handler.NewAuthHandler(authService)
 
+	keyBox, err := utils.NewSecretBox(cfg.IngestKeyEncryptionKey)
+	if err != nil {
+		log.Printf("Ingest key encryption not configured, key rotation disabled: %v", err)
+	}
+	ingestKeyRevocations, cleanupRevocations := redisDB.SetupRedisIngestKeyRevocation(cfg)
+	defer cleanupRevocations()
+
+	ingestKeys := aggregate.NewIngestKeyService(database.SetupDeviceIngestKeyRepo(cfg), ingestKeyRevocations, keyBox, cfg.IngestKeyGrace)
+
+	eventHandler := handler.NewEventHandler(service.NewEventService(database.SetupSurgeEventRepo(cfg), deviceCache))
+
+	policyService := service.NewThresholdPolicyService(database.SetupThresholdPolicyRepo(cfg))
+	policyHandler := handler.NewThresholdPolicyHandler(policyService)
+
+	deviceService := service.NewDeviceService(deviceRepo, database.SetupDeviceMemberRepo(cfg), database.SetupDeviceCredentialRepo(cfg), usersRepo, ingestKeys, deviceCache, policyService)
+	authorizer := aggregate.NewDeviceAuthorizer(deviceRepo, database.SetupAuditRepo(cfg))
+	deviceHandler := handler.NewDeviceHandler(deviceService)
+
 	gin.SetMode(cfg.GinMode)
 	router := gin.Default()
 
+	// X-Forwarded-For hanya dipercaya dari proxy di TRUSTED_PROXIES agar IP
+	// di audit log tidak bisa dipalsukan client.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(middleware.CORSMiddleware(cfg))
 
-	httpRouter.SetupRoutes(router, apiHandler, authHandler)
+	httpRouter.SetupRoutes(router, apiHandler, authHandler, deviceHandler, policyHandler, eventHandler, authorizer)
 
-	wsRouter.WebSocketRoutes(router, redisRealtimeRepo)
+	wsRouter.WebSocketRoutes(router, redisRealtimeRepo, hub, authorizer, cfg.CORSAllowOrigins, cfg.WSHistoryWindow, cfg.WSAccessRecheck)
 
 	log.Printf("API server started on port %s", cfg.Port)
 	log.Printf("HTTP API endpoint: http://localhost:%s/v1/api", cfg.Port)
 	log.Printf("WebSocket endpoint: ws://localhost:%s/v1/ws/electricity/:deviceID", cfg.Port)
+	log.Printf("WebSocket stream endpoint: ws://localhost:%s/v1/ws/stream", cfg.Port)
 
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
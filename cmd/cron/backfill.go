@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"metertronik/internal/service"
+	"metertronik/pkg/config"
+	"metertronik/pkg/database"
+	"metertronik/pkg/utils"
+)
+
+const backfillUsage = `Usage:
+  cron backfill (-devices id1,id2 | -all) -start DATE -end DATE -level hourly|daily|monthly|yearly [-concurrency N] [-dry-run]
+
+DATE is YYYY-MM-DD or RFC3339 (UTC). The range is [start, end).`
+
+func runBackfill(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	fs.Usage = func() { fmt.Println(backfillUsage) }
+	devicesFlag := fs.String("devices", "", "comma separated device IDs")
+	all := fs.Bool("all", false, "backfill every registered device")
+	startFlag := fs.String("start", "", "start of the range (inclusive)")
+	endFlag := fs.String("end", "", "end of the range (exclusive)")
+	level := fs.String("level", "", "aggregation level: hourly, daily, monthly or yearly")
+	concurrency := fs.Int("concurrency", 4, "maximum number of aggregations running at once")
+	dryRun := fs.Bool("dry-run", false, "only print what would be recomputed")
+	fs.Parse(args)
+
+	start, err := utils.ParseDate(*startFlag)
+	if err != nil {
+		log.Fatalf("Invalid -start: %v", err)
+	}
+	end, err := utils.ParseDate(*endFlag)
+	if err != nil {
+		log.Fatalf("Invalid -end: %v", err)
+	}
+
+	if *all == (*devicesFlag != "") {
+		fmt.Println(backfillUsage)
+		os.Exit(2)
+	}
+
+	influxRepo, cleanupInflux := database.SetupInfluxReader(cfg)
+	defer cleanupInflux()
+
+	postgresRepo, _, cleanupPostgres := database.SetupPostgres(cfg)
+	defer cleanupPostgres()
+
+	deviceCache := service.NewDeviceCache(database.SetupDeviceRepo(cfg), 0, cfg.DefaultTimezone)
+	cronSvc := service.NewCronService(influxRepo, postgresRepo, database.SetupAggregationJobRepo(cfg), deviceCache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		sig := <-utils.SetupSignalChannel()
+		log.Println("[BACKFILL] Interrupted:", sig)
+		cancel()
+	}()
+
+	var deviceIDs []string
+	if *all {
+		deviceIDs, err = database.SetupDeviceRepo(cfg).ListDeviceIDs(ctx, "")
+		if err != nil {
+			log.Fatalf("Failed to list devices: %v", err)
+		}
+	} else {
+		for _, id := range strings.Split(*devicesFlag, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				deviceIDs = append(deviceIDs, id)
+			}
+		}
+	}
+
+	if len(deviceIDs) == 0 {
+		log.Fatalf("No devices to backfill")
+	}
+
+	req := service.BackfillRequest{
+		DeviceIDs:   deviceIDs,
+		Level:       *level,
+		Start:       start.Time,
+		End:         end.Time,
+		Concurrency: *concurrency,
+		DryRun:      *dryRun,
+	}
+
+	// Partisi biasanya hanya dibuat dari tahun berjalan; pastikan tahun-tahun
+	// yang di-backfill sudah punya partisi. Rentang dilebarkan satu hari
+	// karena zona waktu device bisa menggeser data ke tahun UTC sebelahnya.
+	if !req.DryRun {
+		partitionSvc := service.NewPartitionService(database.SetupPartitionRepo(cfg), service.DefaultPartitionSpecs)
+		fromYear := start.Time.Add(-24 * time.Hour).UTC().Year()
+		toYear := end.Time.Add(24 * time.Hour).UTC().Year()
+		if _, err := partitionSvc.EnsureYears(ctx, fromYear, toYear); err != nil {
+			log.Fatalf("[BACKFILL] Failed to ensure partitions for %d-%d: %v", fromYear, toYear, err)
+		}
+	}
+
+	log.Printf("[BACKFILL] %s aggregation for %d device(s) in [%s, %s) dry-run=%v",
+		req.Level, len(deviceIDs), start.Format(), end.Format(), req.DryRun)
+
+	began := time.Now()
+	failed, err := cronSvc.Backfill(ctx, req, func(p service.BackfillProgress) {
+		status := "ok"
+		switch {
+		case req.DryRun:
+			status = "planned"
+		case p.Err != nil:
+			status = "error: " + p.Err.Error()
+		}
+		log.Printf("[BACKFILL] %d/%d %s %s %s %s", p.Done, p.Total, p.Job.Level, p.Job.DeviceID,
+			utils.NewTimeData(p.Job.Period).Format(), status)
+	})
+	if err != nil {
+		log.Fatalf("[BACKFILL] Stopped: %v", err)
+	}
+
+	log.Printf("[BACKFILL] Finished in %s, %d failed", time.Since(began).Round(time.Second), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"time"
 
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
 	"metertronik/internal/service"
 	"metertronik/pkg/config"
 	"metertronik/pkg/database"
+	redisDB "metertronik/pkg/database/redis"
+	"metertronik/pkg/metrics"
 	"metertronik/pkg/utils"
 )
 
@@ -17,27 +22,59 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	influxRepo, cleanupInflux := database.SetupInfluxDB(cfg)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "partitions":
+			runPartitions(cfg, os.Args[2:])
+			return
+		case "backfill":
+			runBackfill(cfg, os.Args[2:])
+			return
+		case "provision":
+			runProvision(cfg, os.Args[2:])
+			return
+		}
+	}
+
+	influxRepo, cleanupInflux := database.SetupInfluxReader(cfg)
 	defer cleanupInflux()
 
 	postgresRepo, _, cleanupPostgres := database.SetupPostgres(cfg)
 	defer cleanupPostgres()
 
-	cronSvc := service.NewCronService(influxRepo, postgresRepo)
+	deviceRepo := database.SetupDeviceRepo(cfg)
+	deviceCache := service.NewDeviceCache(deviceRepo, 0, cfg.DefaultTimezone)
+	cronSvc := service.NewCronService(influxRepo, postgresRepo, database.SetupAggregationJobRepo(cfg), deviceCache)
+	partitionSvc := service.NewPartitionService(database.SetupPartitionRepo(cfg), service.DefaultPartitionSpecs)
+	surgeEventRepo := database.SetupSurgeEventRepo(cfg)
+
+	leaderLease, cleanupLease := redisDB.SetupRedisLeaderLease(cfg)
+	defer cleanupLease()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	metrics.Serve(cfg.CronMetricsAddr)
+
+	// Hanya leader yang mengagregasi; replika lain menunggu sampai lease
+	// leader habis lalu mengambil alih.
+	elector := service.NewLeaderElector(leaderLease, instanceID(cfg.CronInstanceID), cfg.CronLeaderTTL)
+	elector.Start(ctx)
+	defer elector.Stop()
+
 	sigChan := utils.SetupSignalChannel()
 
 	now := utils.TimeNow()
 
 	nextHour := now.Truncate(time.Hour).Add(time.Hour)
 
-	nextDay := now.StartOfDay().Add(24 * time.Hour)
+	// Daily dijalankan per device setelah tengah malam lokalnya; log hanya
+	// menampilkan jadwal untuk zona waktu default.
+	defaultLoc := utils.LoadLocation(cfg.DefaultTimezone)
+	nextDay := now.DateIn(defaultLoc).AddDays(1).StartIn(defaultLoc)
 
 	log.Printf("[INIT] Next hourly aggregation in %s\n", utils.TimeUntil(nextHour).Round(time.Minute))
-	log.Printf("[INIT] Next daily aggregation in %s\n", utils.TimeUntil(nextDay).Round(time.Minute))
+	log.Printf("[INIT] Next daily aggregation (%s) in %s\n", defaultLoc, utils.TimeUntil(nextDay).Round(time.Minute))
 
 	hourlyTimer := time.NewTimer(utils.TimeUntil(nextHour))
 
@@ -54,83 +91,81 @@ func main() {
 		}
 	}()
 
-	activeDevices, err := influxRepo.GetActiveDeviceIDs(ctx, 48)
-	if err != nil {
-		log.Printf("[WARNING] Failed to get active device IDs: %v", err)
-		activeDevices = []string{"device-001"}
-	}
+	// Hanya device terdaftar dengan status active yang diagregasi.
+	activeDevices := registeredDevices(ctx, deviceRepo, nil)
 	log.Printf("[INIT] Active devices found: %v", activeDevices)
+
+	catchUpCfg := service.CatchUpConfig{
+		Lookback:    cfg.CronCatchUpWindow,
+		MaxAttempts: cfg.CronMaxAttempts,
+	}
+
 	log.Println("[INIT] Cron service started")
 
 	for {
 		select {
 
+		case leader := <-elector.Changes():
+			if !leader {
+				log.Println("[LEADER] Standing by, aggregation paused")
+				continue
+			}
+
+			// Catch-up mengejar periode yang terlewat selama instance ini
+			// belum menjadi leader (termasuk saat startup).
+			leaderCtx := elector.Context()
+			maintainPartitions(leaderCtx, partitionSvc, cfg)
+
+			log.Printf("[CATCHUP] Catching up missing aggregations for the last %s", cfg.CronCatchUpWindow)
+			cronSvc.CatchUp(leaderCtx, activeDevices, utils.TimeNow(), catchUpCfg)
+
 		case <-reminderTicker.C:
 			now := utils.TimeNow()
 
-			devices, err := influxRepo.GetActiveDeviceIDs(ctx, 48)
-			if err == nil && len(devices) > 0 {
-				activeDevices = devices
-				log.Printf("[REMINDER] Active devices updated: %v", activeDevices)
-			}
+			activeDevices = registeredDevices(ctx, deviceRepo, activeDevices)
 
 			nextHourly := now.Truncate(time.Hour).Add(time.Hour)
 
-			var nextDaily utils.TimeData
-			if now.Time.Hour() < 24 {
-				nextDaily = now.StartOfDay().Add(24 * time.Hour)
-			}
+			nextDaily := now.DateIn(defaultLoc).AddDays(1).StartIn(defaultLoc)
 
 			log.Printf(
-				"[REMINDER] Hourly in %s | Daily in %s (UTC) | Active devices: %d\n",
+				"[REMINDER] Hourly in %s | Daily in %s (%s) | Active devices: %d | Leader: %t\n",
 				utils.TimeUntil(nextHourly).Round(time.Minute),
 				utils.TimeUntil(nextDaily).Round(time.Minute),
+				defaultLoc,
 				len(activeDevices),
+				elector.IsLeader(),
 			)
 
+			if elector.IsLeader() {
+				closeStaleSurgeEvents(elector.Context(), surgeEventRepo, cfg.SurgeMaxGap)
+			}
+
 		case <-hourlyC:
 			now := utils.TimeNow()
 
-			devices, err := influxRepo.GetActiveDeviceIDs(ctx, 48)
-			if err == nil && len(devices) > 0 {
-				activeDevices = devices
+			if hourlyTicker == nil {
+				hourlyTicker = time.NewTicker(time.Hour)
+				hourlyC = hourlyTicker.C
 			}
 
-			targetHour := now.
-				Add(-time.Hour).
-				Truncate(time.Hour)
-
-			log.Printf("[RUN] HourlyAggregation for (UTC): %s | Processing %d device(s)",
-				targetHour.Format(), len(activeDevices))
-
-			for _, deviceID := range activeDevices {
-				log.Printf("[RUN] Processing device: %s", deviceID)
-				if _, err := cronSvc.HourlyAggregation(ctx, targetHour.Time, deviceID); err != nil {
-					log.Printf("[ERROR] HourlyAggregation for device %s: %v", deviceID, err)
-				} else {
-					log.Printf("[SUCCESS] HourlyAggregation completed for device: %s", deviceID)
-				}
+			if !elector.IsLeader() {
+				log.Printf("[RUN] Not leader, skipping aggregation at (UTC): %s", now.TruncateHour().Format())
+				continue
 			}
+			leaderCtx := elector.Context()
 
-			if targetHour.Time.Hour() == 23 {
-				targetDay := targetHour.Truncate(24 * time.Hour)
+			activeDevices = registeredDevices(ctx, deviceRepo, activeDevices)
 
-				log.Printf("[RUN] DailyAggregation for: %s | Processing %d device(s)",
-					targetDay.FormatLayout("2006-01-02"), len(activeDevices))
+			log.Printf("[RUN] Aggregation catch-up at (UTC): %s | Processing %d device(s)",
+				now.TruncateHour().Format(), len(activeDevices))
 
-				for _, deviceID := range activeDevices {
-					log.Printf("[RUN] Processing daily aggregation for device: %s", deviceID)
-					if _, err := cronSvc.DailyAggregation(ctx, targetDay.Time, deviceID); err != nil {
-						log.Printf("[ERROR] DailyAggregation for device %s: %v", deviceID, err)
-					} else {
-						log.Printf("[SUCCESS] DailyAggregation completed for device: %s", deviceID)
-					}
-				}
-			}
+			cronSvc.CatchUp(leaderCtx, activeDevices, now, catchUpCfg)
 
-			if hourlyTicker == nil {
-				hourlyTicker = time.NewTicker(time.Hour)
-				hourlyC = hourlyTicker.C
+			log.Printf("[SUCCESS] Aggregation catch-up completed")
+
+			if now.TruncateHour().Time.Hour() == 0 {
+				maintainPartitions(leaderCtx, partitionSvc, cfg)
 			}
 
 		case sig := <-sigChan:
@@ -139,3 +174,33 @@ func main() {
 		}
 	}
 }
+
+// registeredDevices membaca device active dari registry. Jika registry gagal
+// dibaca, daftar sebelumnya tetap dipakai.
+func registeredDevices(ctx context.Context, deviceRepo repository.DeviceRepo, previous []string) []string {
+	devices, err := deviceRepo.ListDeviceIDs(ctx, entity.DeviceStatusActive)
+	if err != nil {
+		log.Printf("[WARNING] Failed to list registered devices: %v", err)
+		return previous
+	}
+
+	return devices
+}
+
+// closeStaleSurgeEvents menutup surge event milik device yang berhenti
+// mengirim reading di tengah surge, karena event hanya ditutup oleh reading
+// berikutnya di ingestor.
+func closeStaleSurgeEvents(ctx context.Context, surgeEventRepo repository.SurgeEventRepo, maxGap time.Duration) {
+	if maxGap <= 0 {
+		return
+	}
+
+	closed, err := surgeEventRepo.CloseStaleSurgeEvents(ctx, utils.TimeNow().Add(-maxGap))
+	if err != nil {
+		log.Printf("[ERROR] Failed closing stale surge events: %v", err)
+		return
+	}
+	if closed > 0 {
+		log.Printf("[SURGE] Closed %d stale surge event(s)", closed)
+	}
+}
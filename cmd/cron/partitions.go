@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"metertronik/internal/service"
+	"metertronik/pkg/config"
+	"metertronik/pkg/database"
+	"metertronik/pkg/utils"
+)
+
+const partitionsUsage = `Usage:
+  cron partitions ensure [-from YEAR] [-ahead N]  create partitions and indexes from YEAR (default: this year) through the coming years
+  cron partitions report [-from YEAR] [-ahead N]  list existing partitions and report gaps
+  cron partitions retain [-years N] [-drop]       detach (and optionally drop) partitions older than N years`
+
+func runPartitions(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Println(partitionsUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("partitions "+args[0], flag.ExitOnError)
+	ahead := fs.Int("ahead", cfg.PartitionAheadYears, "number of years ahead to cover")
+	from := fs.Int("from", 0, "first year to cover, e.g. before a backfill (default: current year)")
+	years := fs.Int("years", cfg.PartitionRetentionYears, "retention window in years")
+	drop := fs.Bool("drop", cfg.PartitionRetentionDrop, "drop partitions after detaching them")
+	fs.Parse(args[1:])
+
+	_, _, cleanupPostgres := database.SetupPostgres(cfg)
+	defer cleanupPostgres()
+
+	partitionRepo := database.SetupPartitionRepo(cfg)
+	partitionSvc := service.NewPartitionService(partitionRepo, service.DefaultPartitionSpecs)
+
+	ctx := context.Background()
+	now := utils.TimeNow()
+
+	switch args[0] {
+	case "ensure":
+		fromYear := now.Time.UTC().Year()
+		if *from > 0 {
+			fromYear = *from
+		}
+		created, err := partitionSvc.EnsureYears(ctx, fromYear, now.Time.UTC().Year()+*ahead)
+		if err != nil {
+			log.Fatalf("Failed to ensure partitions: %v", err)
+		}
+		fmt.Printf("Created %d partition(s): %v\n", len(created), created)
+
+	case "report":
+		for _, spec := range service.DefaultPartitionSpecs {
+			partitions, err := partitionRepo.ListPartitions(ctx, spec.Table)
+			if err != nil {
+				log.Fatalf("Failed to list partitions: %v", err)
+			}
+			fmt.Printf("%s:\n", spec.Table)
+			for _, p := range partitions {
+				fmt.Printf("  %-24s [%s, %s)\n", p.Name, p.From.FormatLayout("2006-01-02"), p.To.FormatLayout("2006-01-02"))
+			}
+		}
+
+		gaps, err := partitionSvc.ReportGaps(ctx, now, *from, *ahead)
+		if err != nil {
+			log.Fatalf("Failed to report partition gaps: %v", err)
+		}
+		if len(gaps) == 0 {
+			fmt.Println("No partition gaps found")
+			return
+		}
+		for _, gap := range gaps {
+			fmt.Printf("GAP %s [%s, %s)\n", gap.Parent, gap.From.FormatLayout("2006-01-02"), gap.To.FormatLayout("2006-01-02"))
+		}
+		os.Exit(1)
+
+	case "retain":
+		removed, err := partitionSvc.ApplyRetention(ctx, now, *years, *drop)
+		if err != nil {
+			log.Fatalf("Failed to apply partition retention: %v", err)
+		}
+		fmt.Printf("Removed %d partition(s): %v\n", len(removed), removed)
+
+	default:
+		fmt.Println(partitionsUsage)
+		os.Exit(2)
+	}
+}
+
+// maintainPartitions dijalankan scheduler saat startup dan setiap hari agar
+// partisi periode berikutnya selalu sudah tersedia sebelum dibutuhkan.
+func maintainPartitions(ctx context.Context, partitionSvc *service.PartitionService, cfg *config.Config) {
+	now := utils.TimeNow()
+
+	if _, err := partitionSvc.EnsureAhead(ctx, now, cfg.PartitionAheadYears); err != nil {
+		log.Printf("[ERROR] Partition maintenance failed: %v", err)
+	}
+
+	gaps, err := partitionSvc.ReportGaps(ctx, now, 0, cfg.PartitionAheadYears)
+	if err != nil {
+		log.Printf("[ERROR] Partition gap report failed: %v", err)
+	}
+	for _, gap := range gaps {
+		log.Printf("[WARNING] Partition gap in %s: [%s, %s)", gap.Parent,
+			gap.From.FormatLayout("2006-01-02"), gap.To.FormatLayout("2006-01-02"))
+	}
+
+	if cfg.PartitionRetentionYears > 0 {
+		if _, err := partitionSvc.ApplyRetention(ctx, now, cfg.PartitionRetentionYears, cfg.PartitionRetentionDrop); err != nil {
+			log.Printf("[ERROR] Partition retention failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"metertronik/internal/service"
+	"metertronik/pkg/config"
+	"metertronik/pkg/database"
+	redisDB "metertronik/pkg/database/redis"
+	"metertronik/pkg/utils"
+)
+
+const provisionUsage = `Usage:
+  cron provision -device ID [-type TYPE] [-secret SECRET]   register a meter and print its claim secret and ingest key
+  cron provision -device ID -delete                        remove a meter with its claim secret and ingest keys`
+
+func runProvision(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("provision", flag.ExitOnError)
+	deviceID := fs.String("device", "", "controller ID of the meter")
+	deviceType := fs.String("type", "", "device type stored on first provisioning")
+	secret := fs.String("secret", "", "claim secret to use instead of a generated one")
+	remove := fs.Bool("delete", false, "remove the meter from the registry instead of provisioning it")
+	fs.Parse(args)
+
+	if *deviceID == "" {
+		fmt.Println(provisionUsage)
+		os.Exit(2)
+	}
+
+	_, _, cleanupPostgres := database.SetupPostgres(cfg)
+	defer cleanupPostgres()
+
+	provisionSvc := service.NewProvisionService(database.SetupDeviceRepo(cfg), database.SetupDeviceCredentialRepo(cfg))
+
+	if *remove {
+		if err := provisionSvc.Delete(context.Background(), *deviceID); err != nil {
+			log.Fatalf("Failed to delete device %s: %v", *deviceID, err)
+		}
+		fmt.Printf("Device %s deleted\n", *deviceID)
+
+		// Kunci ingest ikut terhapus; beri tahu ingestor agar cache verifier
+		// untuk device ini langsung dibuang.
+		revocations, cleanupRevocations := redisDB.SetupRedisIngestKeyRevocation(cfg)
+		defer cleanupRevocations()
+		if revocations != nil {
+			if err := revocations.PublishRevocation(context.Background(), *deviceID); err != nil {
+				log.Printf("[WARNING] Failed to publish ingest key revocation for device %s: %v", *deviceID, err)
+			}
+		}
+		return
+	}
+
+	claimSecret, err := provisionSvc.Provision(context.Background(), *deviceID, *deviceType, *secret)
+	if err != nil {
+		log.Fatalf("Failed to provision device %s: %v", *deviceID, err)
+	}
+
+	fmt.Printf("Device:       %s\n", *deviceID)
+	fmt.Printf("Claim secret: %s\n", claimSecret)
+
+	// Kunci ingest ditanam di firmware saat produksi; provisioning ulang
+	// menerbitkan kunci baru dengan masa tenggang untuk kunci lama.
+	keyBox, err := utils.NewSecretBox(cfg.IngestKeyEncryptionKey)
+	if err != nil {
+		log.Printf("[WARNING] Ingest key not issued, encryption not configured: %v", err)
+		return
+	}
+
+	ingestKeys := service.NewIngestKeyService(database.SetupDeviceIngestKeyRepo(cfg), nil, keyBox, cfg.IngestKeyGrace)
+	issued, err := ingestKeys.Rotate(context.Background(), *deviceID)
+	if err != nil {
+		log.Fatalf("Failed to issue ingest key for device %s: %v", *deviceID, err)
+	}
+
+	fmt.Printf("Ingest key:   %d\n", issued.KeyID)
+	fmt.Printf("Ingest secret (base64): %s\n", issued.Secret)
+	fmt.Printf("Ingest API key: %s\n", issued.APIKey)
+}
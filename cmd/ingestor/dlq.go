@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"metertronik/internal/handler/amqp"
+	"metertronik/pkg/config"
+)
+
+const dlqUsage = `Usage:
+  ingestor dlq inspect [-limit N]   show messages in the dead-letter queue
+  ingestor dlq redrive [-limit N]   republish dead-lettered messages to their original route`
+
+func runDeadLetter(cfg *config.Config, consumerCfg *amqp.ConsumerConfig, args []string) {
+	if len(args) == 0 {
+		fmt.Println(dlqUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("dlq "+args[0], flag.ExitOnError)
+	limit := fs.Int("limit", 100, "maximum number of messages to process")
+	fs.Parse(args[1:])
+
+	tool := amqp.NewDeadLetterTool(consumerCfg)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "inspect":
+		messages, err := tool.Inspect(ctx, cfg.RabbitMQURL, *limit)
+		if err != nil {
+			log.Fatalf("Failed to inspect dead-letter queue: %v", err)
+		}
+
+		for i, m := range messages {
+			fmt.Printf("#%d retries=%d failed_at=%s route=%s/%s\n", i+1, m.RetryCount, m.FailedAt, m.OriginalExchange, m.OriginalRoutingKey)
+			fmt.Printf("   error: %s\n", m.LastError)
+			fmt.Printf("   body:  %s\n", string(m.Body))
+		}
+		fmt.Printf("%d message(s) in %s (showing up to %d)\n", len(messages), consumerCfg.DeadLetterQueue, *limit)
+
+	case "redrive":
+		count, err := tool.Redrive(ctx, cfg.RabbitMQURL, *limit)
+		if err != nil {
+			log.Fatalf("Failed to redrive dead-letter queue after %d message(s): %v", count, err)
+		}
+		fmt.Printf("Redriven %d message(s) from %s\n", count, consumerCfg.DeadLetterQueue)
+
+	default:
+		fmt.Println(dlqUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+
+	"metertronik/internal/handler/ingest"
+	httpRouter "metertronik/internal/router/http"
+	"metertronik/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveIngestHTTP menjalankan POST /v1/ingest untuk meter yang hanya bisa
+// keluar lewat HTTPS, berdampingan dengan consumer AMQP.
+func serveIngestHTTP(cfg *config.Config, ingestHandler *ingest.HTTPHandler) {
+	gin.SetMode(cfg.GinMode)
+	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	httpRouter.SetupIngestRoutes(router, ingestHandler)
+
+	go func() {
+		log.Printf("HTTP ingest endpoint: http://%s/v1/ingest", cfg.IngestHTTPAddr)
+		if err := router.Run(cfg.IngestHTTPAddr); err != nil {
+			log.Printf("HTTP ingest server stopped: %v", err)
+		}
+	}()
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"metertronik/internal/handler/amqp"
+	"metertronik/internal/handler/ingest"
 	"metertronik/internal/service"
 	"metertronik/pkg/config"
 	"metertronik/pkg/database"
 	"metertronik/pkg/database/redis"
+	"metertronik/pkg/metrics"
+	"metertronik/pkg/utils"
 )
 
 func main() {
@@ -17,27 +21,80 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	influxRepo, cleanupInflux := database.SetupInfluxDB(cfg)
-	defer cleanupInflux()
-
-	RedisRealtimeRepo, cleanupRedis := redis.SetupRedisRealtime(cfg)
-	defer cleanupRedis()
-
-	svc := service.NewIngestService(influxRepo, RedisRealtimeRepo)
-
 	consumerCfg := &amqp.ConsumerConfig{
+		URL:           cfg.RabbitMQURL,
 		QueueName:     cfg.RabbitMQQueueName,
 		RoutingKey:    cfg.RabbitMQRoutingKey,
 		Exchange:      cfg.RabbitMQExchange,
 		PrefetchCount: cfg.RabbitMQPrefetchCount,
 		RetryDelay:    cfg.RabbitMQRetryDelay,
 		LogInterval:   cfg.ConsumerLogInterval,
+
+		MaxRetries:         cfg.RabbitMQMaxRetries,
+		RetryTTL:           cfg.RabbitMQRetryTTL,
+		DeadLetterExchange: cfg.RabbitMQDeadLetterExchange,
+		DeadLetterQueue:    cfg.RabbitMQDeadLetterQueue,
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		runDeadLetter(cfg, consumerCfg, os.Args[2:])
+		return
+	}
+
+	influxRepo, cleanupInflux := database.SetupInfluxDB(cfg)
+	defer cleanupInflux()
+
+	RedisRealtimeRepo, cleanupRedis := redis.SetupRedisRealtime(cfg)
+	defer cleanupRedis()
+
+	_, _, cleanupPostgres := database.SetupPostgres(cfg)
+	defer cleanupPostgres()
+
+	deviceCache := service.NewDeviceCache(database.SetupDeviceRepo(cfg), 0, cfg.DefaultTimezone)
+
+	policies := service.NewThresholdPolicyCache(database.SetupThresholdPolicyRepo(cfg), cfg.IngestPolicyReload)
+
+	surges := service.NewSurgeDetector(database.SetupSurgeEventRepo(cfg), cfg.SurgeSettleReadings, cfg.SurgeMaxGap)
+
+	svc := service.NewIngestService(influxRepo, RedisRealtimeRepo, deviceCache, policies, surges, cfg.RealtimeHistoryRetention, cfg.IngestDedupTTL)
+
+	keyBox, err := utils.NewSecretBox(cfg.IngestKeyEncryptionKey)
+	if err != nil {
+		if cfg.IngestRequireSignature {
+			log.Fatalf("INGEST_KEY_ENCRYPTION_KEY is required to verify signed messages: %v", err)
+		}
+		log.Printf("[WARNING] Ingest key encryption not configured, signed messages cannot be verified: %v", err)
+	}
+
+	ingestKeyRevocations, cleanupRevocations := redis.SetupRedisIngestKeyRevocation(cfg)
+	defer cleanupRevocations()
+
+	ingestKeys := service.NewIngestKeyService(database.SetupDeviceIngestKeyRepo(cfg), ingestKeyRevocations, keyBox, cfg.IngestKeyGrace)
+	verifier := service.NewIngestVerifier(ingestKeys, cfg.IngestRequireSignature)
+	verifier.WatchRevocations(context.Background(), ingestKeyRevocations)
+	if !cfg.IngestRequireSignature {
+		log.Printf("[WARNING] INGEST_REQUIRE_SIGNATURE=false, unsigned messages are accepted")
+	}
+
+	validator := service.NewReadingValidator(database.SetupReadingRuleRepo(cfg), deviceCache, 0)
+	clock := service.NewClockValidator(RedisRealtimeRepo, cfg.IngestClockMaxAhead, cfg.IngestClockMaxBehind)
+	pipeline := service.NewIngestPipeline(svc, verifier, validator, clock, database.SetupQuarantineRepo(cfg))
+
+	metrics.Serve(cfg.IngestMetricsAddr)
+
+	if cfg.IngestHTTPAddr != "" {
+		ingestHandler := ingest.NewHTTPHandler(pipeline, verifier, deviceCache, cfg.IngestHTTPMaxBodyBytes, cfg.IngestHTTPMaxBatch)
+		serveIngestHTTP(cfg, ingestHandler)
+	}
+
+	consumer, err := newConsumer(cfg, pipeline, consumerCfg)
+	if err != nil {
+		log.Fatalf("Failed to create %s consumer: %v", cfg.IngestTransport, err)
 	}
-	consumer := amqp.NewConsumer(svc, consumerCfg)
 
 	ctx := context.Background()
-	log.Printf("Consumer started, waiting for messages...")
-	if err := consumer.StartConsuming(ctx, cfg.RabbitMQURL); err != nil {
+	log.Printf("Consumer started (%s), waiting for messages...", cfg.IngestTransport)
+	if err := consumer.StartConsuming(ctx); err != nil {
 		log.Fatalf("Failed to start consuming: %v", err)
 	}
 }
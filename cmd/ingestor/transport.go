@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"metertronik/internal/handler/amqp"
+	"metertronik/internal/handler/ingest"
+	"metertronik/internal/handler/mqtt"
+	"metertronik/internal/service"
+	"metertronik/pkg/config"
+)
+
+// newConsumer memilih transport broker berdasarkan INGEST_TRANSPORT.
+func newConsumer(cfg *config.Config, pipeline *service.IngestPipeline, amqpCfg *amqp.ConsumerConfig) (ingest.Consumer, error) {
+	switch cfg.IngestTransport {
+	case "amqp":
+		return amqp.NewConsumer(pipeline, amqpCfg), nil
+	case "mqtt":
+		return mqtt.NewConsumer(pipeline, &mqtt.ConsumerConfig{
+			BrokerURL:    cfg.MQTTBrokerURL,
+			ClientID:     mqttClientID(cfg.MQTTClientID),
+			Username:     cfg.MQTTUsername,
+			Password:     cfg.MQTTPassword,
+			Topics:       cfg.MQTTTopics,
+			CleanSession: cfg.MQTTCleanSession,
+
+			MaxRetries:     cfg.MQTTMaxRetries,
+			MaxInflight:    cfg.MQTTMaxInflight,
+			RetryDelay:     cfg.MQTTRetryDelay,
+			ReconnectDelay: cfg.MQTTReconnectDelay,
+		})
+	default:
+		return nil, fmt.Errorf("unknown INGEST_TRANSPORT %q (expected amqp or mqtt)", cfg.IngestTransport)
+	}
+}
+
+// mqttClientID memberi tiap replika client ID sendiri; broker memutus koneksi
+// lama jika dua client memakai ID yang sama. Hostname dipakai (bukan nilai
+// acak) agar sesi persisten tetap bisa dilanjutkan setelah restart.
+func mqttClientID(configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Sprintf("metertronik-ingestor-%d", os.Getpid())
+	}
+
+	return "metertronik-ingestor-" + hostname
+}
@@ -2,6 +2,8 @@ module metertronik
 
 go 1.25.3
 
+require github.com/eclipse/paho.mqtt.golang v1.5.1
+
 require (
 	github.com/apapsch/go-jsonmerge/v2 v2.0.0 // indirect
 	github.com/bytedance/gopkg v0.1.3 // indirect
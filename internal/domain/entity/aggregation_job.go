@@ -0,0 +1,28 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+const (
+	JobKindHourly  = "hourly"
+	JobKindDaily   = "daily"
+	JobKindMonthly = "monthly"
+	JobKindYearly  = "yearly"
+)
+
+const (
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusEmpty   = "empty"
+	JobStatusFailed  = "failed"
+)
+
+type AggregationJob struct {
+	JobKind   string         `json:"job_kind" gorm:"column:job_kind;type:varchar(20);primaryKey"`
+	DeviceID  string         `json:"device_id" gorm:"column:device_id;type:varchar(50);primaryKey"`
+	Period    utils.TimeData `json:"period" gorm:"column:period;type:timestamptz;primaryKey"`
+	Status    string         `json:"status" gorm:"column:status;type:varchar(20);not null"`
+	Attempts  int            `json:"attempts" gorm:"column:attempts;not null"`
+	Error     string         `json:"error" gorm:"column:error"`
+	CreatedAt utils.TimeData `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt utils.TimeData `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
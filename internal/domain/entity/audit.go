@@ -0,0 +1,19 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+const (
+	AuditActionDeviceAccessDenied = "device_access_denied"
+)
+
+// AuditLog mencatat kejadian keamanan, mis. user yang mencoba membuka data
+// device milik orang lain.
+type AuditLog struct {
+	ID        int64          `json:"id" gorm:"primaryKey"`
+	UserID    int64          `json:"user_id" gorm:"column:user_id;not null"`
+	Action    string         `json:"action" gorm:"column:action;type:varchar(50);not null"`
+	DeviceID  string         `json:"device_id" gorm:"column:device_id;type:varchar(64)"`
+	Resource  string         `json:"resource" gorm:"column:resource;type:varchar(255)"`
+	IPAddress string         `json:"ip_address" gorm:"column:ip_address;type:varchar(64)"`
+	CreatedAt utils.TimeData `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
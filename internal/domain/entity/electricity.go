@@ -16,6 +16,26 @@ type RealTimeElectricity struct {
 	PowerSurge  float64        `json:"power_surge" gorm:"type:decimal(10,2);not null"`
 	PSPercent   float64        `json:"power_surge_percentage" gorm:"type:decimal(4,2);not null"`
 	CreatedAt   utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
+
+	// ClockCorrected menandai reading yang timestamp device-nya di luar
+	// toleransi dan diganti waktu terima; DeviceTime adalah timestamp asli.
+	ClockCorrected bool            `json:"clock_corrected,omitempty" gorm:"-"`
+	DeviceTime     *utils.TimeData `json:"device_time,omitempty" gorm:"-"`
+}
+
+// ClockStats adalah statistik selisih jam device terhadap waktu terima
+// server (skew positif berarti jam device lebih cepat).
+type ClockStats struct {
+	DeviceID        string          `json:"device_id"`
+	Samples         int64           `json:"samples"`
+	Corrected       int64           `json:"corrected"`
+	AvgSkewMs       float64         `json:"avg_skew_ms"`
+	LastSkewMs      int64           `json:"last_skew_ms"`
+	MaxAheadMs      int64           `json:"max_ahead_ms"`
+	MaxBehindMs     int64           `json:"max_behind_ms"`
+	LastDeviceTime  *utils.TimeData `json:"last_device_time,omitempty"`
+	LastSeenAt      *utils.TimeData `json:"last_seen_at,omitempty"`
+	LastCorrectedAt *utils.TimeData `json:"last_corrected_at,omitempty"`
 }
 
 type HourlyElectricity struct {
@@ -28,6 +48,9 @@ type HourlyElectricity struct {
 	MinPower   float64 `json:"min_power" gorm:"column:min_power;type:decimal(10,2)"`
 	MaxPower   float64 `json:"max_power" gorm:"column:max_power;type:decimal(10,2)"`
 
+	CoveragePercent float64 `json:"coverage_percent" gorm:"column:coverage_percent;type:decimal(5,2)"`
+	SampleCount     int     `json:"sample_count" gorm:"column:sample_count"`
+
 	TS        utils.TimeData `json:"ts" gorm:"column:ts;type:timestamptz;not null"`
 	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
 }
@@ -43,7 +66,11 @@ type DailyElectricity struct {
 	MinPower   float64 `json:"min_power" gorm:"column:min_power;type:decimal(10,2)"`
 	MaxPower   float64 `json:"max_power" gorm:"column:max_power;type:decimal(10,2)"`
 
+	CoveragePercent float64 `json:"coverage_percent" gorm:"column:coverage_percent;type:decimal(5,2)"`
+	SampleCount     int     `json:"sample_count" gorm:"column:sample_count"`
+
 	Day       utils.TimeData `json:"day" gorm:"column:day;type:date;not null"`
+	Timezone  string         `json:"timezone" gorm:"column:timezone;type:varchar(64)"`
 	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
 }
 
@@ -53,5 +80,16 @@ type MonthlyElectricity struct {
 
 	Energy    float64        `json:"energy" gorm:"column:energy;type:decimal(10,3);not null"`
 	TotalCost float64        `json:"total_cost" gorm:"column:total_cost;type:decimal(15,2);not null"`
+	Timezone  string         `json:"timezone" gorm:"column:timezone;type:varchar(64)"`
+	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type YearlyElectricity struct {
+	DeviceID string         `json:"device_id" gorm:"column:device_id;type:varchar(50);not null"`
+	Year     utils.TimeData `json:"year" gorm:"column:year;type:date;not null"`
+
+	Energy    float64        `json:"energy" gorm:"column:energy;type:decimal(12,3);not null"`
+	TotalCost float64        `json:"total_cost" gorm:"column:total_cost;type:decimal(17,2);not null"`
+	Timezone  string         `json:"timezone" gorm:"column:timezone;type:varchar(64)"`
 	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
 }
@@ -0,0 +1,16 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+type Partition struct {
+	Parent string         `json:"parent"`
+	Name   string         `json:"name"`
+	From   utils.TimeData `json:"from"`
+	To     utils.TimeData `json:"to"`
+}
+
+type PartitionGap struct {
+	Parent string         `json:"parent"`
+	From   utils.TimeData `json:"from"`
+	To     utils.TimeData `json:"to"`
+}
@@ -0,0 +1,43 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+// Field reading yang bisa diberi batas fisik lewat ReadingRule.
+const (
+	ReadingFieldVoltage     = "voltage"
+	ReadingFieldCurrent     = "current"
+	ReadingFieldPower       = "power"
+	ReadingFieldEnergy      = "energy"
+	ReadingFieldPowerFactor = "power_factor"
+	ReadingFieldFrequency   = "frequency"
+)
+
+// Kode alasan reading dikarantina.
+const (
+	QuarantineMalformed         = "malformed_payload"
+	QuarantineUnsupportedSchema = "unsupported_schema"
+	QuarantineMissingDeviceID   = "missing_device_id"
+	QuarantineNotFinite         = "not_finite"
+	QuarantineOutOfRange        = "out_of_range"
+)
+
+// ReadingRule adalah batas fisik satu field reading untuk satu tipe device.
+// DeviceType kosong berlaku untuk semua tipe; Min/Max nil berarti tanpa batas.
+type ReadingRule struct {
+	DeviceType string   `json:"device_type" gorm:"column:device_type;primaryKey"`
+	Field      string   `json:"field" gorm:"column:field;primaryKey"`
+	MinValue   *float64 `json:"min_value" gorm:"column:min_value"`
+	MaxValue   *float64 `json:"max_value" gorm:"column:max_value"`
+}
+
+// QuarantinedReading menyimpan reading yang ditolak validasi beserta payload
+// aslinya agar bisa diperiksa tanpa masuk ke InfluxDB.
+type QuarantinedReading struct {
+	ID            int64          `json:"id" gorm:"primaryKey"`
+	DeviceID      string         `json:"device_id" gorm:"column:device_id;type:varchar(64)"`
+	SchemaVersion int            `json:"schema_version" gorm:"column:schema_version"`
+	Reason        string         `json:"reason" gorm:"column:reason;type:varchar(30);not null"`
+	Detail        string         `json:"detail" gorm:"column:detail"`
+	Payload       string         `json:"payload" gorm:"column:payload;not null"`
+	ReceivedAt    utils.TimeData `json:"received_at" gorm:"column:received_at;autoCreateTime"`
+}
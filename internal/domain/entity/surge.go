@@ -0,0 +1,43 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+// SurgeEvent adalah lonjakan daya yang dibuka saat reading melewati
+// threshold surge device dan ditutup saat daya kembali stabil. EndedAt nil
+// berarti event masih berlangsung.
+type SurgeEvent struct {
+	ID       int64  `json:"id" gorm:"primaryKey"`
+	DeviceID string `json:"device_id" gorm:"column:device_id;type:varchar(64);not null"`
+
+	StartedAt utils.TimeData  `json:"started_at" gorm:"column:started_at;not null"`
+	EndedAt   *utils.TimeData `json:"ended_at" gorm:"column:ended_at"`
+
+	// BaselinePower adalah daya sebelum event; PeakPower adalah daya yang
+	// paling jauh dari baseline dan Magnitude selisih absolutnya (W).
+	BaselinePower float64 `json:"baseline_power" gorm:"column:baseline_power"`
+	PeakPower     float64 `json:"peak_power" gorm:"column:peak_power"`
+	Magnitude     float64 `json:"magnitude" gorm:"column:magnitude"`
+	PeakSurge     float64 `json:"peak_surge" gorm:"column:peak_surge"`
+	PeakPercent   float64 `json:"peak_percent" gorm:"column:peak_percent"`
+
+	Samples      int            `json:"samples" gorm:"column:samples"`
+	LastSampleAt utils.TimeData `json:"last_sample_at" gorm:"column:last_sample_at"`
+	CreatedAt    utils.TimeData `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+const (
+	SurgeEventStatusOpen   = "open"
+	SurgeEventStatusClosed = "closed"
+)
+
+// SurgeEventFilter membatasi hasil ListSurgeEvents. Nilai kosong berarti
+// tanpa filter; LastID adalah cursor halaman berikutnya (id lebih kecil).
+type SurgeEventFilter struct {
+	DeviceID     string
+	From         *utils.TimeData
+	To           *utils.TimeData
+	MinMagnitude float64
+	Status       string
+	LastID       int64
+	Limit        int
+}
@@ -0,0 +1,50 @@
+package entity
+
+import "metertronik/pkg/utils"
+
+// ThresholdPolicy mengatur sensitivitas deteksi perubahan dan surge saat
+// ingest. Policy dipasang langsung ke device (threshold_policy_id) atau
+// berlaku untuk semua device milik OwnerID dengan DeviceType yang sama.
+// Policy tanpa OwnerID adalah policy global yang hanya bisa dibaca lewat API.
+type ThresholdPolicy struct {
+	ID         int64  `json:"id" gorm:"primaryKey"`
+	OwnerID    *int64 `json:"owner_id"`
+	Name       string `json:"name" gorm:"not null"`
+	DeviceType string `json:"device_type"`
+
+	// Persentase perubahan minimum per field agar reading baru dianggap
+	// signifikan dan menggantikan cache latest.
+	VoltagePercent     float64 `json:"voltage_percent"`
+	CurrentPercent     float64 `json:"current_percent"`
+	PowerPercent       float64 `json:"power_percent"`
+	EnergyPercent      float64 `json:"energy_percent"`
+	PowerFactorPercent float64 `json:"power_factor_percent"`
+	FrequencyPercent   float64 `json:"frequency_percent"`
+
+	// Surge dianggap terjadi bila selisih daya melebihi SurgeWatts atau
+	// SurgePercent; persentase hanya dihitung bila daya sebelumnya minimal
+	// SurgeBaselineWatts.
+	SurgeWatts         float64 `json:"surge_watts"`
+	SurgePercent       float64 `json:"surge_percent"`
+	SurgeBaselineWatts float64 `json:"surge_baseline_watts"`
+
+	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt utils.TimeData `json:"updated_at"`
+}
+
+// DefaultThresholdPolicy adalah nilai yang dipakai bila device tidak punya
+// policy.
+func DefaultThresholdPolicy() *ThresholdPolicy {
+	return &ThresholdPolicy{
+		Name:               "default",
+		VoltagePercent:     10,
+		CurrentPercent:     10,
+		PowerPercent:       10,
+		EnergyPercent:      10,
+		PowerFactorPercent: 10,
+		FrequencyPercent:   10,
+		SurgeWatts:         500,
+		SurgePercent:       15,
+		SurgeBaselineWatts: 50,
+	}
+}
@@ -1,6 +1,9 @@
 package entity
 
-import "metertronik/pkg/utils"
+import (
+	"metertronik/pkg/utils"
+	"time"
+)
 
 // type User struct {
 //  ID        int64      `json:"id" gorm:"primaryKey"`
@@ -25,11 +28,83 @@ type User struct {
 	UpdatedAt utils.TimeData  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+const (
+	EnergyModeDelta      = "delta"
+	EnergyModeCumulative = "cumulative"
+)
+
+// Device dengan status selain active tidak diagregasi oleh cron.
+const (
+	DeviceStatusActive   = "active"
+	DeviceStatusDisabled = "disabled"
+)
+
 type Device struct {
-	ID              int64          `json:"id" gorm:"primaryKey"`
-	DeviceName      string         `json:"device_name" gorm:"not null"`
-	DeviceType      string         `json:"device_type" gorm:"not null"`
-	DeviceStatus    string         `json:"device_status" gorm:"not null"`
-	DeviceLocation  string         `json:"device_location" gorm:"not null"`
-	DeviceCreatedAt utils.TimeData `json:"device_created_at" gorm:"autoCreateTime"`
+	ID                int64          `json:"id" gorm:"primaryKey"`
+	DeviceID          string         `json:"device_id" gorm:"uniqueIndex;not null"`
+	OwnerID           *int64         `json:"owner_id"`
+	DeviceName        string         `json:"device_name" gorm:"not null"`
+	DeviceType        string         `json:"device_type" gorm:"not null"`
+	DeviceStatus      string         `json:"device_status" gorm:"not null"`
+	DeviceLocation    string         `json:"device_location" gorm:"not null"`
+	EnergyMode        string         `json:"energy_mode" gorm:"default:delta"`
+	EnergyRegisterMax float64        `json:"energy_register_max" gorm:"type:decimal(15,3)"`
+	Timezone          string         `json:"timezone"`
+	TariffClass       string         `json:"tariff_class"`
+	DeviceCreatedAt   utils.TimeData `json:"device_created_at" gorm:"autoCreateTime"`
+	DeviceUpdatedAt   utils.TimeData `json:"device_updated_at"`
+
+	// IngestMaxBodyBytes membatasi ukuran request ingest HTTP device ini;
+	// 0 berarti memakai INGEST_HTTP_MAX_BODY_BYTES.
+	IngestMaxBodyBytes int64 `json:"ingest_max_body_bytes"`
+
+	// ThresholdPolicyID memasang policy threshold khusus; nil berarti policy
+	// dipilih berdasarkan tipe device.
+	ThresholdPolicyID *int64 `json:"threshold_policy_id"`
+}
+
+const DeviceMemberRoleViewer = "viewer"
+
+// DeviceMember memberi user selain pemilik akses baca ke data device.
+type DeviceMember struct {
+	ID        int64          `json:"id" gorm:"primaryKey"`
+	DeviceID  string         `json:"device_id" gorm:"not null"`
+	UserID    int64          `json:"user_id" gorm:"not null"`
+	Role      string         `json:"role" gorm:"default:viewer"`
+	CreatedAt utils.TimeData `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// DeviceCredential adalah secret klaim yang dicetak pada device saat
+// provisioning. Hanya hash bcrypt-nya yang disimpan.
+type DeviceCredential struct {
+	DeviceID        string         `json:"device_id" gorm:"primaryKey"`
+	ClaimSecretHash string         `json:"-" gorm:"not null"`
+	ProvisionedAt   utils.TimeData `json:"provisioned_at"`
+}
+
+// DeviceIngestKey adalah kunci HMAC yang dipakai device untuk menandatangani
+// pesan ingest. Secret disimpan terenkripsi karena server perlu membacanya
+// untuk verifikasi. API key ingest HTTP diterbitkan terpisah dan hanya
+// disimpan hash SHA-256-nya. Kunci lama tetap berlaku sampai ExpiresAt
+// setelah rotasi.
+type DeviceIngestKey struct {
+	ID               int64           `json:"-" gorm:"primaryKey"`
+	DeviceID         string          `json:"device_id" gorm:"not null"`
+	KeyID            int             `json:"key_id" gorm:"not null"`
+	SecretCiphertext string          `json:"-" gorm:"not null"`
+	APIKeyHash       string          `json:"-" gorm:"column:api_key_hash"`
+	CreatedAt        utils.TimeData  `json:"created_at"`
+	ExpiresAt        *utils.TimeData `json:"expires_at"`
+}
+
+func (d *Device) IsCumulative() bool {
+	return d != nil && d.EnergyMode == EnergyModeCumulative
+}
+
+// Location mengembalikan zona waktu device untuk batas hari/bulan lokal.
+func (d *Device) Location() *time.Location {
+	if d == nil {
+		return time.UTC
+	}
+	return utils.LoadLocation(d.Timezone)
 }
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+type AggregationJobRepo interface {
+	GetJobs(ctx context.Context, jobKind string, deviceID string, start utils.TimeData, end utils.TimeData) ([]entity.AggregationJob, error)
+	SaveJob(ctx context.Context, job *entity.AggregationJob) error
+}
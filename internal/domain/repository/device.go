@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"metertronik/internal/domain/entity"
+	"time"
+)
+
+type DeviceRepo interface {
+	GetDevice(ctx context.Context, deviceID string) (*entity.Device, error)
+	CanAccessDevice(ctx context.Context, deviceID string, userID int64) (bool, error)
+
+	CreateDevice(ctx context.Context, device *entity.Device) error
+	UpdateDevice(ctx context.Context, device *entity.Device) error
+	DeleteDevice(ctx context.Context, deviceID string) error
+	// ListDevicesForUser mengembalikan device milik user dan device yang
+	// dibagikan kepadanya.
+	ListDevicesForUser(ctx context.Context, userID int64) ([]entity.Device, error)
+	// ListDeviceIDs mengembalikan device terdaftar; status kosong berarti
+	// semua status.
+	ListDeviceIDs(ctx context.Context, status string) ([]string, error)
+	// SetOwner mengganti pemilik hanya jika pemilik saat ini sama dengan
+	// currentOwner (nil berarti belum dimiliki), lalu menghapus semua anggota
+	// dan melepas threshold policy milik pemilik lama. Mengembalikan false
+	// jika pemilik sudah berubah lebih dulu.
+	SetOwner(ctx context.Context, deviceID string, currentOwner *int64, newOwner *int64) (bool, error)
+}
+
+type DeviceCredentialRepo interface {
+	GetCredential(ctx context.Context, deviceID string) (*entity.DeviceCredential, error)
+	SaveCredential(ctx context.Context, credential *entity.DeviceCredential) error
+}
+
+type DeviceMemberRepo interface {
+	ListMembers(ctx context.Context, deviceID string) ([]entity.DeviceMember, error)
+	AddMember(ctx context.Context, member *entity.DeviceMember) error
+	RemoveMember(ctx context.Context, deviceID string, userID int64) error
+}
+
+type DeviceIngestKeyRepo interface {
+	// ListActiveIngestKeys mengembalikan kunci yang belum kedaluwarsa pada at.
+	ListActiveIngestKeys(ctx context.Context, deviceID string, at time.Time) ([]entity.DeviceIngestKey, error)
+	// RotateIngestKey menyimpan key sebagai kunci aktif baru (KeyID diisi)
+	// dan membuat kunci aktif sebelumnya kedaluwarsa pada graceUntil.
+	RotateIngestKey(ctx context.Context, key *entity.DeviceIngestKey, graceUntil time.Time) error
+	// ExpireIngestKeys membuat semua kunci device yang masih berlaku
+	// kedaluwarsa pada at, tanpa masa tenggang.
+	ExpireIngestKeys(ctx context.Context, deviceID string, at time.Time) error
+}
+
+// IngestKeyRevocationRepo menyiarkan pencabutan kunci ingest dari API ke
+// semua ingestor agar cache verifikasinya dibuang.
+type IngestKeyRevocationRepo interface {
+	PublishRevocation(ctx context.Context, deviceID string) error
+	// Revocations mengalirkan device ID yang kuncinya dicabut sampai ctx
+	// selesai.
+	Revocations(ctx context.Context) <-chan string
+}
+
+type ThresholdPolicyRepo interface {
+	// ListThresholdPolicies mengembalikan semua policy (dipakai cache ingestor).
+	ListThresholdPolicies(ctx context.Context) ([]entity.ThresholdPolicy, error)
+	// ListThresholdPoliciesForUser mengembalikan policy milik user dan policy global.
+	ListThresholdPoliciesForUser(ctx context.Context, userID int64) ([]entity.ThresholdPolicy, error)
+	GetThresholdPolicy(ctx context.Context, id int64) (*entity.ThresholdPolicy, error)
+	CreateThresholdPolicy(ctx context.Context, policy *entity.ThresholdPolicy) error
+	UpdateThresholdPolicy(ctx context.Context, policy *entity.ThresholdPolicy) error
+	DeleteThresholdPolicy(ctx context.Context, id int64) error
+}
+
+type AuditRepo interface {
+	CreateAuditLog(ctx context.Context, log *entity.AuditLog) error
+}
@@ -7,10 +7,18 @@ import (
 	"time"
 )
 
+// PendingWrite menunggu sampai point yang diantrekan tersimpan, baik di
+// InfluxDB maupun di spill file.
+type PendingWrite func(ctx context.Context) error
+
 type InfluxRepo interface {
 	SaveRealTimeElectricity(ctx context.Context, electricity *entity.RealTimeElectricity) error
+	// QueueRealTimeElectricity mengantrekan point ke batch berikutnya tanpa
+	// menunggu flush.
+	QueueRealTimeElectricity(ctx context.Context, electricity *entity.RealTimeElectricity) (PendingWrite, error)
 	GetRealTimeElectricity(ctx context.Context, deviceID string) (*[]entity.RealTimeElectricity, error)
 	GetRealTimeElectricityRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData) (*[]entity.RealTimeElectricity, error)
+	GetLastRealTimeElectricityBefore(ctx context.Context, deviceID string, before utils.TimeData, lookback time.Duration) (*entity.RealTimeElectricity, error)
 	GetActiveDeviceIDs(ctx context.Context, hours int) ([]string, error)
 }
 
@@ -19,7 +27,32 @@ type RedisRealtimeRepo interface {
 	GetLatestElectricity(ctx context.Context, deviceID string) (*entity.RealTimeElectricity, error)
 	DeleteLatestElectricity(ctx context.Context, deviceID string) error
 	SaveElectricityHistory(ctx context.Context, deviceID string, electricity *entity.RealTimeElectricity, ttl time.Duration) error
+	GetElectricityHistory(ctx context.Context, deviceID string, since time.Time) ([]entity.RealTimeElectricity, error)
 	HasChanged(ctx context.Context, deviceID string, newData *entity.RealTimeElectricity) (bool, *entity.RealTimeElectricity, error)
+	PublishElectricity(ctx context.Context, deviceID string, electricity *entity.RealTimeElectricity) error
+	// MarkReadingSeen menandai reading (device, timestamp reading) dan
+	// mengembalikan false jika reading tersebut sudah pernah ditandai. Hanya
+	// timestamp dalam jendela window dari timestamp terbaru device yang
+	// diingat; reading yang lebih tua selalu dianggap baru. newest adalah
+	// timestamp terbaru yang ditandai sebelum reading ini (zero jika belum
+	// ada).
+	MarkReadingSeen(ctx context.Context, deviceID string, ts time.Time, window time.Duration) (first bool, newest time.Time, err error)
+	// ForgetReading menghapus tanda MarkReadingSeen agar reading yang gagal
+	// disimpan bisa diproses ulang.
+	ForgetReading(ctx context.Context, deviceID string, ts time.Time) error
+	// RecordClockSample menambah satu sampel skew jam device pada receivedAt.
+	RecordClockSample(ctx context.Context, deviceID string, deviceTime time.Time, receivedAt time.Time, corrected bool) error
+	// GetClockStats mengembalikan nil jika device belum punya sampel.
+	GetClockStats(ctx context.Context, deviceID string) (*entity.ClockStats, error)
+}
+
+// ElectricitySubscriber menerima data realtime yang dipublikasikan lewat
+// PublishElectricity untuk device yang sedang di-subscribe.
+type ElectricitySubscriber interface {
+	Subscribe(ctx context.Context, deviceIDs ...string) error
+	Unsubscribe(ctx context.Context, deviceIDs ...string) error
+	Messages() <-chan *entity.RealTimeElectricity
+	Close() error
 }
 
 type RedisBatchRepo interface {
@@ -35,18 +68,22 @@ type RedisBatchRepo interface {
 
 type PostgresRepo interface {
 	SaveHourlyElectricity(ctx context.Context, hourlyElectricity *entity.HourlyElectricity) error
-	GetHourlyElectricity(ctx context.Context, deviceID string, hours int, date *utils.TimeData) (*[]entity.HourlyElectricity, error)
+	GetHourlyElectricity(ctx context.Context, deviceID string, hours int, date *utils.TimeData, loc *time.Location) (*[]entity.HourlyElectricity, error)
 	
 	SaveDailyElectricity(ctx context.Context, dailyElectricity *entity.DailyElectricity) error
-	GetDailyElectricity(ctx context.Context, deviceID string, date utils.TimeData) (*entity.DailyElectricity, *[]entity.HourlyElectricity, error)
+	GetDailyElectricity(ctx context.Context, deviceID string, date utils.TimeData, loc *time.Location) (*entity.DailyElectricity, *[]entity.HourlyElectricity, error)
 	
 	UpsertMonthlyElectricity(ctx context.Context, monthlyElectricity *entity.MonthlyElectricity) error
 	GetMonthlyElectricity(ctx context.Context, deviceID string) (*[]entity.MonthlyElectricity,error)
+	GetMonthlyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData) (*[]entity.MonthlyElectricity, error)
+
+	UpsertYearlyElectricity(ctx context.Context, yearlyElectricity *entity.YearlyElectricity) error
+	GetYearlyElectricity(ctx context.Context, deviceID string) (*[]entity.YearlyElectricity, error)
 	
-	GetTarrifs(ctx context.Context) (*entity.Tarrifs, error)
+	GetTarrifs(ctx context.Context, tariffClass string) (*entity.Tarrifs, error)
 	
-	GetDailyElectricityList(ctx context.Context, deviceID string, sortBy string, lastDate *utils.TimeData) (*[]entity.DailyElectricity, error)
-	GetDailyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData, lastDate *utils.TimeData, limit int) (*[]entity.DailyElectricity, error)
+	GetDailyElectricityList(ctx context.Context, deviceID string, sortBy string, lastDate *utils.TimeData, loc *time.Location) (*[]entity.DailyElectricity, error)
+	GetDailyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData, lastDate *utils.TimeData, limit int, loc *time.Location) (*[]entity.DailyElectricity, error)
 	
 	GetHourlyElectricityRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData) (*[]entity.HourlyElectricity, error)
 	
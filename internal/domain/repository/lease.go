@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LeaderLease adalah lease bersama yang hanya bisa dipegang satu holder;
+// lease hilang sendiri jika tidak diperpanjang sebelum ttl habis.
+type LeaderLease interface {
+	Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, holder string) error
+	Holder(ctx context.Context) (string, error)
+}
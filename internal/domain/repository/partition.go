@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"metertronik/internal/domain/entity"
+)
+
+type PartitionRepo interface {
+	ListPartitions(ctx context.Context, parent string) ([]entity.Partition, error)
+	CreatePartition(ctx context.Context, partition entity.Partition, timestamp bool) error
+	CreatePartitionIndex(ctx context.Context, partition string, indexName string, column string) error
+	DetachPartition(ctx context.Context, parent string, partition string) error
+	DropPartition(ctx context.Context, partition string) error
+}
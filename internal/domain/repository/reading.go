@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+type ReadingRuleRepo interface {
+	ListReadingRules(ctx context.Context) ([]entity.ReadingRule, error)
+}
+
+type SurgeEventRepo interface {
+	// GetOpenSurgeEvent mengembalikan nil jika device tidak punya event terbuka.
+	GetOpenSurgeEvent(ctx context.Context, deviceID string) (*entity.SurgeEvent, error)
+	CreateSurgeEvent(ctx context.Context, event *entity.SurgeEvent) error
+	// AddSurgeSample mengembalikan nil jika event sudah ditutup.
+	AddSurgeSample(ctx context.Context, id int64, data *entity.RealTimeElectricity) (*entity.SurgeEvent, error)
+	// CloseSurgeEvent mengembalikan false jika event sudah ditutup atau
+	// sudah menerima reading setelah endedAt.
+	CloseSurgeEvent(ctx context.Context, id int64, endedAt utils.TimeData) (bool, error)
+	// CloseStaleSurgeEvents menutup event terbuka pada last_sample_at-nya.
+	CloseStaleSurgeEvents(ctx context.Context, before utils.TimeData) (int64, error)
+	// ListSurgeEvents mengembalikan event terbaru lebih dulu.
+	ListSurgeEvents(ctx context.Context, filter entity.SurgeEventFilter) ([]entity.SurgeEvent, error)
+}
+
+type QuarantineRepo interface {
+	SaveQuarantinedReading(ctx context.Context, reading *entity.QuarantinedReading) error
+}
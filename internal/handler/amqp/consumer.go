@@ -2,42 +2,54 @@ package amqp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
-	"metertronik/internal/domain/entity"
 	"metertronik/internal/service"
 	"metertronik/pkg/utils"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type Consumer struct {
-	svc *service.IngestService
-	cfg *ConsumerConfig
+	pipeline *service.IngestPipeline
+	cfg      *ConsumerConfig
 }
 
 type ConsumerConfig struct {
+	URL           string
 	QueueName     string
 	RoutingKey    string
 	Exchange      string
 	PrefetchCount int
 	RetryDelay    time.Duration
 	LogInterval   time.Duration
+
+	MaxRetries         int
+	RetryTTL           time.Duration
+	DeadLetterExchange string
+	DeadLetterQueue    string
+}
+
+// RetryQueueName adalah antrian tunda tempat pesan gagal menunggu RetryTTL
+// sebelum dikembalikan ke antrian utama.
+func (cfg *ConsumerConfig) RetryQueueName() string {
+	return cfg.QueueName + ".retry"
 }
 
-func NewConsumer(svc *service.IngestService, cfg *ConsumerConfig) *Consumer {
+func NewConsumer(pipeline *service.IngestPipeline, cfg *ConsumerConfig) *Consumer {
 	return &Consumer{
-		svc: svc,
-		cfg: cfg,
+		pipeline: pipeline,
+		cfg:      cfg,
 	}
 }
 
-func (c *Consumer) StartConsuming(ctx context.Context, connStr string) error {
+func (c *Consumer) StartConsuming(ctx context.Context) error {
 	retryDelay := c.cfg.RetryDelay
 
 	for {
-		err := c.consumeWithReconnect(ctx, connStr)
+		err := c.consumeWithReconnect(ctx, c.cfg.URL)
 		if err != nil {
 		}
 
@@ -124,6 +136,14 @@ func (c *Consumer) consumeWithReconnect(ctx context.Context, connStr string) err
 		return err
 	}
 
+	if err := declareRetryTopology(ch, c.cfg); err != nil {
+		if amqpErr, ok := err.(*amqp.Error); ok {
+			log.Printf("   Error Code: %d", amqpErr.Code)
+			log.Printf("   Error Reason: %s", amqpErr.Reason)
+		}
+		return err
+	}
+
 	err = ch.Qos(
 		c.cfg.PrefetchCount,
 		0,
@@ -135,7 +155,7 @@ func (c *Consumer) consumeWithReconnect(ctx context.Context, connStr string) err
 	msgs, err := ch.Consume(
 		q.Name,
 		"",
-		true,
+		false,
 		false,
 		false,
 		false,
@@ -151,7 +171,7 @@ func (c *Consumer) consumeWithReconnect(ctx context.Context, connStr string) err
 
 	done := make(chan error, 1)
 
-	go processMessages(c, ctx, msgs, done)
+	go processMessages(c, ctx, ch, msgs, done)
 
 	select {
 	case err := <-notifyClose:
@@ -172,9 +192,16 @@ func (c *Consumer) consumeWithReconnect(ctx context.Context, connStr string) err
 	}
 }
 
-func processMessages(c *Consumer, ctx context.Context, msgs <-chan amqp.Delivery, done chan<- error) {
+// processMessages memproses delivery satu per satu sesuai urutan antrian,
+// tetapi ack-nya menunggu point InfluxDB ter-flush di goroutine tersendiri.
+// Jumlah delivery yang menunggu dibatasi PrefetchCount oleh broker, sehingga
+// point-point tersebut bisa berbagi satu batch.
+func processMessages(c *Consumer, ctx context.Context, ch *amqp.Channel, msgs <-chan amqp.Delivery, done chan<- error) {
 	defer close(done)
 
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
 	messageCount := 0
 	lastMessageTime := utils.TimeNow()
 	ticker := time.NewTicker(c.cfg.LogInterval)
@@ -201,21 +228,20 @@ func processMessages(c *Consumer, ctx context.Context, msgs <-chan amqp.Delivery
 			log.Printf("Received message #%d", messageCount)
 			log.Printf("Message delivery tag: %d, Exchange: %s, RoutingKey: %s", d.DeliveryTag, d.Exchange, d.RoutingKey)
 
-			var data entity.RealTimeElectricity
-
-			log.Printf("Unmarshaling message body (size: %d bytes)...", len(d.Body))
-			err := json.Unmarshal(d.Body, &data)
+			log.Printf("Processing message body (size: %d bytes)...", len(d.Body))
+			// Waktu terima diambil dari jam server, bukan d.Timestamp yang diisi
+			// publisher (device) sendiri.
+			pending, err := c.pipeline.Submit(ctx, d.Body, "", utils.TimeNow().Time)
 			if err != nil {
-				log.Printf("Failed to unmarshal message: %v", err)
-				log.Printf("   Message body: %s", string(d.Body))
+				c.settleError(ctx, ch, d, messageCount, err)
 				continue
 			}
-			log.Printf("Message unmarshaled successfully. DeviceID: %s", data.DeviceID)
 
-			log.Printf("Processing electricity data for device: %s...", data.DeviceID)
-			if err := c.svc.ProcessRealTimeElectricity(ctx, &data); err != nil {
-				log.Printf("Error processing electricity data: %v", err)
-			}
+			inflight.Add(1)
+			go func(d amqp.Delivery, number int) {
+				defer inflight.Done()
+				c.settle(ctx, ch, d, number, pending)
+			}(d, messageCount)
 
 			if messageCount == 20 {
 				log.Printf("===== Reached message #20, continuing to wait for message #21... =====")
@@ -223,3 +249,42 @@ func processMessages(c *Consumer, ctx context.Context, msgs <-chan amqp.Delivery
 		}
 	}
 }
+
+// settle menunggu point delivery tersimpan (di InfluxDB atau spill file)
+// lalu meng-ack-nya, atau mengirimnya ke retry bila gagal.
+func (c *Consumer) settle(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, number int, pending *service.PendingReading) {
+	if err := pending.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			// Consumer berhenti; delivery yang belum di-ack akan dikirim
+			// ulang oleh broker.
+			return
+		}
+		c.settleError(ctx, ch, d, number, err)
+		return
+	}
+
+	log.Printf("Message processed successfully. DeviceID: %s", pending.Data.DeviceID)
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message #%d: %v", number, err)
+	}
+}
+
+func (c *Consumer) settleError(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, number int, err error) {
+	var quarantineErr *service.QuarantineError
+	if errors.As(err, &quarantineErr) {
+		if err := d.Ack(false); err != nil {
+			log.Printf("Failed to ack message #%d: %v", number, err)
+		}
+		return
+	}
+
+	var rejectErr *service.RejectError
+	if errors.As(err, &rejectErr) {
+		log.Printf("Rejected message: %v", err)
+		c.deadLetter(ctx, ch, d, err)
+		return
+	}
+
+	log.Printf("Error processing electricity data: %v", err)
+	c.retryOrDeadLetter(ctx, ch, d, err)
+}
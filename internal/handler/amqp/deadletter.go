@@ -0,0 +1,162 @@
+package amqp
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type DeadLetterMessage struct {
+	DeliveryTag        uint64
+	RetryCount         int
+	LastError          string
+	FailedAt           string
+	OriginalExchange   string
+	OriginalRoutingKey string
+	Body               []byte
+}
+
+type DeadLetterTool struct {
+	cfg *ConsumerConfig
+}
+
+func NewDeadLetterTool(cfg *ConsumerConfig) *DeadLetterTool {
+	return &DeadLetterTool{
+		cfg: cfg,
+	}
+}
+
+// Inspect membaca hingga limit pesan dari dead-letter queue tanpa
+// menghapusnya; semua pesan dikembalikan ke antrian setelah dibaca.
+func (t *DeadLetterTool) Inspect(ctx context.Context, connStr string, limit int) ([]DeadLetterMessage, error) {
+	conn, ch, err := t.open(connStr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	var messages []DeadLetterMessage
+	var lastTag uint64
+
+	for len(messages) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		d, ok, err := ch.Get(t.cfg.DeadLetterQueue, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead-letter message: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		lastTag = d.DeliveryTag
+		messages = append(messages, toDeadLetterMessage(d))
+	}
+
+	if lastTag > 0 {
+		if err := ch.Nack(lastTag, true, true); err != nil {
+			return nil, fmt.Errorf("failed to requeue dead-letter messages: %w", err)
+		}
+	}
+
+	return messages, nil
+}
+
+// Redrive memublikasikan ulang hingga limit pesan dari dead-letter queue ke
+// exchange dan routing key asalnya dengan budget retry yang direset.
+func (t *DeadLetterTool) Redrive(ctx context.Context, connStr string, limit int) (int, error) {
+	conn, ch, err := t.open(connStr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	redriven := 0
+
+	for redriven < limit {
+		if err := ctx.Err(); err != nil {
+			return redriven, err
+		}
+
+		d, ok, err := ch.Get(t.cfg.DeadLetterQueue, false)
+		if err != nil {
+			return redriven, fmt.Errorf("failed to get dead-letter message: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		msg := toDeadLetterMessage(d)
+
+		exchange := msg.OriginalExchange
+		routingKey := msg.OriginalRoutingKey
+		if routingKey == "" {
+			exchange = t.cfg.Exchange
+			routingKey = t.cfg.RoutingKey
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		delete(headers, headerRetryCount)
+
+		err = ch.PublishWithContext(ctx, exchange, routingKey, false, false, amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    d.Timestamp,
+			Body:         d.Body,
+		})
+		if err != nil {
+			d.Nack(false, true)
+			return redriven, fmt.Errorf("failed to redrive message: %w", err)
+		}
+
+		if err := d.Ack(false); err != nil {
+			return redriven, fmt.Errorf("failed to ack redriven message: %w", err)
+		}
+
+		redriven++
+	}
+
+	return redriven, nil
+}
+
+func (t *DeadLetterTool) open(connStr string) (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.Dial(connStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if err := declareDeadLetterTopology(ch, t.cfg); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, ch, nil
+}
+
+func toDeadLetterMessage(d amqp.Delivery) DeadLetterMessage {
+	return DeadLetterMessage{
+		DeliveryTag:        d.DeliveryTag,
+		RetryCount:         headerInt(d.Headers, headerRetryCount),
+		LastError:          headerString(d.Headers, headerLastError),
+		FailedAt:           headerString(d.Headers, headerFailedAt),
+		OriginalExchange:   headerString(d.Headers, headerOriginalExchange),
+		OriginalRoutingKey: headerString(d.Headers, headerOriginalRoutingKey),
+		Body:               d.Body,
+	}
+}
@@ -0,0 +1,181 @@
+package amqp
+
+import (
+	"context"
+	"log"
+	"metertronik/pkg/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	headerRetryCount         = "x-retry-count"
+	headerLastError          = "x-last-error"
+	headerOriginalExchange   = "x-original-exchange"
+	headerOriginalRoutingKey = "x-original-routing-key"
+	headerFailedAt           = "x-failed-at"
+)
+
+func declareRetryTopology(ch *amqp.Channel, cfg *ConsumerConfig) error {
+	_, err := ch.QueueDeclare(
+		cfg.RetryQueueName(),
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-message-ttl":             cfg.RetryTTL.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": cfg.QueueName,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return declareDeadLetterTopology(ch, cfg)
+}
+
+func declareDeadLetterTopology(ch *amqp.Channel, cfg *ConsumerConfig) error {
+	if err := ch.ExchangeDeclare(
+		cfg.DeadLetterExchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	if _, err := ch.QueueDeclare(
+		cfg.DeadLetterQueue,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		cfg.DeadLetterQueue,
+		cfg.QueueName,
+		cfg.DeadLetterExchange,
+		false,
+		nil,
+	)
+}
+
+// retryOrDeadLetter memindahkan pesan yang gagal diproses ke antrian retry
+// selama budget retry masih tersisa, lalu ke dead-letter queue jika habis.
+func (c *Consumer) retryOrDeadLetter(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, cause error) {
+	retryCount := headerInt(d.Headers, headerRetryCount)
+
+	if retryCount >= c.cfg.MaxRetries {
+		log.Printf("Retry budget exhausted (%d/%d), moving message to dead-letter queue", retryCount, c.cfg.MaxRetries)
+		c.deadLetter(ctx, ch, d, cause)
+		return
+	}
+
+	headers := failureHeaders(d, cause)
+	headers[headerRetryCount] = int32(retryCount + 1)
+
+	err := ch.PublishWithContext(ctx, "", c.cfg.RetryQueueName(), false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	})
+	if err != nil {
+		log.Printf("Failed to publish message to retry queue: %v", err)
+		if err := d.Nack(false, true); err != nil {
+			log.Printf("Failed to nack message: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Message scheduled for retry %d/%d in %s", retryCount+1, c.cfg.MaxRetries, c.cfg.RetryTTL)
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message: %v", err)
+	}
+}
+
+// deadLetter mengirim pesan ke dead-letter exchange tanpa retry, dipakai
+// untuk pesan yang tidak mungkin berhasil diproses (poison message).
+func (c *Consumer) deadLetter(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, cause error) {
+	headers := failureHeaders(d, cause)
+	headers[headerRetryCount] = int32(headerInt(d.Headers, headerRetryCount))
+
+	err := ch.PublishWithContext(ctx, c.cfg.DeadLetterExchange, c.cfg.QueueName, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	})
+	if err != nil {
+		log.Printf("Failed to publish message to dead-letter exchange: %v", err)
+		if err := d.Nack(false, true); err != nil {
+			log.Printf("Failed to nack message: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Message moved to dead-letter queue %s: %v", c.cfg.DeadLetterQueue, cause)
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message: %v", err)
+	}
+}
+
+func failureHeaders(d amqp.Delivery, cause error) amqp.Table {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	if _, ok := headers[headerOriginalExchange]; !ok {
+		headers[headerOriginalExchange] = d.Exchange
+	}
+	if _, ok := headers[headerOriginalRoutingKey]; !ok {
+		headers[headerOriginalRoutingKey] = d.RoutingKey
+	}
+
+	headers[headerLastError] = cause.Error()
+	headers[headerFailedAt] = utils.TimeNow().Format()
+
+	return headers
+}
+
+func headerInt(headers amqp.Table, key string) int {
+	switch v := headers[key].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func headerString(headers amqp.Table, key string) string {
+	if v, ok := headers[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package amqp
+
+import (
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestHeaderInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{name: "missing", headers: amqp.Table{}, want: 0},
+		{name: "nil table", headers: nil, want: 0},
+		{name: "int32", headers: amqp.Table{headerRetryCount: int32(3)}, want: 3},
+		{name: "int64", headers: amqp.Table{headerRetryCount: int64(4)}, want: 4},
+		{name: "int8", headers: amqp.Table{headerRetryCount: int8(6)}, want: 6},
+		{name: "int16", headers: amqp.Table{headerRetryCount: int16(2)}, want: 2},
+		{name: "uint8", headers: amqp.Table{headerRetryCount: uint8(1)}, want: 1},
+		{name: "int", headers: amqp.Table{headerRetryCount: 5}, want: 5},
+		{name: "string is ignored", headers: amqp.Table{headerRetryCount: "3"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headerInt(tt.headers, headerRetryCount); got != tt.want {
+				t.Errorf("headerInt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureHeaders(t *testing.T) {
+	tests := []struct {
+		name             string
+		delivery         amqp.Delivery
+		wantExchange     string
+		wantRoutingKey   string
+		wantCustomHeader bool
+	}{
+		{
+			name: "first failure records origin",
+			delivery: amqp.Delivery{
+				Exchange:   "electricity",
+				RoutingKey: "reading.dev-1",
+				Headers:    amqp.Table{"x-custom": "keep"},
+			},
+			wantExchange:     "electricity",
+			wantRoutingKey:   "reading.dev-1",
+			wantCustomHeader: true,
+		},
+		{
+			// Pesan dari antrian retry datang lewat default exchange; asal
+			// pesan dari kegagalan pertama harus dipertahankan.
+			name: "retried message keeps original origin",
+			delivery: amqp.Delivery{
+				Exchange:   "",
+				RoutingKey: "electricity.queue",
+				Headers: amqp.Table{
+					headerOriginalExchange:   "electricity",
+					headerOriginalRoutingKey: "reading.dev-1",
+					headerRetryCount:         int32(1),
+				},
+			},
+			wantExchange:   "electricity",
+			wantRoutingKey: "reading.dev-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := failureHeaders(tt.delivery, errors.New("influx down"))
+
+			if got := headerString(headers, headerOriginalExchange); got != tt.wantExchange {
+				t.Errorf("original exchange = %q, want %q", got, tt.wantExchange)
+			}
+			if got := headerString(headers, headerOriginalRoutingKey); got != tt.wantRoutingKey {
+				t.Errorf("original routing key = %q, want %q", got, tt.wantRoutingKey)
+			}
+			if got := headerString(headers, headerLastError); got != "influx down" {
+				t.Errorf("last error = %q, want %q", got, "influx down")
+			}
+			if headerString(headers, headerFailedAt) == "" {
+				t.Error("failed-at header is empty")
+			}
+			if _, ok := headers["x-custom"]; ok != tt.wantCustomHeader {
+				t.Errorf("custom header present = %t, want %t", ok, tt.wantCustomHeader)
+			}
+			if _, ok := tt.delivery.Headers[headerLastError]; ok {
+				t.Error("failureHeaders modified the delivery headers")
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"errors"
+	aggregate "metertronik/internal/service"
+	"metertronik/internal/service/http"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService: deviceService,
+	}
+}
+
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	data, err := h.deviceService.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) GetDevice(c *gin.Context) {
+	data, err := h.deviceService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
+	var req service.DeviceInput
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.deviceService.Update(c.Request.Context(), currentUserID(c), c.Param("id"), req)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device updated",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	if err := h.deviceService.Delete(c.Request.Context(), currentUserID(c), deviceID); err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device removed from account",
+		"id":      deviceID,
+	})
+}
+
+type AddMemberRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+type ClaimDeviceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Secret   string `json:"secret" binding:"required"`
+}
+
+type TransferDeviceRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+func (h *DeviceHandler) ClaimDevice(c *gin.Context) {
+	var req ClaimDeviceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.deviceService.Claim(c.Request.Context(), currentUserID(c), req.DeviceID, req.Secret)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device claimed",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) UnclaimDevice(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	if err := h.deviceService.Unclaim(c.Request.Context(), currentUserID(c), deviceID); err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device unclaimed",
+		"id":      deviceID,
+	})
+}
+
+func (h *DeviceHandler) TransferDevice(c *gin.Context) {
+	var req TransferDeviceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.deviceService.Transfer(c.Request.Context(), currentUserID(c), c.Param("id"), req.Identifier)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device transferred",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) ListMembers(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	data, err := h.deviceService.ListMembers(c.Request.Context(), deviceID)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      deviceID,
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) AddMember(c *gin.Context) {
+	var req AddMemberRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.deviceService.AddMember(c.Request.Context(), currentUserID(c), c.Param("id"), req.Identifier)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added",
+		"data":    data,
+	})
+}
+
+func (h *DeviceHandler) RemoveMember(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	memberID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "user_id must be a number",
+		})
+		return
+	}
+
+	if err := h.deviceService.RemoveMember(c.Request.Context(), currentUserID(c), deviceID, memberID); err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed",
+		"id":      deviceID,
+		"user_id": memberID,
+	})
+}
+
+func (h *DeviceHandler) ListIngestKeys(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	data, err := h.deviceService.IngestKeys(c.Request.Context(), currentUserID(c), deviceID)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      deviceID,
+		"data":    data,
+	})
+}
+
+// RotateIngestKey mengembalikan secret dan API key baru satu kali; keduanya
+// harus segera dipasang di firmware device.
+func (h *DeviceHandler) RotateIngestKey(c *gin.Context) {
+	data, err := h.deviceService.RotateIngestKey(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Ingest key rotated",
+		"data":    data,
+	})
+}
+
+// currentUserID membaca user id yang diset JWTMiddleware.
+func currentUserID(c *gin.Context) int64 {
+	return int64(c.GetInt("user_id"))
+}
+
+func deviceError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &validationErr):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrDeviceNotFound),
+		errors.Is(err, service.ErrUserNotFound),
+		errors.Is(err, service.ErrMemberNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrNotDeviceOwner),
+		errors.Is(err, service.ErrInvalidClaim):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrDeviceAlreadyClaimed):
+		status = http.StatusConflict
+	case errors.Is(err, aggregate.ErrIngestKeysDisabled):
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
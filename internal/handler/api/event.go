@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"metertronik/internal/service/http"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventHandler struct {
+	eventService *service.EventService
+}
+
+func NewEventHandler(eventService *service.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// GetSurgeEvents mendukung query start, end, min_magnitude, status
+// (open/closed), limit dan last (id event terakhir halaman sebelumnya).
+func (h *EventHandler) GetSurgeEvents(c *gin.Context) {
+	id := c.Param("id")
+
+	data, err := h.eventService.SurgeEvents(c.Request.Context(), id, service.EventQuery{
+		Start:        c.Query("start"),
+		End:          c.Query("end"),
+		MinMagnitude: c.Query("min_magnitude"),
+		Status:       c.Query("status"),
+		Last:         c.Query("last"),
+		Limit:        c.Query("limit"),
+	})
+
+	if err != nil {
+		eventError(c, err)
+		return
+	}
+
+	var lastID int64
+	if len(data) > 0 {
+		lastID = data[len(data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      id,
+		"data":    data,
+		"last_id": lastID,
+	})
+}
+
+func eventError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+
+	status := http.StatusInternalServerError
+	if errors.As(err, &validationErr) {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
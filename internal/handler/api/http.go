@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"metertronik/internal/service/http"
 	"net/http"
 
@@ -121,3 +122,67 @@ func (h *ApiHandler) GetMonthlyList(c *gin.Context) {
 		"data":    data,
 	})
 }
+
+func (h *ApiHandler) GetYearlyList(c *gin.Context) {
+	id := c.Param("id")
+	date := c.Query("date")
+
+	data, err := h.apiService.YearlyList(c.Request.Context(), id, date)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      id,
+		"data":    data,
+	})
+}
+
+func (h *ApiHandler) GetRealtimeHistory(c *gin.Context) {
+	id := c.Param("id")
+	window := c.Query("window")
+
+	data, duration, err := h.apiService.RealtimeHistory(c.Request.Context(), id, window)
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidWindow) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      id,
+		"window":  duration.String(),
+		"data":    data,
+	})
+}
+
+func (h *ApiHandler) GetClockStats(c *gin.Context) {
+	id := c.Param("id")
+
+	data, err := h.apiService.ClockStats(c.Request.Context(), id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"id":      id,
+		"data":    data,
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"metertronik/internal/service/http"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ThresholdPolicyHandler struct {
+	policyService *service.ThresholdPolicyService
+}
+
+func NewThresholdPolicyHandler(policyService *service.ThresholdPolicyService) *ThresholdPolicyHandler {
+	return &ThresholdPolicyHandler{
+		policyService: policyService,
+	}
+}
+
+func (h *ThresholdPolicyHandler) ListPolicies(c *gin.Context) {
+	data, err := h.policyService.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		policyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OK",
+		"data":    data,
+	})
+}
+
+func (h *ThresholdPolicyHandler) CreatePolicy(c *gin.Context) {
+	var req service.ThresholdPolicyInput
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.policyService.Create(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		policyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Threshold policy created",
+		"data":    data,
+	})
+}
+
+func (h *ThresholdPolicyHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := policyID(c)
+	if !ok {
+		return
+	}
+
+	var req service.ThresholdPolicyInput
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data, err := h.policyService.Update(c.Request.Context(), currentUserID(c), id, req)
+	if err != nil {
+		policyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Threshold policy updated",
+		"data":    data,
+	})
+}
+
+func (h *ThresholdPolicyHandler) DeletePolicy(c *gin.Context) {
+	id, ok := policyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.policyService.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		policyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Threshold policy deleted",
+		"id":      id,
+	})
+}
+
+func policyID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("policy_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "policy_id must be a number",
+		})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func policyError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &validationErr):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrPolicyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrPolicyReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrPolicyExists):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
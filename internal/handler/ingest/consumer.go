@@ -0,0 +1,10 @@
+package ingest
+
+import "context"
+
+// Consumer adalah transport ingest berbasis broker (AMQP atau MQTT). Semua
+// implementasi memproses pesan lewat service.IngestPipeline yang sama dan
+// baru meng-ack pesan setelah pemrosesan selesai.
+type Consumer interface {
+	StartConsuming(ctx context.Context) error
+}
@@ -0,0 +1,200 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"metertronik/internal/service"
+	"metertronik/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	headerDeviceID = "X-Device-ID"
+	headerAPIKey   = "X-API-Key"
+)
+
+// Status per reading pada response ingest HTTP. failed berarti gagal
+// sementara (mis. InfluxDB tidak tersedia) dan boleh dikirim ulang.
+const (
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+	StatusFailed   = "failed"
+)
+
+var httpReadingsTotal = expvar.NewMap("ingest_http_readings_total")
+
+type ReadingResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HTTPHandler struct {
+	pipeline       *service.IngestPipeline
+	verifier       *service.IngestVerifier
+	deviceCache    *service.DeviceCache
+	defaultMaxBody int64
+	maxBatch       int
+}
+
+// NewHTTPHandler membuat handler POST /v1/ingest untuk device yang tidak
+// bisa menjangkau broker. defaultMaxBody dipakai untuk device yang tidak
+// punya batas sendiri.
+func NewHTTPHandler(pipeline *service.IngestPipeline, verifier *service.IngestVerifier, deviceCache *service.DeviceCache, defaultMaxBody int64, maxBatch int) *HTTPHandler {
+	return &HTTPHandler{
+		pipeline:       pipeline,
+		verifier:       verifier,
+		deviceCache:    deviceCache,
+		defaultMaxBody: defaultMaxBody,
+		maxBatch:       maxBatch,
+	}
+}
+
+// Ingest menerima satu reading (objek JSON) atau batch (array JSON) untuk
+// device pada header X-Device-ID, diautentikasi dengan X-API-Key.
+func (h *HTTPHandler) Ingest(c *gin.Context) {
+	ctx := c.Request.Context()
+	receivedAt := utils.TimeNow().Time
+	deviceID := c.GetHeader(headerDeviceID)
+	apiKey := c.GetHeader(headerAPIKey)
+
+	if deviceID == "" || apiKey == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "X-Device-ID and X-API-Key headers are required"})
+		return
+	}
+
+	if err := h.verifier.AuthenticateAPIKey(ctx, deviceID, apiKey); err != nil {
+		var rejectErr *service.RejectError
+		if errors.As(err, &rejectErr) {
+			log.Printf("HTTP ingest rejected for device %s: %v", deviceID, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid device credentials"})
+			return
+		}
+		log.Printf("Failed to authenticate HTTP ingest for device %s: %v", deviceID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify device credentials"})
+		return
+	}
+
+	maxBody := h.defaultMaxBody
+	if device := h.deviceCache.Get(ctx, deviceID); device.IngestMaxBodyBytes > 0 {
+		maxBody = device.IngestMaxBodyBytes
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxBody)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	readings, err := splitReadings(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(readings) > h.maxBatch {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("at most %d readings per request", h.maxBatch)})
+		return
+	}
+
+	payloads := make([][]byte, len(readings))
+	for i, raw := range readings {
+		payloads[i] = raw
+	}
+
+	// Semua reading diantrekan dulu agar masuk ke batch InfluxDB yang sama,
+	// baru kemudian ditunggu.
+	pendings, errs := h.pipeline.SubmitPayloads(ctx, payloads, deviceID, receivedAt)
+	for i, pending := range pendings {
+		if errs[i] == nil {
+			errs[i] = pending.Wait(ctx)
+		}
+	}
+
+	results := make([]ReadingResult, len(readings))
+	counts := map[string]int{}
+
+	for i, err := range errs {
+		results[i] = readingResult(deviceID, i, err)
+		counts[results[i].Status]++
+		httpReadingsTotal.Add(results[i].Status, 1)
+	}
+
+	status := http.StatusOK
+	switch {
+	case counts[StatusAccepted] == len(results):
+	case counts[StatusAccepted] == 0 && counts[StatusFailed] > 0:
+		status = http.StatusServiceUnavailable
+	case counts[StatusAccepted] == 0:
+		status = http.StatusUnprocessableEntity
+	default:
+		status = http.StatusMultiStatus
+	}
+
+	c.JSON(status, gin.H{
+		"device_id": deviceID,
+		"accepted":  counts[StatusAccepted],
+		"rejected":  counts[StatusRejected],
+		"failed":    counts[StatusFailed],
+		"results":   results,
+	})
+}
+
+// readingResult memetakan hasil pipeline untuk satu reading ke status
+// response.
+func readingResult(deviceID string, index int, err error) ReadingResult {
+	if err == nil {
+		return ReadingResult{Index: index, Status: StatusAccepted}
+	}
+
+	var quarantineErr *service.QuarantineError
+	if errors.As(err, &quarantineErr) {
+		return ReadingResult{Index: index, Status: StatusRejected, Reason: quarantineErr.Violation.Reason, Error: quarantineErr.Violation.Detail}
+	}
+
+	var rejectErr *service.RejectError
+	if errors.As(err, &rejectErr) {
+		return ReadingResult{Index: index, Status: StatusRejected, Reason: rejectErr.Reason, Error: rejectErr.Err.Error()}
+	}
+
+	log.Printf("HTTP ingest failed for device %s reading %d: %v", deviceID, index, err)
+	return ReadingResult{Index: index, Status: StatusFailed, Error: "failed to store reading, retry later"}
+}
+
+// splitReadings menerima objek tunggal atau array reading.
+func splitReadings(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, errors.New("request body is empty")
+	}
+
+	if body[0] != '[' {
+		if !json.Valid(body) {
+			return nil, errors.New("request body is not valid JSON")
+		}
+		return []json.RawMessage{body}, nil
+	}
+
+	var readings []json.RawMessage
+	if err := json.Unmarshal(body, &readings); err != nil {
+		return nil, errors.New("request body is not a valid JSON array")
+	}
+	if len(readings) == 0 {
+		return nil, errors.New("request contains no readings")
+	}
+
+	return readings, nil
+}
@@ -0,0 +1,276 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"metertronik/internal/service"
+	"metertronik/pkg/utils"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Semua subscription memakai QoS 1 agar pesan baru di-ack setelah diproses.
+const subscribeQoS = 1
+
+const connectTimeout = 30 * time.Second
+
+type ConsumerConfig struct {
+	BrokerURL    string
+	ClientID     string
+	Username     string
+	Password     string
+	Topics       []string
+	CleanSession bool
+
+	MaxRetries int
+	// MaxInflight membatasi pesan yang sudah diproses tetapi belum di-ack
+	// karena point-nya menunggu flush batch InfluxDB.
+	MaxInflight    int
+	RetryDelay     time.Duration
+	ReconnectDelay time.Duration
+}
+
+// inflightMessage adalah pesan yang menunggu point-nya tersimpan sebelum
+// di-ack.
+type inflightMessage struct {
+	msg        paho.Message
+	deviceID   string
+	receivedAt time.Time
+	pending    *service.PendingReading
+}
+
+type Consumer struct {
+	pipeline *service.IngestPipeline
+	cfg      *ConsumerConfig
+	patterns []*TopicPattern
+}
+
+func NewConsumer(pipeline *service.IngestPipeline, cfg *ConsumerConfig) (*Consumer, error) {
+	if len(cfg.Topics) == 0 {
+		return nil, errors.New("at least one MQTT topic pattern is required")
+	}
+
+	patterns := make([]*TopicPattern, 0, len(cfg.Topics))
+	for _, topic := range cfg.Topics {
+		pattern, err := ParseTopicPattern(topic)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return &Consumer{
+		pipeline: pipeline,
+		cfg:      cfg,
+		patterns: patterns,
+	}, nil
+}
+
+func (c *Consumer) StartConsuming(ctx context.Context) error {
+	for {
+		err := c.consume(ctx)
+		if err != nil {
+			log.Printf("MQTT consumer disconnected: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.cfg.ReconnectDelay):
+		}
+	}
+}
+
+// consume menjalankan satu sesi koneksi. Pesan yang gagal diproses setelah
+// retry habis tidak di-ack; sesi diputus agar broker mengirim ulang pesan
+// tersebut saat reconnect (butuh CleanSession=false dan ClientID tetap).
+func (c *Consumer) consume(ctx context.Context) error {
+	failed := make(chan error, 1)
+	var stopping atomic.Bool
+
+	fail := func(err error) {
+		stopping.Store(true)
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(c.cfg.BrokerURL).
+		SetClientID(c.cfg.ClientID).
+		SetUsername(c.cfg.Username).
+		SetPassword(c.cfg.Password).
+		SetCleanSession(c.cfg.CleanSession).
+		SetAutoReconnect(false).
+		SetAutoAckDisabled(true).
+		SetOrderMatters(true).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			fail(fmt.Errorf("connection lost: %w", err))
+		})
+
+	inflight := make(chan *inflightMessage, max(c.cfg.MaxInflight, 1))
+	sessionDone := make(chan struct{})
+	go c.ackInOrder(ctx, inflight, sessionDone, &stopping, fail)
+
+	client := paho.NewClient(opts)
+
+	token := client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		return errors.New("timed out connecting to MQTT broker")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	defer client.Disconnect(250)
+	defer close(sessionDone)
+
+	filters := make(map[string]byte, len(c.patterns))
+	for _, pattern := range c.patterns {
+		filters[pattern.Filter()] = subscribeQoS
+	}
+
+	handler := func(_ paho.Client, msg paho.Message) {
+		if stopping.Load() {
+			return
+		}
+		item, err := c.handle(ctx, msg)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if item == nil {
+			return
+		}
+
+		// Antrian penuh menahan handler sehingga broker berhenti mengirim.
+		select {
+		case inflight <- item:
+		case <-sessionDone:
+		}
+	}
+
+	token = client.SubscribeMultiple(filters, handler)
+	if !token.WaitTimeout(connectTimeout) {
+		return errors.New("timed out subscribing to MQTT topics")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to subscribe to MQTT topics: %w", err)
+	}
+
+	log.Printf("MQTT consumer subscribed to %d topic(s) on %s", len(filters), c.cfg.BrokerURL)
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle memproses satu pesan tanpa menunggu flush InfluxDB. Pesan yang
+// dikarantina atau ditolak permanen langsung di-ack (MQTT tidak punya
+// dead-letter queue; penolakan tercatat di metrik ingest_rejected_total) dan
+// menghasilkan nil. Error dikembalikan hanya bila pesan gagal sementara dan
+// retry sudah habis.
+func (c *Consumer) handle(ctx context.Context, msg paho.Message) (*inflightMessage, error) {
+	deviceID, ok := c.deviceID(msg.Topic())
+	if !ok {
+		log.Printf("Dropping message on unmapped topic %s", msg.Topic())
+		msg.Ack()
+		return nil, nil
+	}
+
+	receivedAt := utils.TimeNow().Time
+
+	pending, err := c.process(ctx, msg, deviceID, receivedAt, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		msg.Ack()
+		return nil, nil
+	}
+
+	return &inflightMessage{msg: msg, deviceID: deviceID, receivedAt: receivedAt, pending: pending}, nil
+}
+
+// ackInOrder meng-ack pesan sesuai urutan terima setelah point-nya tersimpan.
+// Jika penyimpanan gagal, pesan diproses ulang secara sinkron; bila tetap
+// gagal, sesi diputus dan pesan ini beserta pesan sesudahnya tidak di-ack.
+func (c *Consumer) ackInOrder(ctx context.Context, inflight <-chan *inflightMessage, sessionDone <-chan struct{}, stopping *atomic.Bool, fail func(error)) {
+	for {
+		select {
+		case <-sessionDone:
+			return
+		case item := <-inflight:
+			if stopping.Load() {
+				continue
+			}
+
+			if err := item.pending.Wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Error storing message on topic %s (retry 1/%d): %v", item.msg.Topic(), c.cfg.MaxRetries, err)
+				if _, err := c.process(ctx, item.msg, item.deviceID, item.receivedAt, 1, true); err != nil {
+					fail(err)
+					continue
+				}
+			}
+
+			item.msg.Ack()
+		}
+	}
+}
+
+// process menjalankan pipeline dengan retry untuk error sementara, mulai dari
+// percobaan ke-attempt. wait menentukan apakah flush InfluxDB ikut ditunggu.
+// Pending nil berarti pesan dikarantina atau ditolak permanen.
+func (c *Consumer) process(ctx context.Context, msg paho.Message, deviceID string, receivedAt time.Time, attempt int, wait bool) (*service.PendingReading, error) {
+	for ; ; attempt++ {
+		pending, err := c.pipeline.Submit(ctx, msg.Payload(), deviceID, receivedAt)
+		if err == nil && wait {
+			err = pending.Wait(ctx)
+		}
+		if err == nil {
+			return pending, nil
+		}
+
+		var quarantineErr *service.QuarantineError
+		if errors.As(err, &quarantineErr) {
+			return nil, nil
+		}
+
+		var rejectErr *service.RejectError
+		if errors.As(err, &rejectErr) {
+			log.Printf("Rejected message on topic %s: %v", msg.Topic(), err)
+			return nil, nil
+		}
+
+		if attempt >= c.cfg.MaxRetries {
+			return nil, fmt.Errorf("message on topic %s failed after %d retries: %w", msg.Topic(), attempt, err)
+		}
+
+		log.Printf("Error processing message on topic %s (retry %d/%d): %v", msg.Topic(), attempt+1, c.cfg.MaxRetries, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.cfg.RetryDelay):
+		}
+	}
+}
+
+func (c *Consumer) deviceID(topic string) (string, bool) {
+	for _, pattern := range c.patterns {
+		if deviceID, ok := pattern.DeviceID(topic); ok {
+			return deviceID, true
+		}
+	}
+	return "", false
+}
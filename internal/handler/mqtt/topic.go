@@ -0,0 +1,86 @@
+package mqtt
+
+import (
+	"fmt"
+	"strings"
+)
+
+const deviceIDPlaceholder = "{device_id}"
+
+// TopicPattern memetakan topic MQTT ke device ID, mis.
+// "metertronik/{device_id}/electricity". Segmen {device_id} disubscribe
+// sebagai wildcard "+"; segmen lain boleh berupa literal, "+" atau "#"
+// (hanya di akhir).
+type TopicPattern struct {
+	segments    []string
+	deviceIndex int
+}
+
+func ParseTopicPattern(pattern string) (*TopicPattern, error) {
+	segments := strings.Split(pattern, "/")
+	deviceIndex := -1
+
+	for i, segment := range segments {
+		switch {
+		case segment == deviceIDPlaceholder:
+			if deviceIndex >= 0 {
+				return nil, fmt.Errorf("topic pattern %q has more than one %s", pattern, deviceIDPlaceholder)
+			}
+			deviceIndex = i
+		case segment == "#":
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("topic pattern %q: # must be the last segment", pattern)
+			}
+		case strings.ContainsAny(segment, "+#{}"):
+			if segment != "+" {
+				return nil, fmt.Errorf("topic pattern %q: invalid segment %q", pattern, segment)
+			}
+		}
+	}
+
+	if deviceIndex < 0 {
+		return nil, fmt.Errorf("topic pattern %q must contain %s", pattern, deviceIDPlaceholder)
+	}
+
+	return &TopicPattern{
+		segments:    segments,
+		deviceIndex: deviceIndex,
+	}, nil
+}
+
+// Filter adalah topic filter yang disubscribe ke broker.
+func (p *TopicPattern) Filter() string {
+	filter := make([]string, len(p.segments))
+	copy(filter, p.segments)
+	filter[p.deviceIndex] = "+"
+	return strings.Join(filter, "/")
+}
+
+// DeviceID mengambil device ID dari topic bila topic cocok dengan pattern.
+func (p *TopicPattern) DeviceID(topic string) (string, bool) {
+	parts := strings.Split(topic, "/")
+
+	for i, segment := range p.segments {
+		if segment == "#" {
+			break
+		}
+		if i >= len(parts) {
+			return "", false
+		}
+		if segment != "+" && segment != deviceIDPlaceholder && segment != parts[i] {
+			return "", false
+		}
+	}
+
+	last := p.segments[len(p.segments)-1]
+	if last != "#" && len(parts) != len(p.segments) {
+		return "", false
+	}
+
+	deviceID := parts[p.deviceIndex]
+	if deviceID == "" {
+		return "", false
+	}
+
+	return deviceID, true
+}
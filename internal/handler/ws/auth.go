@@ -0,0 +1,147 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"metertronik/pkg/utils/token"
+
+	"github.com/gorilla/websocket"
+)
+
+// tokenSubprotocol dipakai client browser yang tidak bisa mengirim header
+// Authorization: Sec-WebSocket-Protocol: access_token, <jwt>.
+const tokenSubprotocol = "access_token"
+
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{tokenSubprotocol},
+		CheckOrigin: func(r *http.Request) bool {
+			return originAllowed(r.Header.Get("Origin"), allowedOrigins)
+		},
+	}
+}
+
+// originAllowed mengikuti CORS_ALLOW_ORIGINS. Request tanpa Origin (bukan
+// dari browser) tetap diizinkan karena tetap harus membawa token.
+func originAllowed(origin string, allowedOrigins []string) bool {
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	origin = u.Scheme + "://" + u.Host
+
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func accessToken(r *http.Request) string {
+	if t := r.URL.Query().Get("token"); t != "" {
+		return t
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == tokenSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+
+	return ""
+}
+
+// authenticate memvalidasi access token sebelum upgrade. Jika gagal,
+// response 401 sudah ditulis.
+func (h *StreamHandler) authenticate(w http.ResponseWriter, r *http.Request) (*token.AccessClaims, bool) {
+	tokenStr := accessToken(r)
+	if tokenStr == "" {
+		writeError(w, http.StatusUnauthorized, "missing token")
+		return nil, false
+	}
+
+	claims, err := token.ParseAccessToken(tokenStr)
+	if err != nil {
+		log.Printf("WebSocket token not valid: %v", err)
+		writeError(w, http.StatusUnauthorized, "token expired")
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// authorize memeriksa akses lewat DeviceAuthorizer sehingga penolakan
+// WebSocket ikut tercatat di audit log. clientIP berasal dari c.ClientIP()
+// agar sama dengan route HTTP dan mengikuti trusted proxies gin.
+func (h *StreamHandler) authorize(ctx context.Context, r *http.Request, clientIP string, claims *token.AccessClaims, deviceID string) bool {
+	ok, err := h.authorizer.Authorize(ctx, claims.UserID, deviceID, "WS "+r.URL.Path, clientIP)
+	if err != nil {
+		log.Printf("Failed to check access of user %d to device %s: %v", claims.UserID, deviceID, err)
+		return false
+	}
+
+	return ok
+}
+
+// revoked dipakai pemeriksaan ulang akses: hanya penolakan yang pasti yang
+// dihitung, sehingga gangguan database sesaat tidak memutus stream.
+func (h *StreamHandler) revoked(ctx context.Context, r *http.Request, clientIP string, claims *token.AccessClaims, deviceID string) bool {
+	ok, err := h.authorizer.Authorize(ctx, claims.UserID, deviceID, "WS "+r.URL.Path, clientIP)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to recheck access of user %d to device %s: %v", claims.UserID, deviceID, err)
+		}
+		return false
+	}
+
+	return !ok
+}
+
+// closeOnExpiry menutup socket saat access token kedaluwarsa; client perlu
+// menyambung ulang dengan token baru.
+func closeOnExpiry(ctx context.Context, conn *websocket.Conn, expiresAt time.Time, cancel context.CancelFunc) {
+	timer := time.NewTimer(time.Until(expiresAt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	closeWith(conn, "token expired")
+	cancel()
+}
+
+// closeWith mengirim close frame policy violation dengan alasan tersebut.
+func closeWith(conn *websocket.Conn, reason string) {
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait)); err != nil {
+		log.Printf("Failed to send close frame: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
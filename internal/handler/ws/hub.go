@@ -0,0 +1,216 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/repository"
+)
+
+const clientBufferSize = 32
+
+// Message adalah satu data realtime yang sudah di-marshal sekali oleh Hub
+// dan dibagikan ke semua client yang men-subscribe device tersebut.
+type Message struct {
+	DeviceID string
+	Payload  json.RawMessage
+
+	// Field berikut hanya dipakai koneksi multiplex, yang menaruh frame
+	// balasan dan history di antrean yang sama dengan data live agar
+	// urutannya terjaga. Lihat multiplexConn.encode.
+	frame   []byte
+	hold    bool
+	history bool
+	newest  time.Time
+}
+
+// Client mewakili satu koneksi WebSocket di Hub.
+type Client struct {
+	send chan Message
+}
+
+func NewClient() *Client {
+	return &Client{
+		send: make(chan Message, clientBufferSize),
+	}
+}
+
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// push mengantrekan pesan milik koneksi itu sendiri. Berbeda dengan fan-out
+// Hub, push menunggu sampai ada tempat di buffer atau ctx selesai.
+func (c *Client) push(ctx context.Context, msg Message) {
+	select {
+	case c.send <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// Hub men-subscribe channel Redis satu kali per device, berapa pun jumlah
+// socket yang membukanya, dan melepasnya saat client terakhir pergi.
+type Hub struct {
+	subscriber repository.ElectricitySubscriber
+
+	mu      sync.Mutex
+	devices map[string]map[*Client]struct{}
+	clients map[*Client]map[string]struct{}
+
+	// redisMu menyerialkan SUBSCRIBE/UNSUBSCRIBE ke Redis dan melindungi
+	// subscribed. Panggilan Redis tidak pernah dilakukan sambil memegang mu
+	// agar fan-out di Run tidak ikut menunggu.
+	redisMu    sync.Mutex
+	subscribed map[string]struct{}
+}
+
+func NewHub(subscriber repository.ElectricitySubscriber) *Hub {
+	return &Hub{
+		subscriber: subscriber,
+		devices:    make(map[string]map[*Client]struct{}),
+		clients:    make(map[*Client]map[string]struct{}),
+		subscribed: make(map[string]struct{}),
+	}
+}
+
+// Run meneruskan pesan dari Redis ke client sampai ctx selesai. Client yang
+// buffer-nya penuh melewatkan pesan tersebut agar tidak menahan yang lain.
+func (h *Hub) Run(ctx context.Context) {
+	messages := h.subscriber.Messages()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			payload, err := json.Marshal(data)
+			if err != nil {
+				log.Printf("Failed to marshal stream data for device %s: %v", data.DeviceID, err)
+				continue
+			}
+
+			msg := Message{DeviceID: data.DeviceID, Payload: payload}
+
+			h.mu.Lock()
+			for c := range h.devices[data.DeviceID] {
+				select {
+				case c.send <- msg:
+				default:
+					log.Printf("Client buffer full, dropping update for device %s", data.DeviceID)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+func (h *Hub) Subscribe(ctx context.Context, deviceID string, c *Client) error {
+	h.mu.Lock()
+	if _, ok := h.clients[c][deviceID]; ok {
+		h.mu.Unlock()
+		return nil
+	}
+
+	if h.devices[deviceID] == nil {
+		h.devices[deviceID] = make(map[*Client]struct{})
+	}
+	h.devices[deviceID][c] = struct{}{}
+
+	if h.clients[c] == nil {
+		h.clients[c] = make(map[string]struct{})
+	}
+	h.clients[c][deviceID] = struct{}{}
+	h.mu.Unlock()
+
+	if err := h.sync(ctx, deviceID); err != nil {
+		h.mu.Lock()
+		h.remove(deviceID, c)
+		h.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (h *Hub) Unsubscribe(ctx context.Context, deviceID string, c *Client) {
+	h.mu.Lock()
+	removed := h.remove(deviceID, c)
+	h.mu.Unlock()
+
+	if removed {
+		h.sync(ctx, deviceID)
+	}
+}
+
+// UnsubscribeAll melepas semua device milik client; dipanggil saat socket
+// ditutup.
+func (h *Hub) UnsubscribeAll(ctx context.Context, c *Client) {
+	h.mu.Lock()
+	deviceIDs := make([]string, 0, len(h.clients[c]))
+	for deviceID := range h.clients[c] {
+		h.remove(deviceID, c)
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	delete(h.clients, c)
+	h.mu.Unlock()
+
+	for _, deviceID := range deviceIDs {
+		h.sync(ctx, deviceID)
+	}
+}
+
+// remove melepas client dari device di map; pemanggil memegang mu.
+func (h *Hub) remove(deviceID string, c *Client) bool {
+	if _, ok := h.clients[c][deviceID]; !ok {
+		return false
+	}
+
+	delete(h.clients[c], deviceID)
+	delete(h.devices[deviceID], c)
+	if len(h.devices[deviceID]) == 0 {
+		delete(h.devices, deviceID)
+	}
+
+	return true
+}
+
+// sync menyamakan subscription Redis untuk device dengan isi map: subscribe
+// jika ada client, unsubscribe jika tidak ada lagi. Karena selalu membaca
+// map terbaru di bawah redisMu, panggilan sync terakhir menentukan hasil
+// akhirnya meski Subscribe dan Unsubscribe berjalan bersamaan.
+func (h *Hub) sync(ctx context.Context, deviceID string) error {
+	h.redisMu.Lock()
+	defer h.redisMu.Unlock()
+
+	h.mu.Lock()
+	wanted := len(h.devices[deviceID]) > 0
+	h.mu.Unlock()
+
+	_, subscribed := h.subscribed[deviceID]
+
+	switch {
+	case wanted && !subscribed:
+		if err := h.subscriber.Subscribe(ctx, deviceID); err != nil {
+			return err
+		}
+		h.subscribed[deviceID] = struct{}{}
+		log.Printf("Hub subscribed to device %s", deviceID)
+
+	case !wanted && subscribed:
+		delete(h.subscribed, deviceID)
+		if err := h.subscriber.Unsubscribe(ctx, deviceID); err != nil {
+			log.Printf("Hub failed to unsubscribe device %s: %v", deviceID, err)
+			return nil
+		}
+		log.Printf("Hub unsubscribed from device %s", deviceID)
+	}
+
+	return nil
+}
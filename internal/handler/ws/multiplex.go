@@ -0,0 +1,309 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils/token"
+)
+
+// multiplexConn adalah state satu koneksi multiplex. Frame balasan dan
+// history diantrekan di client yang sama dengan data live, sehingga
+// writePump menulis semuanya sesuai urutan masuk.
+type multiplexConn struct {
+	handler  *StreamHandler
+	request  *http.Request
+	clientIP string
+	claims   *token.AccessClaims
+	client   *Client
+	ctx      context.Context
+
+	// mu melindungi devices, yang juga diubah oleh pemeriksaan ulang akses.
+	mu      sync.Mutex
+	devices map[string]struct{}
+
+	// held menampung data live device yang history-nya belum ditulis. Hanya
+	// diakses dari writePump.
+	held map[string][]Message
+}
+
+// HandleMultiplexWebSocket melayani /v1/ws/stream: satu koneksi untuk banyak
+// device, dikendalikan dengan frame subscribe/unsubscribe/ping.
+func (h *StreamHandler) HandleMultiplexWebSocket(w http.ResponseWriter, r *http.Request, clientIP string) {
+	claims, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade WebSocket connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	mc := &multiplexConn{
+		handler:  h,
+		request:  r,
+		clientIP: clientIP,
+		claims:   claims,
+		client:   NewClient(),
+		ctx:      ctx,
+		devices:  make(map[string]struct{}),
+		held:     make(map[string][]Message),
+	}
+
+	log.Printf("Multiplex WebSocket client connected (user %d)", claims.UserID)
+
+	go closeOnExpiry(ctx, conn, claims.ExpiresAt, cancel)
+	go h.recheckAccess(ctx, mc.recheckAccess)
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		readPump(conn, cancel, mc.handleControl)
+	}()
+
+	writePump(ctx, conn, mc.client, mc.encode)
+
+	// Batalkan ctx agar readPump yang tertahan di reply (buffer penuh) ikut
+	// berhenti, lalu tunggu readPump selesai agar tidak ada subscribe yang
+	// tertinggal setelah semua device dilepas.
+	cancel()
+	conn.Close()
+	<-readDone
+	h.hub.UnsubscribeAll(context.Background(), mc.client)
+
+	mc.mu.Lock()
+	log.Printf("Multiplex WebSocket connection closed (%d device(s))", len(mc.devices))
+	mc.mu.Unlock()
+}
+
+func encodeData(msg Message) ([]byte, error) {
+	return json.Marshal(ServerMessage{
+		Type:     TypeData,
+		DeviceID: msg.DeviceID,
+		Data:     msg.Payload,
+	})
+}
+
+func (mc *multiplexConn) handleControl(data []byte) {
+	var msg ControlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		mc.reply(errorMessage("", "", ErrCodeBadRequest, "invalid JSON message"))
+		return
+	}
+
+	switch msg.Type {
+	case TypePing:
+		mc.reply(ServerMessage{Type: TypePong, ID: msg.ID})
+	case TypeSubscribe:
+		mc.subscribe(msg)
+	case TypeUnsubscribe:
+		mc.unsubscribe(msg)
+	default:
+		mc.reply(errorMessage(msg.ID, "", ErrCodeUnknownType, fmt.Sprintf("unknown message type %q", msg.Type)))
+	}
+}
+
+func (mc *multiplexConn) subscribe(msg ControlMessage) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if len(msg.DeviceIDs) == 0 {
+		mc.reply(errorMessage(msg.ID, "", ErrCodeBadRequest, "device_ids is required"))
+		return
+	}
+
+	var subscribed []string
+
+	for _, deviceID := range msg.DeviceIDs {
+		if deviceID == "" {
+			continue
+		}
+		if _, ok := mc.devices[deviceID]; ok {
+			subscribed = append(subscribed, deviceID)
+			continue
+		}
+
+		if !mc.handler.authorize(mc.ctx, mc.request, mc.clientIP, mc.claims, deviceID) {
+			mc.reply(errorMessage(msg.ID, deviceID, ErrCodeForbidden, "access to device denied"))
+			continue
+		}
+
+		if len(mc.devices) >= maxDevicesPerConn {
+			mc.reply(errorMessage(msg.ID, deviceID, ErrCodeTooManyDevices,
+				fmt.Sprintf("at most %d devices per connection", maxDevicesPerConn)))
+			continue
+		}
+
+		// Tahan data live device ini sampai history-nya ditulis. Penanda
+		// diantrekan sebelum Subscribe sehingga selalu mendahului data live.
+		mc.client.push(mc.ctx, Message{DeviceID: deviceID, hold: true})
+
+		if err := mc.handler.hub.Subscribe(mc.ctx, deviceID, mc.client); err != nil {
+			log.Printf("Failed to subscribe device %s: %v", deviceID, err)
+			mc.client.push(mc.ctx, Message{DeviceID: deviceID, history: true})
+			mc.reply(errorMessage(msg.ID, deviceID, ErrCodeSubscribeFailed, "failed to subscribe device"))
+			continue
+		}
+
+		mc.devices[deviceID] = struct{}{}
+		subscribed = append(subscribed, deviceID)
+		mc.sendHistory(deviceID)
+	}
+
+	if len(subscribed) > 0 {
+		mc.reply(ServerMessage{Type: TypeSubscribed, ID: msg.ID, DeviceIDs: subscribed})
+	}
+}
+
+func (mc *multiplexConn) unsubscribe(msg ControlMessage) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if len(msg.DeviceIDs) == 0 {
+		mc.reply(errorMessage(msg.ID, "", ErrCodeBadRequest, "device_ids is required"))
+		return
+	}
+
+	for _, deviceID := range msg.DeviceIDs {
+		mc.handler.hub.Unsubscribe(context.Background(), deviceID, mc.client)
+		delete(mc.devices, deviceID)
+	}
+
+	mc.reply(ServerMessage{Type: TypeUnsubscribed, ID: msg.ID, DeviceIDs: msg.DeviceIDs})
+}
+
+// recheckAccess memeriksa ulang akses ke setiap device yang di-subscribe dan
+// melepas device yang aksesnya sudah dicabut, disertai frame error
+// access_revoked.
+func (mc *multiplexConn) recheckAccess() {
+	mc.mu.Lock()
+	deviceIDs := make([]string, 0, len(mc.devices))
+	for deviceID := range mc.devices {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	mc.mu.Unlock()
+
+	for _, deviceID := range deviceIDs {
+		if !mc.handler.revoked(mc.ctx, mc.request, mc.clientIP, mc.claims, deviceID) {
+			continue
+		}
+
+		mc.mu.Lock()
+		if _, ok := mc.devices[deviceID]; ok {
+			mc.handler.hub.Unsubscribe(context.Background(), deviceID, mc.client)
+			delete(mc.devices, deviceID)
+			log.Printf("Access of user %d to device %s revoked, unsubscribed", mc.claims.UserID, deviceID)
+			mc.reply(errorMessage("", deviceID, ErrCodeAccessRevoked, "access to device revoked"))
+		}
+		mc.mu.Unlock()
+	}
+}
+
+// sendHistory mengantrekan backfill device sebagai satu frame history.
+// Pesan ini juga melepas data live yang ditahan untuk device tersebut,
+// walaupun history-nya kosong.
+func (mc *multiplexConn) sendHistory(deviceID string) {
+	msg := Message{DeviceID: deviceID, history: true}
+
+	if history := mc.handler.backfill(mc.ctx, deviceID); len(history) > 0 {
+		payload, err := json.Marshal(history)
+		if err != nil {
+			log.Printf("Error marshaling data: %v", err)
+		} else if frame, err := json.Marshal(ServerMessage{Type: TypeHistory, DeviceID: deviceID, Data: payload}); err != nil {
+			log.Printf("Error marshaling reply: %v", err)
+		} else {
+			msg.frame = frame
+			msg.newest = history[len(history)-1].CreatedAt.Time
+		}
+	}
+
+	mc.client.push(mc.ctx, msg)
+}
+
+func (mc *multiplexConn) reply(msg ServerMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling reply: %v", err)
+		return
+	}
+
+	mc.client.push(mc.ctx, Message{frame: data})
+}
+
+// encode dipanggil writePump untuk setiap pesan di antrean. Data live device
+// yang sedang ditahan disimpan dulu, lalu ditulis setelah frame history-nya;
+// data yang sudah tercakup di history dilewati agar tidak terkirim dua kali.
+func (mc *multiplexConn) encode(msg Message) ([][]byte, error) {
+	switch {
+	case msg.hold:
+		mc.held[msg.DeviceID] = nil
+		return nil, nil
+
+	case msg.history:
+		held := mc.held[msg.DeviceID]
+		delete(mc.held, msg.DeviceID)
+
+		var frames [][]byte
+		if msg.frame != nil {
+			frames = append(frames, msg.frame)
+		}
+		for _, live := range held {
+			if !newerThan(live, msg.newest) {
+				continue
+			}
+			frame, err := encodeData(live)
+			if err != nil {
+				log.Printf("Error encoding message: %v", err)
+				continue
+			}
+			frames = append(frames, frame)
+		}
+		return frames, nil
+
+	case msg.frame != nil:
+		return [][]byte{msg.frame}, nil
+	}
+
+	if held, ok := mc.held[msg.DeviceID]; ok {
+		if len(held) >= clientBufferSize {
+			log.Printf("Client buffer full, dropping update for device %s", msg.DeviceID)
+			return nil, nil
+		}
+		mc.held[msg.DeviceID] = append(held, msg)
+		return nil, nil
+	}
+
+	frame, err := encodeData(msg)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{frame}, nil
+}
+
+// newerThan melaporkan apakah data live lebih baru dari reading terakhir di
+// history. Zero newest (tanpa history) dan payload yang tidak terbaca
+// dianggap lebih baru.
+func newerThan(msg Message, newest time.Time) bool {
+	if newest.IsZero() {
+		return true
+	}
+
+	var data entity.RealTimeElectricity
+	if err := json.Unmarshal(msg.Payload, &data); err != nil {
+		return true
+	}
+
+	return data.CreatedAt.Time.After(newest)
+}
@@ -0,0 +1,64 @@
+package ws
+
+import "encoding/json"
+
+// Pesan dari client pada endpoint multiplex.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePing        = "ping"
+)
+
+// Pesan dari server pada endpoint multiplex.
+const (
+	TypeData         = "data"
+	TypeHistory      = "history"
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypePong         = "pong"
+	TypeError        = "error"
+)
+
+// Kode error yang dikirim lewat frame error.
+const (
+	ErrCodeBadRequest      = "bad_request"
+	ErrCodeUnknownType     = "unknown_type"
+	ErrCodeForbidden       = "forbidden"
+	ErrCodeTooManyDevices  = "too_many_devices"
+	ErrCodeSubscribeFailed = "subscribe_failed"
+	ErrCodeAccessRevoked   = "access_revoked"
+)
+
+// maxDevicesPerConn membatasi jumlah device yang bisa di-subscribe satu
+// koneksi multiplex.
+const maxDevicesPerConn = 50
+
+// ControlMessage adalah frame dari client, mis.
+// {"type":"subscribe","id":"1","device_ids":["device-001","device-002"]}.
+type ControlMessage struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id,omitempty"`
+	DeviceIDs []string `json:"device_ids,omitempty"`
+}
+
+// ServerMessage adalah frame dari server. Frame data selalu membawa
+// device_id sehingga client bisa membedakan device dalam satu koneksi.
+type ServerMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	DeviceID  string          `json:"device_id,omitempty"`
+	DeviceIDs []string        `json:"device_ids,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Code      string          `json:"code,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+func errorMessage(id string, deviceID string, code string, message string) ServerMessage {
+	return ServerMessage{
+		Type:     TypeError,
+		ID:       id,
+		DeviceID: deviceID,
+		Code:     code,
+		Message:  message,
+	}
+}
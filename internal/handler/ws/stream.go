@@ -2,9 +2,10 @@ package ws
 
 import (
 	"context"
-	"encoding/json"
 	"log"
+	"metertronik/internal/domain/entity"
 	"metertronik/internal/domain/repository"
+	aggregate "metertronik/internal/service"
 	"metertronik/pkg/utils"
 	"net/http"
 	"time"
@@ -13,114 +14,199 @@ import (
 )
 
 const (
-	writeWait  = 10 * time.Second
-	pongWait   = 60 * time.Second
-	pingPeriod = (pongWait * 9) / 10
-	pollPeriod = 1 * time.Second
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type StreamHandler struct {
 	RedisRealtimeRepo repository.RedisRealtimeRepo
+	hub               *Hub
+	authorizer        *aggregate.DeviceAuthorizer
+	upgrader          websocket.Upgrader
+	historyWindow     time.Duration
+	accessRecheck     time.Duration
 }
 
-func NewStreamHandler(RedisRealtimeRepo repository.RedisRealtimeRepo) *StreamHandler {
+// NewStreamHandler membuat handler stream. historyWindow adalah rentang data
+// terakhir yang dikirim saat client baru tersambung sebelum update live.
+// accessRecheck adalah jeda pemeriksaan ulang akses device pada stream yang
+// terbuka (0 = hanya diperiksa saat subscribe).
+func NewStreamHandler(RedisRealtimeRepo repository.RedisRealtimeRepo, hub *Hub, authorizer *aggregate.DeviceAuthorizer, allowedOrigins []string, historyWindow time.Duration, accessRecheck time.Duration) *StreamHandler {
 	return &StreamHandler{
 		RedisRealtimeRepo: RedisRealtimeRepo,
+		hub:               hub,
+		authorizer:        authorizer,
+		upgrader:          newUpgrader(allowedOrigins),
+		historyWindow:     historyWindow,
+		accessRecheck:     accessRecheck,
 	}
 }
 
-func (h *StreamHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request, deviceID string) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+func (h *StreamHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request, deviceID string, clientIP string) {
+	claims, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(r.Context(), r, clientIP, claims, deviceID) {
+		writeError(w, http.StatusForbidden, "access to device denied")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade WebSocket connection: %v", err)
 		return
 	}
 	defer conn.Close()
 
-	log.Printf("WebSocket client connected for device: %s", deviceID)
-
-	conn.SetReadDeadline(utils.TimeNow().Time.Add(pongWait))
-	conn.SetPongHandler(func(string) error {
-		conn.SetReadDeadline(utils.TimeNow().Time.Add(pongWait))
-		return nil
-	})
+	log.Printf("WebSocket client connected for device: %s (user %d)", deviceID, claims.UserID)
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
-	pingTicker := time.NewTicker(pingPeriod)
-	defer pingTicker.Stop()
+	go closeOnExpiry(ctx, conn, claims.ExpiresAt, cancel)
+	go h.recheckAccess(ctx, func() {
+		if h.revoked(ctx, r, clientIP, claims, deviceID) {
+			log.Printf("Access of user %d to device %s revoked, closing stream", claims.UserID, deviceID)
+			closeWith(conn, "access to device revoked")
+			cancel()
+		}
+	})
+
+	client := NewClient()
+	if err := h.hub.Subscribe(ctx, deviceID, client); err != nil {
+		log.Printf("Failed to subscribe device %s: %v", deviceID, err)
+		return
+	}
+	defer h.hub.UnsubscribeAll(context.Background(), client)
+
+	// Kirim backfill history (atau data terakhir jika history kosong) agar
+	// chart langsung terisi; update berikutnya datang dari Hub.
+	for _, data := range h.backfill(ctx, deviceID) {
+		conn.SetWriteDeadline(utils.TimeNow().Time.Add(writeWait))
+		if err := conn.WriteJSON(data); err != nil {
+			log.Printf("Error writing message: %v", err)
+			return
+		}
+	}
 
-	dataTicker := time.NewTicker(pollPeriod)
-	defer dataTicker.Stop()
+	go readPump(conn, cancel, nil)
 
-	done := make(chan struct{})
+	writePump(ctx, conn, client, func(msg Message) ([][]byte, error) {
+		return [][]byte{msg.Payload}, nil
+	})
 
-	go func() {
-		defer close(done)
-		for {
-			select {
-			case <-pingTicker.C:
-				conn.SetWriteDeadline(utils.TimeNow().Time.Add(writeWait))
-				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-					log.Printf("Failed to send ping: %v", err)
-					return
-				}
-			case <-ctx.Done():
-				return
-			}
+	log.Printf("WebSocket connection closed for device: %s", deviceID)
+}
+
+// backfill mengambil data dalam historyWindow terakhir, urut dari yang paling
+// lama. Jika history kosong, dipakai data terakhir saja.
+func (h *StreamHandler) backfill(ctx context.Context, deviceID string) []entity.RealTimeElectricity {
+	if h.historyWindow > 0 {
+		since := utils.TimeNow().Time.Add(-h.historyWindow)
+		history, err := h.RedisRealtimeRepo.GetElectricityHistory(ctx, deviceID, since)
+		if err != nil {
+			log.Printf("Error getting electricity history: %v", err)
+		} else if len(history) > 0 {
+			return history
 		}
-	}()
+	}
+
+	data, err := h.RedisRealtimeRepo.GetLatestElectricity(ctx, deviceID)
+	if err != nil {
+		log.Printf("Error getting latest electricity data: %v", err)
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
 
-	var lastDataHash string
+	return []entity.RealTimeElectricity{*data}
+}
+
+// recheckAccess menjalankan check setiap accessRecheck sampai ctx selesai,
+// agar stream ikut berhenti setelah device di-unclaim, dipindahkan, atau
+// anggotanya dicabut.
+func (h *StreamHandler) recheckAccess(ctx context.Context, check func()) {
+	if h.accessRecheck <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.accessRecheck)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-dataTicker.C:
-			data, err := h.RedisRealtimeRepo.GetLatestElectricity(ctx, deviceID)
-			if err != nil {
-				log.Printf("Error getting latest electricity data: %v", err)
-				continue
-			}
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
 
-			if data == nil {
-				log.Printf("No data available in cache for device %s", deviceID)
-				continue
+// readPump membaca frame dari client sampai koneksi putus, lalu membatalkan
+// ctx koneksi. onMessage boleh nil jika endpoint tidak menerima pesan.
+func readPump(conn *websocket.Conn, cancel context.CancelFunc, onMessage func([]byte)) {
+	defer cancel()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(utils.TimeNow().Time.Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(utils.TimeNow().Time.Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket read error: %v", err)
 			}
+			return
+		}
+
+		if onMessage != nil {
+			onMessage(data)
+		}
+	}
+}
 
-			dataJSON, err := json.Marshal(data)
+// writePump adalah satu-satunya penulis ke conn: meneruskan pesan dari
+// antrean client (diformat lewat encode menjadi nol atau lebih frame) dan
+// mengirim ping berkala.
+func writePump(ctx context.Context, conn *websocket.Conn, client *Client, encode func(Message) ([][]byte, error)) {
+	pingTicker := time.NewTicker(pingPeriod)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case msg := <-client.Messages():
+			frames, err := encode(msg)
 			if err != nil {
-				log.Printf("Error marshaling data: %v", err)
+				log.Printf("Error encoding message: %v", err)
 				continue
 			}
-			currentHash := string(dataJSON)
 
-			if currentHash == lastDataHash {
-				continue
+			for _, frame := range frames {
+				conn.SetWriteDeadline(utils.TimeNow().Time.Add(writeWait))
+				if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+					log.Printf("Error writing message: %v", err)
+					return
+				}
 			}
 
+		case <-pingTicker.C:
 			conn.SetWriteDeadline(utils.TimeNow().Time.Add(writeWait))
-			if err := conn.WriteJSON(data); err != nil {
-				log.Printf("Error writing message: %v", err)
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Printf("Failed to send ping: %v", err)
 				return
 			}
 
-			lastDataHash = currentHash
-			log.Printf("Sent data update for device %s", deviceID)
-
-		case <-done:
-			log.Printf("WebSocket connection closed for device: %s", deviceID)
-			return
 		case <-ctx.Done():
-			log.Printf("Context cancelled for device: %s", deviceID)
 			return
 		}
 	}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"metertronik/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceAccess memastikan user dari JWTMiddleware boleh membaca device pada
+// parameter :id. Harus dipasang setelah JWTMiddleware.
+func DeviceAccess(authorizer *service.DeviceAuthorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deviceID := c.Param("id")
+		userID := int64(c.GetInt("user_id"))
+
+		ok, err := authorizer.Authorize(c.Request.Context(), userID, deviceID, c.Request.Method+" "+c.FullPath(), c.ClientIP())
+		if err != nil {
+			log.Printf("Failed to check access of user %d to device %s: %v", userID, deviceID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check device access"})
+			c.Abort()
+			return
+		}
+
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access to device denied"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -3,12 +3,10 @@ package middleware
 import (
 	"strings"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"metertronik/pkg/utils/token"
 	"log"
 )
 
-var jwtSecret = []byte("metertronik")
-
 func JWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("JWTMiddleware")
@@ -23,21 +21,16 @@ func JWTMiddleware() gin.HandlerFunc {
 
 		tokenStr := strings.TrimPrefix(auth, "Bearer ")
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		claims, err := token.ParseAccessToken(tokenStr)
 
-		if err != nil || !token.Valid {
-			log.Println("Token Not Valid: ")
+		if err != nil {
+			log.Println("Token Not Valid: ", err)
 			c.JSON(401, gin.H{"error": "token expired"})
 			c.Abort()
 			return
 		}
 
-		log.Println("Token Valid? : ", token.Valid)
-
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", int(claims["uid"].(float64)))
+		c.Set("user_id", int(claims.UserID))
 
 		log.Println("passing middleware")
 
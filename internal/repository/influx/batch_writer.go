@@ -0,0 +1,245 @@
+package influx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+const writeTimeout = 10 * time.Second
+
+var ErrWriterClosed = errors.New("influx batch writer is closed")
+
+// BatchWriterConfig mengatur BatchWriter. FlushInterval adalah batas waktu
+// point menunggu di batch dan juga interval pemutaran ulang spill file.
+type BatchWriterConfig struct {
+	BatchSize     int
+	BufferSize    int
+	FlushInterval time.Duration
+	SpillPath     string
+}
+
+type pendingLine struct {
+	line string
+	done chan error
+}
+
+// BatchWriter menulis point ke InfluxDB secara berkelompok: batch di-flush
+// saat mencapai BatchSize atau setiap FlushInterval. Batch yang gagal ditulis
+// disimpan ke spill file dan diputar ulang begitu InfluxDB kembali tersedia.
+type BatchWriter struct {
+	writeAPI api.WriteAPIBlocking
+	cfg      BatchWriterConfig
+	spill    *spillFile
+
+	lines     chan pendingLine
+	closing   chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewBatchWriter(writeAPI api.WriteAPIBlocking, cfg BatchWriterConfig) *BatchWriter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BufferSize < cfg.BatchSize {
+		cfg.BufferSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	w := &BatchWriter{
+		writeAPI: writeAPI,
+		cfg:      cfg,
+		spill:    newSpillFile(cfg.SpillPath),
+		lines:    make(chan pendingLine, cfg.BufferSize),
+		closing:  make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// Enqueue menambahkan point ke batch berikutnya tanpa menunggu flush. Fungsi
+// yang dikembalikan menunggu sampai batch tersebut tertulis ke InfluxDB atau
+// tersimpan di spill file; pesan baru boleh di-ack setelah fungsi itu
+// mengembalikan nil.
+func (w *BatchWriter) Enqueue(ctx context.Context, point *write.Point) (func(context.Context) error, error) {
+	pending := pendingLine{
+		line: strings.TrimSuffix(write.PointToLineProtocol(point, time.Nanosecond), "\n"),
+		done: make(chan error, 1),
+	}
+
+	select {
+	case <-w.closing:
+		return nil, ErrWriterClosed
+	default:
+	}
+
+	select {
+	case w.lines <- pending:
+	case <-w.closing:
+		return nil, ErrWriterClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func(ctx context.Context) error {
+		return w.wait(ctx, pending)
+	}, nil
+}
+
+// Write mengantrekan point lalu menunggu batch-nya tersimpan.
+func (w *BatchWriter) Write(ctx context.Context, point *write.Point) error {
+	wait, err := w.Enqueue(ctx, point)
+	if err != nil {
+		return err
+	}
+	return wait(ctx)
+}
+
+func (w *BatchWriter) wait(ctx context.Context, pending pendingLine) error {
+	select {
+	case err := <-pending.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.stopped:
+		// Point yang masuk tepat saat Close mungkin tidak ikut di-drain.
+		select {
+		case err := <-pending.done:
+			return err
+		default:
+			return ErrWriterClosed
+		}
+	}
+}
+
+// Close menghentikan writer dan menulis (atau men-spill) sisa buffer.
+func (w *BatchWriter) Close() {
+	w.closeOnce.Do(func() {
+		close(w.closing)
+	})
+	<-w.stopped
+}
+
+// run mem-flush batch saat mencapai BatchSize atau setiap FlushInterval,
+// dan memutar ulang spill file pada interval yang sama.
+func (w *BatchWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]pendingLine, 0, w.cfg.BatchSize)
+
+	for {
+		select {
+		case pending := <-w.lines:
+			batch = append(batch, pending)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+			w.replay()
+
+		case <-w.closing:
+			for {
+				batch = w.fill(batch)
+				if len(batch) == 0 {
+					return
+				}
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// fill mengambil point yang sudah antre tanpa menunggu, sampai BatchSize.
+func (w *BatchWriter) fill(batch []pendingLine) []pendingLine {
+	for len(batch) < w.cfg.BatchSize {
+		select {
+		case pending := <-w.lines:
+			batch = append(batch, pending)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (w *BatchWriter) flush(batch []pendingLine) {
+	lines := make([]string, len(batch))
+	for i, pending := range batch {
+		lines[i] = pending.line
+	}
+
+	err := w.persist(lines)
+	for _, pending := range batch {
+		pending.done <- err
+	}
+}
+
+// persist menulis batch ke InfluxDB atau, jika gagal, ke spill file. Error
+// hanya dikembalikan jika keduanya gagal.
+func (w *BatchWriter) persist(lines []string) error {
+	// Selama masih ada data di spill file, batch baru ikut di-spill agar
+	// InfluxDB yang sedang down tidak dihantam timeout di setiap flush.
+	if w.spill.pending() {
+		return w.spillBatch(lines)
+	}
+
+	if err := w.writeLines(lines); err != nil {
+		log.Printf("Failed to write batch of %d point(s) to InfluxDB: %v", len(lines), err)
+		return w.spillBatch(lines)
+	}
+
+	return nil
+}
+
+func (w *BatchWriter) replay() {
+	if !w.spill.pending() {
+		return
+	}
+
+	replayed, err := w.spill.replay(w.cfg.BatchSize, w.writeLines)
+	if err != nil {
+		log.Printf("Spill replay stopped after %d point(s): %v", replayed, err)
+		return
+	}
+
+	log.Printf("Spill replay completed, %d point(s) written to InfluxDB", replayed)
+}
+
+func (w *BatchWriter) spillBatch(lines []string) error {
+	if err := w.spill.append(lines); err != nil {
+		log.Printf("[ERROR] Failed to spill %d point(s) to %s: %v", len(lines), w.cfg.SpillPath, err)
+		return fmt.Errorf("failed to spill %d point(s): %w", len(lines), err)
+	}
+	log.Printf("Spilled %d point(s) to %s", len(lines), w.cfg.SpillPath)
+	return nil
+}
+
+func (w *BatchWriter) writeLines(lines []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	return w.writeAPI.WriteRecord(ctx, lines...)
+}
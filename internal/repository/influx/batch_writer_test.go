@@ -0,0 +1,89 @@
+package influx
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// fakeWriteAPI mencatat ukuran setiap batch yang ditulis.
+type fakeWriteAPI struct {
+	api.WriteAPIBlocking
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (f *fakeWriteAPI) WriteRecord(ctx context.Context, lines ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, len(lines))
+	return nil
+}
+
+func (f *fakeWriteAPI) sizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.batches...)
+}
+
+func TestBatchWriterFlush(t *testing.T) {
+	tests := []struct {
+		name          string
+		batchSize     int
+		flushInterval time.Duration
+		points        int
+		// wait menunggu semua point sebelum Close; tanpa wait sisa batch
+		// baru ditulis oleh Close.
+		wait        bool
+		wantBatches []int
+	}{
+		{name: "flush on batch size", batchSize: 3, flushInterval: time.Hour, points: 6, wait: true, wantBatches: []int{3, 3}},
+		{name: "remainder flushed on close", batchSize: 3, flushInterval: time.Hour, points: 7, wantBatches: []int{3, 3, 1}},
+		{name: "flush on interval", batchSize: 100, flushInterval: 200 * time.Millisecond, points: 5, wait: true, wantBatches: []int{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			writeAPI := &fakeWriteAPI{}
+			writer := NewBatchWriter(writeAPI, BatchWriterConfig{
+				BatchSize:     tt.batchSize,
+				FlushInterval: tt.flushInterval,
+				SpillPath:     filepath.Join(t.TempDir(), "influx.lp"),
+			})
+
+			waits := make([]func(context.Context) error, 0, tt.points)
+			for i := 0; i < tt.points; i++ {
+				point := write.NewPoint("electricity", map[string]string{"device_id": "dev-1"},
+					map[string]interface{}{"power": float64(i)}, time.Unix(int64(i), 0))
+				wait, err := writer.Enqueue(ctx, point)
+				if err != nil {
+					t.Fatalf("enqueue point %d: %v", i, err)
+				}
+				waits = append(waits, wait)
+			}
+
+			if tt.wait {
+				waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
+				for i, wait := range waits {
+					if err := wait(waitCtx); err != nil {
+						t.Fatalf("wait point %d: %v", i, err)
+					}
+				}
+			}
+			writer.Close()
+
+			if got := writeAPI.sizes(); !reflect.DeepEqual(got, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", got, tt.wantBatches)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package influx
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"metertronik/internal/domain/entity"
 	"metertronik/internal/domain/repository"
@@ -14,17 +15,22 @@ import (
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
 )
 
+// ErrReadOnly dikembalikan saat menulis lewat repo yang dibuat tanpa writer.
+var ErrReadOnly = errors.New("influx repository is read-only")
+
 type ElectricityRepo struct {
 	client influxdb2.Client
 	org    string
 	bucket string
+	writer *BatchWriter
 }
 
-func NewElectricityRepo(client influxdb2.Client, org, bucket string) repository.InfluxRepo {
+func NewElectricityRepo(client influxdb2.Client, org, bucket string, writer *BatchWriter) repository.InfluxRepo {
 	return &ElectricityRepo{
 		client: client,
 		org:    org,
 		bucket: bucket,
+		writer: writer,
 	}
 }
 
@@ -78,8 +84,15 @@ func (r *ElectricityRepo) GetRealTimeElectricity(ctx context.Context, deviceID s
 }
 
 func (r *ElectricityRepo) SaveRealTimeElectricity(ctx context.Context, electricity *entity.RealTimeElectricity) error {
-	writeAPI := r.client.WriteAPIBlocking(r.org, r.bucket)
+	wait, err := r.QueueRealTimeElectricity(ctx, electricity)
+	if err != nil {
+		return err
+	}
 
+	return wait(ctx)
+}
+
+func (r *ElectricityRepo) QueueRealTimeElectricity(ctx context.Context, electricity *entity.RealTimeElectricity) (repository.PendingWrite, error) {
 	point := write.NewPoint(
 		"electricity",
 		map[string]string{
@@ -94,15 +107,30 @@ func (r *ElectricityRepo) SaveRealTimeElectricity(ctx context.Context, electrici
 			"frequency":              electricity.Frequency,
 			"power_surge":            electricity.PowerSurge,
 			"power_surge_percentage": electricity.PSPercent,
+			"clock_corrected":        electricity.ClockCorrected,
 		},
 		utils.ToUTC(electricity.CreatedAt.Time),
 	)
 
-	if err := writeAPI.WritePoint(ctx, point); err != nil {
-		return fmt.Errorf("failed to write point to InfluxDB: %w", err)
+	if electricity.DeviceTime != nil {
+		point.AddField("device_time_ms", electricity.DeviceTime.Time.UnixMilli())
+	}
+
+	if r.writer == nil {
+		return nil, ErrReadOnly
+	}
+
+	wait, err := r.writer.Enqueue(ctx, point)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue point for InfluxDB: %w", err)
 	}
 
-	return nil
+	return func(ctx context.Context) error {
+		if err := wait(ctx); err != nil {
+			return fmt.Errorf("failed to write point to InfluxDB: %w", err)
+		}
+		return nil
+	}, nil
 }
 
 func (r *ElectricityRepo) GetRealTimeElectricityRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData) (*[]entity.RealTimeElectricity, error) {
@@ -148,6 +176,52 @@ func (r *ElectricityRepo) GetRealTimeElectricityRange(ctx context.Context, devic
 	return &list, nil
 }
 
+// GetLastRealTimeElectricityBefore mengembalikan pembacaan terakhir sebelum
+// before dalam rentang lookback, atau nil jika tidak ada.
+func (r *ElectricityRepo) GetLastRealTimeElectricityBefore(ctx context.Context, deviceID string, before utils.TimeData, lookback time.Duration) (*entity.RealTimeElectricity, error) {
+	queryAPI := r.client.QueryAPI(r.org)
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r["_measurement"] == "electricity" and r["device_id"] == "%s")
+		|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+		|> sort(columns: ["_time"], desc: true)
+		|> limit(n: 1)
+	`,
+		r.bucket,
+		utils.NewTimeData(before.Time.Add(-lookback)).FormatUTC(),
+		before.FormatUTC(),
+		deviceID,
+	)
+
+	res, err := queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last electricity before %s: %w", before.FormatUTC(), err)
+	}
+
+	var last *entity.RealTimeElectricity
+
+	for res.Next() {
+		rec := res.Record()
+		last = &entity.RealTimeElectricity{
+			Voltage:   rec.ValueByKey("voltage").(float64),
+			Current:   rec.ValueByKey("current").(float64),
+			Power:     rec.ValueByKey("power").(float64),
+			Energy:    rec.ValueByKey("energy").(float64),
+			Frequency: rec.ValueByKey("frequency").(float64),
+			DeviceID:  deviceID,
+			CreatedAt: utils.NewTimeData(rec.ValueByKey("_time").(time.Time)),
+		}
+	}
+
+	if res.Err() != nil {
+		return nil, fmt.Errorf("error reading query result: %w", res.Err())
+	}
+
+	return last, nil
+}
+
 func (r *ElectricityRepo) GetActiveDeviceIDs(ctx context.Context, hours int) ([]string, error) {
 	queryAPI := r.client.QueryAPI(r.org)
 
@@ -0,0 +1,156 @@
+package influx
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// spillFile adalah buffer append-only berisi line protocol yang belum
+// berhasil ditulis ke InfluxDB. Hanya diakses dari goroutine BatchWriter.
+type spillFile struct {
+	path string
+	size int64
+}
+
+func newSpillFile(path string) *spillFile {
+	s := &spillFile{path: path}
+
+	if info, err := os.Stat(path); err == nil {
+		s.size = info.Size()
+	}
+
+	return s
+}
+
+func (s *spillFile) pending() bool {
+	return s.size > 0
+}
+
+func (s *spillFile) append(lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		n, err := w.WriteString(line + "\n")
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// replay menulis ulang isi spill file per batch. Jika sebuah batch gagal,
+// bagian yang sudah berhasil dibuang dan sisanya tetap tersimpan.
+func (s *spillFile) replay(batchSize int, writeFn func([]string) error) (int, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.size = 0
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	reader := bufio.NewReader(f)
+	batch := make([]string, 0, batchSize)
+	var offset, batchBytes int64
+	replayed := 0
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if len(line) > 0 {
+			batchBytes += int64(len(line))
+			if trimmed := strings.TrimSuffix(line, "\n"); trimmed != "" {
+				batch = append(batch, trimmed)
+			}
+		}
+
+		if len(batch) >= batchSize || (readErr != nil && len(batch) > 0) {
+			if err := writeFn(batch); err != nil {
+				f.Close()
+				if compactErr := s.compact(offset); compactErr != nil {
+					return replayed, compactErr
+				}
+				return replayed, err
+			}
+			replayed += len(batch)
+			offset += batchBytes
+			batch = batch[:0]
+			batchBytes = 0
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return replayed, readErr
+		}
+	}
+
+	f.Close()
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return replayed, err
+	}
+	s.size = 0
+
+	return replayed, nil
+}
+
+func (s *spillFile) compact(offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+
+	src, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	dst.Close()
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.size = written
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package influx
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSpillFileReplay(t *testing.T) {
+	lines := []string{"a v=1 1", "b v=2 2", "c v=3 3", "d v=4 4", "e v=5 5"}
+
+	tests := []struct {
+		name         string
+		batchSize    int
+		failOnCall   int
+		wantReplayed int
+		wantErr      bool
+		wantPending  bool
+		wantRemain   []string
+	}{
+		{name: "all batches written", batchSize: 2, wantReplayed: 5},
+		{name: "single batch", batchSize: 10, wantReplayed: 5},
+		{name: "first batch fails", batchSize: 2, failOnCall: 1, wantReplayed: 0, wantErr: true, wantPending: true, wantRemain: lines},
+		{name: "second batch fails", batchSize: 2, failOnCall: 2, wantReplayed: 2, wantErr: true, wantPending: true, wantRemain: lines[2:]},
+		{name: "last partial batch fails", batchSize: 2, failOnCall: 3, wantReplayed: 4, wantErr: true, wantPending: true, wantRemain: lines[4:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spill := newSpillFile(filepath.Join(t.TempDir(), "spill", "influx.lp"))
+			if err := spill.append(lines); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			if !spill.pending() {
+				t.Fatal("spill file not pending after append")
+			}
+
+			calls := 0
+			var written []string
+			replayed, err := spill.replay(tt.batchSize, func(batch []string) error {
+				calls++
+				if calls == tt.failOnCall {
+					return errors.New("influx down")
+				}
+				written = append(written, batch...)
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replay error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if replayed != tt.wantReplayed {
+				t.Errorf("replayed = %d, want %d", replayed, tt.wantReplayed)
+			}
+			if len(written) > 0 && !reflect.DeepEqual(written, lines[:tt.wantReplayed]) {
+				t.Errorf("written = %v, want %v", written, lines[:tt.wantReplayed])
+			}
+			if spill.pending() != tt.wantPending {
+				t.Errorf("pending = %t, want %t", spill.pending(), tt.wantPending)
+			}
+
+			if !tt.wantPending {
+				return
+			}
+
+			// Sisa spill file harus tepat baris yang belum tertulis.
+			var remain []string
+			if _, err := spill.replay(100, func(batch []string) error {
+				remain = append(remain, batch...)
+				return nil
+			}); err != nil {
+				t.Fatalf("second replay: %v", err)
+			}
+			if !reflect.DeepEqual(remain, tt.wantRemain) {
+				t.Errorf("remaining = %v, want %v", remain, tt.wantRemain)
+			}
+		})
+	}
+}
+
+func TestSpillFileReplayMissingFile(t *testing.T) {
+	spill := newSpillFile(filepath.Join(t.TempDir(), "missing.lp"))
+	spill.size = 10
+
+	replayed, err := spill.replay(10, func([]string) error {
+		t.Fatal("write called for missing spill file")
+		return nil
+	})
+	if err != nil || replayed != 0 {
+		t.Fatalf("replay = (%d, %v), want (0, nil)", replayed, err)
+	}
+	if spill.pending() {
+		t.Error("missing spill file still pending")
+	}
+}
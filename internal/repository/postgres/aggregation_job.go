@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AggregationJobRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewAggregationJobRepoPostgres(db *gorm.DB) repository.AggregationJobRepo {
+	return &AggregationJobRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *AggregationJobRepoPostgres) GetJobs(ctx context.Context, jobKind string, deviceID string, start utils.TimeData, end utils.TimeData) ([]entity.AggregationJob, error) {
+	var jobs []entity.AggregationJob
+
+	if err := r.db.WithContext(ctx).
+		Table("aggregation_jobs").
+		Where("job_kind = ? AND device_id = ? AND period >= ? AND period < ?", jobKind, deviceID, start, end).
+		Order("period ASC").
+		Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get aggregation jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *AggregationJobRepoPostgres) SaveJob(ctx context.Context, job *entity.AggregationJob) error {
+	job.UpdatedAt = utils.TimeNow()
+
+	return r.db.WithContext(ctx).
+		Table("aggregation_jobs").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "job_kind"}, {Name: "device_id"}, {Name: "period"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"status", "attempts", "error", "updated_at",
+			}),
+		}).
+		Create(job).Error
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewAuditRepoPostgres(db *gorm.DB) repository.AuditRepo {
+	return &AuditRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *AuditRepoPostgres) CreateAuditLog(ctx context.Context, log *entity.AuditLog) error {
+	if err := r.db.WithContext(ctx).Table("audit_logs").Create(log).Error; err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
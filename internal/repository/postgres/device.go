@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type DeviceRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepoPostgres(db *gorm.DB) repository.DeviceRepo {
+	return &DeviceRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *DeviceRepoPostgres) GetDevice(ctx context.Context, deviceID string) (*entity.Device, error) {
+	var device entity.Device
+
+	if err := r.db.WithContext(ctx).Table("devices").Where("device_id = ?", deviceID).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	return &device, nil
+}
+
+// CanAccessDevice bernilai true untuk pemilik device dan anggotanya.
+func (r *DeviceRepoPostgres) CanAccessDevice(ctx context.Context, deviceID string, userID int64) (bool, error) {
+	var count int64
+
+	if err := r.db.WithContext(ctx).Table("devices").
+		Where("device_id = ?", deviceID).
+		Where("owner_id = ? OR EXISTS (SELECT 1 FROM device_members m WHERE m.device_id = devices.device_id AND m.user_id = ?)", userID, userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check device access: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *DeviceRepoPostgres) CreateDevice(ctx context.Context, device *entity.Device) error {
+	if err := r.db.WithContext(ctx).Table("devices").Create(device).Error; err != nil {
+		return fmt.Errorf("failed to create device: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDevice menyimpan kolom yang bisa diubah lewat API; device_id,
+// owner_id dan waktu pembuatan tidak ikut diubah.
+func (r *DeviceRepoPostgres) UpdateDevice(ctx context.Context, device *entity.Device) error {
+	result := r.db.WithContext(ctx).Table("devices").
+		Where("device_id = ?", device.DeviceID).
+		Updates(map[string]interface{}{
+			"device_name":           device.DeviceName,
+			"device_type":           device.DeviceType,
+			"device_status":         device.DeviceStatus,
+			"device_location":       device.DeviceLocation,
+			"energy_mode":           device.EnergyMode,
+			"energy_register_max":   device.EnergyRegisterMax,
+			"timezone":              device.Timezone,
+			"tariff_class":          device.TariffClass,
+			"threshold_policy_id":   device.ThresholdPolicyID,
+			"ingest_max_body_bytes": device.IngestMaxBodyBytes,
+			"device_updated_at":     device.DeviceUpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update device: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *DeviceRepoPostgres) DeleteDevice(ctx context.Context, deviceID string) error {
+	result := r.db.WithContext(ctx).Table("devices").Where("device_id = ?", deviceID).Delete(&entity.Device{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete device: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *DeviceRepoPostgres) ListDevicesForUser(ctx context.Context, userID int64) ([]entity.Device, error) {
+	var devices []entity.Device
+
+	if err := r.db.WithContext(ctx).Table("devices").
+		Where("owner_id = ? OR device_id IN (SELECT device_id FROM device_members WHERE user_id = ?)", userID, userID).
+		Order("device_id asc").
+		Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+func (r *DeviceRepoPostgres) ListDeviceIDs(ctx context.Context, status string) ([]string, error) {
+	var deviceIDs []string
+
+	query := r.db.WithContext(ctx).Table("devices")
+	if status != "" {
+		query = query.Where("device_status = ?", status)
+	}
+
+	if err := query.Order("device_id asc").Pluck("device_id", &deviceIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list device ids: %w", err)
+	}
+
+	return deviceIDs, nil
+}
+
+func (r *DeviceRepoPostgres) SetOwner(ctx context.Context, deviceID string, currentOwner *int64, newOwner *int64) (bool, error) {
+	changed := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Table("devices").Where("device_id = ?", deviceID)
+		if currentOwner == nil {
+			query = query.Where("owner_id IS NULL")
+		} else {
+			query = query.Where("owner_id = ?", *currentOwner)
+		}
+
+		result := query.Updates(map[string]interface{}{
+			"owner_id":          newOwner,
+			"device_updated_at": utils.TimeNow(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Table("device_members").Where("device_id = ?", deviceID).Delete(&entity.DeviceMember{}).Error; err != nil {
+			return err
+		}
+
+		// Policy pribadi pemilik lama tidak bisa dilihat pemilik baru dan
+		// tidak boleh lagi diubah pemilik lama untuk device ini.
+		if currentOwner != nil {
+			if err := tx.Table("devices").
+				Where("device_id = ? AND threshold_policy_id IN (SELECT id FROM threshold_policies WHERE owner_id = ?)", deviceID, *currentOwner).
+				Update("threshold_policy_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to set device owner: %w", err)
+	}
+
+	return changed, nil
+}
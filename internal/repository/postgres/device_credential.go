@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceCredentialRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewDeviceCredentialRepoPostgres(db *gorm.DB) repository.DeviceCredentialRepo {
+	return &DeviceCredentialRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *DeviceCredentialRepoPostgres) GetCredential(ctx context.Context, deviceID string) (*entity.DeviceCredential, error) {
+	var credential entity.DeviceCredential
+
+	if err := r.db.WithContext(ctx).Table("device_credentials").Where("device_id = ?", deviceID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get device credential: %w", err)
+	}
+
+	return &credential, nil
+}
+
+// SaveCredential menimpa secret lama jika device diprovisioning ulang.
+func (r *DeviceCredentialRepoPostgres) SaveCredential(ctx context.Context, credential *entity.DeviceCredential) error {
+	if err := r.db.WithContext(ctx).Table("device_credentials").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"claim_secret_hash", "provisioned_at"}),
+		}).
+		Create(credential).Error; err != nil {
+		return fmt.Errorf("failed to save device credential: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DeviceIngestKeyRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewDeviceIngestKeyRepoPostgres(db *gorm.DB) repository.DeviceIngestKeyRepo {
+	return &DeviceIngestKeyRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *DeviceIngestKeyRepoPostgres) ListActiveIngestKeys(ctx context.Context, deviceID string, at time.Time) ([]entity.DeviceIngestKey, error) {
+	var keys []entity.DeviceIngestKey
+
+	if err := r.db.WithContext(ctx).Table("device_ingest_keys").
+		Where("device_id = ? AND (expires_at IS NULL OR expires_at > ?)", deviceID, at).
+		Order("key_id desc").
+		Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ingest keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *DeviceIngestKeyRepoPostgres) RotateIngestKey(ctx context.Context, key *entity.DeviceIngestKey, graceUntil time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Kunci baris device agar dua rotasi bersamaan tidak memakai key_id
+		// yang sama.
+		if err := tx.Exec("SELECT 1 FROM devices WHERE device_id = ? FOR UPDATE", key.DeviceID).Error; err != nil {
+			return err
+		}
+
+		var maxKeyID int
+		if err := tx.Table("device_ingest_keys").
+			Where("device_id = ?", key.DeviceID).
+			Select("COALESCE(MAX(key_id), 0)").
+			Scan(&maxKeyID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("device_ingest_keys").
+			Where("device_id = ? AND (expires_at IS NULL OR expires_at > ?)", key.DeviceID, graceUntil).
+			Update("expires_at", graceUntil).Error; err != nil {
+			return err
+		}
+
+		key.KeyID = maxKeyID + 1
+		return tx.Table("device_ingest_keys").Create(key).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rotate ingest key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeviceIngestKeyRepoPostgres) ExpireIngestKeys(ctx context.Context, deviceID string, at time.Time) error {
+	if err := r.db.WithContext(ctx).Table("device_ingest_keys").
+		Where("device_id = ? AND (expires_at IS NULL OR expires_at > ?)", deviceID, at).
+		Update("expires_at", at).Error; err != nil {
+		return fmt.Errorf("failed to expire ingest keys: %w", err)
+	}
+
+	return nil
+}
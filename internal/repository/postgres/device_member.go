@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceMemberRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewDeviceMemberRepoPostgres(db *gorm.DB) repository.DeviceMemberRepo {
+	return &DeviceMemberRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *DeviceMemberRepoPostgres) ListMembers(ctx context.Context, deviceID string) ([]entity.DeviceMember, error) {
+	var members []entity.DeviceMember
+
+	if err := r.db.WithContext(ctx).Table("device_members").
+		Where("device_id = ?", deviceID).
+		Order("created_at asc").
+		Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to list device members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember bersifat idempoten: menambahkan user yang sudah menjadi anggota
+// hanya memperbarui role-nya.
+func (r *DeviceMemberRepoPostgres) AddMember(ctx context.Context, member *entity.DeviceMember) error {
+	if err := r.db.WithContext(ctx).Table("device_members").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).
+		Create(member).Error; err != nil {
+		return fmt.Errorf("failed to add device member: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeviceMemberRepoPostgres) RemoveMember(ctx context.Context, deviceID string, userID int64) error {
+	result := r.db.WithContext(ctx).Table("device_members").
+		Where("device_id = ? AND user_id = ?", deviceID, userID).
+		Delete(&entity.DeviceMember{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove device member: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
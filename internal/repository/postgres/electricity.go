@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"metertronik/internal/domain/entity"
 	"metertronik/pkg/utils"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -37,17 +39,35 @@ func (r *ElectricityRepoPostgres) SaveDailyElectricity(ctx context.Context, dail
 }
 
 
-func (r *ElectricityRepoPostgres) GetTarrifs(ctx context.Context) (*entity.Tarrifs, error) {
+// GetTarrifs mengambil tarif yang berlaku untuk golongan tariffClass. Jika
+// golongan kosong atau tidak ditemukan, dipakai tarif berlaku yang pertama.
+func (r *ElectricityRepoPostgres) GetTarrifs(ctx context.Context, tariffClass string) (*entity.Tarrifs, error) {
 	var tarrifs entity.Tarrifs
 
-	if err := r.db.WithContext(ctx).Table("tarrifs").Where("effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", utils.TimeNow(), utils.TimeNow()).First(&tarrifs).Error; err != nil {
+	query := func() *gorm.DB {
+		return r.db.WithContext(ctx).Table("tarrifs").Where("effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", utils.TimeNow(), utils.TimeNow())
+	}
+
+	if tariffClass != "" {
+		err := query().Where("type_tarrif = ?", tariffClass).First(&tarrifs).Error
+		if err == nil {
+			return &tarrifs, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get tarrifs: %w", err)
+		}
+	}
+
+	if err := query().First(&tarrifs).Error; err != nil {
 		return nil, fmt.Errorf("failed to get tarrifs: %w", err)
 	}
 
 	return &tarrifs, nil
 }
 
-func (r *ElectricityRepoPostgres) GetHourlyElectricity(ctx context.Context, deviceID string, hours int, date *utils.TimeData) (*[]entity.HourlyElectricity, error) {
+// GetHourlyElectricity mengambil baris hourly untuk tanggal kalender date
+// (hari lokal di zona loc), atau hours jam terakhir jika date nil.
+func (r *ElectricityRepoPostgres) GetHourlyElectricity(ctx context.Context, deviceID string, hours int, date *utils.TimeData, loc *time.Location) (*[]entity.HourlyElectricity, error) {
 	var hourlyElectricity []entity.HourlyElectricity
 
 	var endTime utils.TimeData
@@ -57,14 +77,14 @@ func (r *ElectricityRepoPostgres) GetHourlyElectricity(ctx context.Context, devi
 		endTime = utils.TimeNowHourly()
 		startTime = endTime.AddHours(-hours)
 	} else {
-		startOfDay := date.StartOfDay()
-		endOfDay := date.EndOfDay()
+		startOfDay := date.StartIn(loc)
+		nextDay := date.AddDays(1).StartIn(loc)
 		now := utils.TimeNow()
 
-		if endOfDay.Time.After(now.Time) {
+		if nextDay.Time.After(now.Time) {
 			endTime = utils.TimeNowHourly()
 		} else {
-			endTime = endOfDay.TruncateHour()
+			endTime = nextDay.Add(-time.Hour).TruncateHour()
 		}
 
 		startTime = endTime.AddHours(-hours)
@@ -81,14 +101,14 @@ func (r *ElectricityRepoPostgres) GetHourlyElectricity(ctx context.Context, devi
 	return &hourlyElectricity, nil
 }
 
-func (r *ElectricityRepoPostgres) GetDailyElectricity(ctx context.Context, deviceID string, date utils.TimeData) (*entity.DailyElectricity, *[]entity.HourlyElectricity, error) {
+func (r *ElectricityRepoPostgres) GetDailyElectricity(ctx context.Context, deviceID string, date utils.TimeData, loc *time.Location) (*entity.DailyElectricity, *[]entity.HourlyElectricity, error) {
 	var dailyElectricity entity.DailyElectricity
 
 	if err := r.db.WithContext(ctx).Table("daily_data").Where("device_id = ? AND day = ?", deviceID, date).First(&dailyElectricity).Error; err != nil {
 		return nil, nil, fmt.Errorf("failed to get daily electricity data: %w", err)
 	}
 
-	hourlyElectricityList, err := r.GetHourlyElectricity(ctx, deviceID, 24, &date)
+	hourlyElectricityList, err := r.GetHourlyElectricity(ctx, deviceID, 24, &date, loc)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get hourly electricity data: %w", err)
@@ -97,10 +117,10 @@ func (r *ElectricityRepoPostgres) GetDailyElectricity(ctx context.Context, devic
 	return &dailyElectricity, hourlyElectricityList, nil
 }
 
-func (r *ElectricityRepoPostgres) GetDailyElectricityList(ctx context.Context, deviceID string, sortBy string, lastDate *utils.TimeData) (*[]entity.DailyElectricity, error) {
+func (r *ElectricityRepoPostgres) GetDailyElectricityList(ctx context.Context, deviceID string, sortBy string, lastDate *utils.TimeData, loc *time.Location) (*[]entity.DailyElectricity, error) {
 	var dailyElectricityList []entity.DailyElectricity
 
-	today := utils.TimeNowDaily()
+	today := utils.TimeNowDailyIn(loc)
 
 	query := r.db.WithContext(ctx).Table("daily_data").
 		Where("device_id = ? AND day < ?", deviceID, today)
@@ -120,10 +140,10 @@ func (r *ElectricityRepoPostgres) GetDailyElectricityList(ctx context.Context, d
 	return &dailyElectricityList, nil
 }
 
-func (r *ElectricityRepoPostgres) GetDailyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData, lastDate *utils.TimeData, limit int) (*[]entity.DailyElectricity, error) {
+func (r *ElectricityRepoPostgres) GetDailyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData, lastDate *utils.TimeData, limit int, loc *time.Location) (*[]entity.DailyElectricity, error) {
 	var dailyElectricityList []entity.DailyElectricity
 
-	today := utils.TimeNowDaily()
+	today := utils.TimeNowDailyIn(loc)
 
 	query := r.db.WithContext(ctx).Table("daily_data").
 		Where("device_id = ? AND day BETWEEN ? AND ? AND day < ?", deviceID, start, end, today)
@@ -166,6 +186,7 @@ func (r *ElectricityRepoPostgres) UpsertHourlyElectricity(ctx context.Context, d
 			DoUpdates: clause.AssignmentColumns([]string{
 				"energy", "total_cost", "avg_voltage",
 				"avg_current", "avg_power", "min_power", "max_power",
+				"coverage_percent", "sample_count",
 			}),
 		}).
 		Create(data).Error
@@ -179,6 +200,7 @@ func (r *ElectricityRepoPostgres) UpsertDailyElectricity(ctx context.Context, da
 			DoUpdates: clause.AssignmentColumns([]string{
 				"energy", "total_cost", "avg_voltage",
 				"avg_current", "avg_power", "min_power", "max_power",
+				"coverage_percent", "sample_count", "timezone",
 			}),
 		}).
 		Create(data).Error
@@ -190,7 +212,7 @@ func (r *ElectricityRepoPostgres) UpsertMonthlyElectricity(ctx context.Context,
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "device_id"}, {Name: "month"}},
 			DoUpdates: clause.AssignmentColumns([]string{
-				"energy", "total_cost",
+				"energy", "total_cost", "timezone",
 			}),
 		}).
 		Create(monthlyElectricity).Error
@@ -205,4 +227,40 @@ func (r *ElectricityRepoPostgres) GetMonthlyElectricity(ctx context.Context, dev
 	}
 
 	return &monthlyElectricityList, nil
-}
\ No newline at end of file
+}
+
+func (r *ElectricityRepoPostgres) GetMonthlyRange(ctx context.Context, deviceID string, start utils.TimeData, end utils.TimeData) (*[]entity.MonthlyElectricity, error) {
+	var monthlyElectricityList []entity.MonthlyElectricity
+
+	if err := r.db.WithContext(ctx).
+		Table("monthly_data").
+		Where("device_id = ? AND month >= ? AND month < ?", deviceID, start, end).
+		Order("month asc").
+		Find(&monthlyElectricityList).Error; err != nil {
+		return nil, fmt.Errorf("failed to get monthly electricity data range: %w", err)
+	}
+
+	return &monthlyElectricityList, nil
+}
+
+func (r *ElectricityRepoPostgres) UpsertYearlyElectricity(ctx context.Context, yearlyElectricity *entity.YearlyElectricity) error {
+	return r.db.WithContext(ctx).
+		Table("yearly_data").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "device_id"}, {Name: "year"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"energy", "total_cost", "timezone",
+			}),
+		}).
+		Create(yearlyElectricity).Error
+}
+
+func (r *ElectricityRepoPostgres) GetYearlyElectricity(ctx context.Context, deviceID string) (*[]entity.YearlyElectricity, error) {
+	var yearlyElectricityList []entity.YearlyElectricity
+
+	if err := r.db.WithContext(ctx).Table("yearly_data").Where("device_id = ?", deviceID).Order("year desc").Find(&yearlyElectricityList).Error; err != nil {
+		return nil, fmt.Errorf("failed to get yearly electricity data: %w", err)
+	}
+
+	return &yearlyElectricityList, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+	"regexp"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var partitionBoundRe = regexp.MustCompile(`FROM \('([^']+)'\) TO \('([^']+)'\)`)
+
+type PartitionRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewPartitionRepoPostgres(db *gorm.DB) repository.PartitionRepo {
+	return &PartitionRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *PartitionRepoPostgres) ListPartitions(ctx context.Context, parent string) ([]entity.Partition, error) {
+	var rows []struct {
+		Name  string
+		Bound string
+	}
+
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = ?`, parent).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", parent, err)
+	}
+
+	partitions := make([]entity.Partition, 0, len(rows))
+
+	for _, row := range rows {
+		match := partitionBoundRe.FindStringSubmatch(row.Bound)
+		if match == nil {
+			return nil, fmt.Errorf("unsupported partition bound for %s: %s", row.Name, row.Bound)
+		}
+
+		from, err := parsePartitionBound(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse partition bound for %s: %w", row.Name, err)
+		}
+
+		to, err := parsePartitionBound(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse partition bound for %s: %w", row.Name, err)
+		}
+
+		partitions = append(partitions, entity.Partition{
+			Parent: parent,
+			Name:   row.Name,
+			From:   from,
+			To:     to,
+		})
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].From.Time.Before(partitions[j].From.Time)
+	})
+
+	return partitions, nil
+}
+
+func (r *PartitionRepoPostgres) CreatePartition(ctx context.Context, partition entity.Partition, timestamp bool) error {
+	layout := "2006-01-02"
+	if timestamp {
+		layout = "2006-01-02 15:04:05+00"
+	}
+
+	query := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		partition.Name,
+		partition.Parent,
+		partition.From.FormatLayout(layout),
+		partition.To.FormatLayout(layout),
+	)
+
+	if err := r.db.WithContext(ctx).Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", partition.Name, err)
+	}
+
+	return nil
+}
+
+func (r *PartitionRepoPostgres) CreatePartitionIndex(ctx context.Context, partition string, indexName string, column string) error {
+	query := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s(%s)`, indexName, partition, column)
+
+	if err := r.db.WithContext(ctx).Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to create index %s: %w", indexName, err)
+	}
+
+	return nil
+}
+
+func (r *PartitionRepoPostgres) DetachPartition(ctx context.Context, parent string, partition string) error {
+	query := fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, parent, partition)
+
+	if err := r.db.WithContext(ctx).Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to detach partition %s: %w", partition, err)
+	}
+
+	return nil
+}
+
+func (r *PartitionRepoPostgres) DropPartition(ctx context.Context, partition string) error {
+	query := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, partition)
+
+	if err := r.db.WithContext(ctx).Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", partition, err)
+	}
+
+	return nil
+}
+
+func parsePartitionBound(value string) (utils.TimeData, error) {
+	layouts := []string{
+		"2006-01-02",
+		"2006-01-02 15:04:05-07",
+		"2006-01-02 15:04:05-07:00",
+		"2006-01-02 15:04:05",
+	}
+
+	var lastErr error
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return utils.NewTimeData(t), nil
+		}
+		lastErr = err
+	}
+
+	return utils.TimeData{}, lastErr
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type ReadingRuleRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewReadingRuleRepoPostgres(db *gorm.DB) repository.ReadingRuleRepo {
+	return &ReadingRuleRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *ReadingRuleRepoPostgres) ListReadingRules(ctx context.Context) ([]entity.ReadingRule, error) {
+	var rules []entity.ReadingRule
+
+	err := r.db.WithContext(ctx).
+		Table("reading_rules").
+		Order("device_type ASC, field ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reading rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+type QuarantineRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewQuarantineRepoPostgres(db *gorm.DB) repository.QuarantineRepo {
+	return &QuarantineRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *QuarantineRepoPostgres) SaveQuarantinedReading(ctx context.Context, reading *entity.QuarantinedReading) error {
+	if err := r.db.WithContext(ctx).Table("quarantined_readings").Create(reading).Error; err != nil {
+		return fmt.Errorf("failed to save quarantined reading: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SurgeEventRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewSurgeEventRepoPostgres(db *gorm.DB) repository.SurgeEventRepo {
+	return &SurgeEventRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *SurgeEventRepoPostgres) GetOpenSurgeEvent(ctx context.Context, deviceID string) (*entity.SurgeEvent, error) {
+	var event entity.SurgeEvent
+
+	err := r.db.WithContext(ctx).Table("surge_events").
+		Where("device_id = ? AND ended_at IS NULL", deviceID).
+		Order("started_at DESC").
+		First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get open surge event: %w", err)
+	}
+
+	return &event, nil
+}
+
+func (r *SurgeEventRepoPostgres) CreateSurgeEvent(ctx context.Context, event *entity.SurgeEvent) error {
+	if err := r.db.WithContext(ctx).Table("surge_events").Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create surge event: %w", err)
+	}
+
+	return nil
+}
+
+// AddSurgeSample mengakumulasi reading ke event di database, bukan menimpa
+// dengan salinan di memori, karena replika ingestor lain bisa menulis event
+// yang sama.
+func (r *SurgeEventRepoPostgres) AddSurgeSample(ctx context.Context, id int64, data *entity.RealTimeElectricity) (*entity.SurgeEvent, error) {
+	var events []entity.SurgeEvent
+
+	err := r.db.WithContext(ctx).Table("surge_events").Model(&events).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Updates(map[string]interface{}{
+			// SET dievaluasi terhadap nilai lama, jadi peak_power memakai
+			// magnitude sebelum diperbarui.
+			"peak_power":     gorm.Expr("CASE WHEN ABS(? - baseline_power) > magnitude THEN ? ELSE peak_power END", data.Power, data.Power),
+			"magnitude":      gorm.Expr("GREATEST(magnitude, ABS(? - baseline_power))", data.Power),
+			"peak_surge":     gorm.Expr("GREATEST(peak_surge, ?)", data.PowerSurge),
+			"peak_percent":   gorm.Expr("GREATEST(peak_percent, ?)", data.PSPercent),
+			"samples":        gorm.Expr("samples + 1"),
+			"last_sample_at": gorm.Expr("GREATEST(last_sample_at, ?)", data.CreatedAt),
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to add surge sample: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	return &events[0], nil
+}
+
+// CloseSurgeEvent menutup event pada endedAt kecuali event sudah ditutup atau
+// sudah menerima reading setelah endedAt.
+func (r *SurgeEventRepoPostgres) CloseSurgeEvent(ctx context.Context, id int64, endedAt utils.TimeData) (bool, error) {
+	result := r.db.WithContext(ctx).Table("surge_events").
+		Where("id = ? AND ended_at IS NULL AND last_sample_at <= ?", id, endedAt).
+		Update("ended_at", endedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to close surge event: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CloseStaleSurgeEvents menutup event terbuka yang reading terakhirnya lebih
+// lama dari before, misalnya karena device offline atau dihapus.
+func (r *SurgeEventRepoPostgres) CloseStaleSurgeEvents(ctx context.Context, before utils.TimeData) (int64, error) {
+	result := r.db.WithContext(ctx).Table("surge_events").
+		Where("ended_at IS NULL AND last_sample_at < ?", before).
+		Update("ended_at", gorm.Expr("last_sample_at"))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to close stale surge events: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (r *SurgeEventRepoPostgres) ListSurgeEvents(ctx context.Context, filter entity.SurgeEventFilter) ([]entity.SurgeEvent, error) {
+	var events []entity.SurgeEvent
+
+	query := r.db.WithContext(ctx).Table("surge_events").Where("device_id = ?", filter.DeviceID)
+
+	if filter.From != nil {
+		query = query.Where("started_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("started_at <= ?", *filter.To)
+	}
+	if filter.MinMagnitude > 0 {
+		query = query.Where("magnitude >= ?", filter.MinMagnitude)
+	}
+	switch filter.Status {
+	case entity.SurgeEventStatusOpen:
+		query = query.Where("ended_at IS NULL")
+	case entity.SurgeEventStatusClosed:
+		query = query.Where("ended_at IS NOT NULL")
+	}
+	if filter.LastID > 0 {
+		query = query.Where("id < ?", filter.LastID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Order("id DESC").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list surge events: %w", err)
+	}
+
+	return events, nil
+}
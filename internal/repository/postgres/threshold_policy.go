@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type ThresholdPolicyRepoPostgres struct {
+	db *gorm.DB
+}
+
+func NewThresholdPolicyRepoPostgres(db *gorm.DB) repository.ThresholdPolicyRepo {
+	return &ThresholdPolicyRepoPostgres{
+		db: db,
+	}
+}
+
+func (r *ThresholdPolicyRepoPostgres) ListThresholdPolicies(ctx context.Context) ([]entity.ThresholdPolicy, error) {
+	var policies []entity.ThresholdPolicy
+
+	if err := r.db.WithContext(ctx).Table("threshold_policies").Order("id ASC").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to list threshold policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *ThresholdPolicyRepoPostgres) ListThresholdPoliciesForUser(ctx context.Context, userID int64) ([]entity.ThresholdPolicy, error) {
+	var policies []entity.ThresholdPolicy
+
+	if err := r.db.WithContext(ctx).Table("threshold_policies").
+		Where("owner_id = ? OR owner_id IS NULL", userID).
+		Order("id ASC").
+		Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to list threshold policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *ThresholdPolicyRepoPostgres) GetThresholdPolicy(ctx context.Context, id int64) (*entity.ThresholdPolicy, error) {
+	var policy entity.ThresholdPolicy
+
+	if err := r.db.WithContext(ctx).Table("threshold_policies").Where("id = ?", id).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get threshold policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+func (r *ThresholdPolicyRepoPostgres) CreateThresholdPolicy(ctx context.Context, policy *entity.ThresholdPolicy) error {
+	if err := r.db.WithContext(ctx).Table("threshold_policies").Create(policy).Error; err != nil {
+		return fmt.Errorf("failed to create threshold policy: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ThresholdPolicyRepoPostgres) UpdateThresholdPolicy(ctx context.Context, policy *entity.ThresholdPolicy) error {
+	result := r.db.WithContext(ctx).Table("threshold_policies").
+		Where("id = ?", policy.ID).
+		Updates(map[string]interface{}{
+			"name":                 policy.Name,
+			"device_type":          policy.DeviceType,
+			"voltage_percent":      policy.VoltagePercent,
+			"current_percent":      policy.CurrentPercent,
+			"power_percent":        policy.PowerPercent,
+			"energy_percent":       policy.EnergyPercent,
+			"power_factor_percent": policy.PowerFactorPercent,
+			"frequency_percent":    policy.FrequencyPercent,
+			"surge_watts":          policy.SurgeWatts,
+			"surge_percent":        policy.SurgePercent,
+			"surge_baseline_watts": policy.SurgeBaselineWatts,
+			"updated_at":           policy.UpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update threshold policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *ThresholdPolicyRepoPostgres) DeleteThresholdPolicy(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Table("threshold_policies").Where("id = ?", id).Delete(&entity.ThresholdPolicy{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete threshold policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
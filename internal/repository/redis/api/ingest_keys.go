@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"metertronik/internal/domain/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const ingestKeyRevokedChannel = "ingest:keys:revoked"
+
+type IngestKeyRevocationRedis struct {
+	client *redis.Client
+}
+
+func NewIngestKeyRevocationRedis(client *redis.Client) repository.IngestKeyRevocationRepo {
+	return &IngestKeyRevocationRedis{
+		client: client,
+	}
+}
+
+func (r *IngestKeyRevocationRedis) PublishRevocation(ctx context.Context, deviceID string) error {
+	if err := r.client.Publish(ctx, ingestKeyRevokedChannel, deviceID).Err(); err != nil {
+		return fmt.Errorf("failed to publish ingest key revocation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IngestKeyRevocationRedis) Revocations(ctx context.Context) <-chan string {
+	pubsub := r.client.Subscribe(ctx, ingestKeyRevokedChannel)
+	revoked := make(chan string, 64)
+
+	go func() {
+		defer close(revoked)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				revoked <- msg.Payload
+			}
+		}
+	}()
+
+	return revoked
+}
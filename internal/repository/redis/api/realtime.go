@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"metertronik/internal/domain/entity"
 	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -68,24 +70,55 @@ func (r *RedisRealtimeRepo) GetLatestElectricity(ctx context.Context, deviceID s
 	return &electricity, nil
 }
 
+// SaveElectricityHistory menyimpan data ke sorted set per device dengan skor
+// waktu pembacaan (milidetik). Data yang lebih tua dari ttl dibuang.
 func (r *RedisRealtimeRepo) SaveElectricityHistory(ctx context.Context, deviceID string, electricity *entity.RealTimeElectricity, ttl time.Duration) error {
-	key := fmt.Sprintf("electricity:%s:%s",
-		deviceID,
-		electricity.CreatedAt.Format(),
-	)
+	key := fmt.Sprintf("electricity:history:%s", deviceID)
 
 	data, err := json.Marshal(electricity)
 	if err != nil {
 		return fmt.Errorf("failed to marshal history electricity: %w", err)
 	}
 
-	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
+	cutoff := time.Now().Add(-ttl).UnixMilli()
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(electricity.CreatedAt.Time.UnixMilli()), Member: data})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", cutoff))
+	pipe.Expire(ctx, key, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to set history cache: %w", err)
 	}
 
 	return nil
 }
 
+// GetElectricityHistory mengembalikan data sejak since, urut dari yang
+// paling lama.
+func (r *RedisRealtimeRepo) GetElectricityHistory(ctx context.Context, deviceID string, since time.Time) ([]entity.RealTimeElectricity, error) {
+	key := fmt.Sprintf("electricity:history:%s", deviceID)
+
+	members, err := r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: fmt.Sprintf("%d", since.UnixMilli()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history cache: %w", err)
+	}
+
+	history := make([]entity.RealTimeElectricity, 0, len(members))
+	for _, member := range members {
+		var electricity entity.RealTimeElectricity
+		if err := json.Unmarshal([]byte(member), &electricity); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal history electricity: %w", err)
+		}
+		history = append(history, electricity)
+	}
+
+	return history, nil
+}
+
 func (r *RedisRealtimeRepo) HasChanged(ctx context.Context, deviceID string, newData *entity.RealTimeElectricity) (bool, *entity.RealTimeElectricity, error) {
 	oldData, err := r.GetLatestElectricity(ctx, deviceID)
 
@@ -110,3 +143,144 @@ func (r *RedisRealtimeRepo) DeleteLatestElectricity(ctx context.Context, deviceI
 
 	return nil
 }
+
+// seenMaxPerDevice membatasi jumlah timestamp yang diingat per device agar
+// memori Redis tidak ikut tumbuh dengan laju ingest.
+const seenMaxPerDevice = 2048
+
+// markSeenScript menyimpan timestamp reading dalam satu sorted set per
+// device, lalu memangkasnya ke jendela waktu dan seenMaxPerDevice anggota.
+// Hasilnya {baru, score terbesar sebelum reading ini (0 jika kosong)}.
+var markSeenScript = redis.NewScript(`
+local key = KEYS[1]
+local before = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+local previous = tonumber(before[2] or '0')
+if redis.call('ZSCORE', key, ARGV[1]) then
+	return {0, previous}
+end
+redis.call('ZADD', key, ARGV[2], ARGV[1])
+local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+redis.call('ZREMRANGEBYSCORE', key, '-inf', string.format('(%d', tonumber(newest[2]) - tonumber(ARGV[3])))
+redis.call('ZREMRANGEBYRANK', key, 0, -(tonumber(ARGV[4]) + 1))
+redis.call('PEXPIRE', key, ARGV[3])
+return {1, previous}
+`)
+
+func seenKey(deviceID string) string {
+	return fmt.Sprintf("electricity:seen:%s", deviceID)
+}
+
+// seenMember memakai presisi nanodetik agar reading terkoreksi yang diterima
+// dalam milidetik yang sama tidak dianggap duplikat; score tetap milidetik.
+func seenMember(ts time.Time) string {
+	return strconv.FormatInt(ts.UnixNano(), 10)
+}
+
+func (r *RedisRealtimeRepo) MarkReadingSeen(ctx context.Context, deviceID string, ts time.Time, window time.Duration) (bool, time.Time, error) {
+	res, err := markSeenScript.Run(ctx, r.client, []string{seenKey(deviceID)},
+		seenMember(ts), ts.UnixMilli(), window.Milliseconds(), seenMaxPerDevice).Int64Slice()
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("failed to mark reading seen: %w", err)
+	}
+
+	var newest time.Time
+	if res[1] > 0 {
+		newest = time.UnixMilli(res[1]).UTC()
+	}
+
+	return res[0] == 1, newest, nil
+}
+
+func (r *RedisRealtimeRepo) ForgetReading(ctx context.Context, deviceID string, ts time.Time) error {
+	if err := r.client.ZRem(ctx, seenKey(deviceID), seenMember(ts)).Err(); err != nil {
+		return fmt.Errorf("failed to forget reading: %w", err)
+	}
+
+	return nil
+}
+
+// Statistik jam dibuang bila device tidak mengirim data selama clockStatsTTL.
+const clockStatsTTL = 7 * 24 * time.Hour
+
+var recordClockSampleScript = redis.NewScript(`
+local key = KEYS[1]
+local skew = tonumber(ARGV[1])
+redis.call('HINCRBY', key, 'samples', 1)
+redis.call('HINCRBY', key, 'sum_skew_ms', skew)
+redis.call('HSET', key, 'last_skew_ms', skew, 'last_device_ms', ARGV[2], 'last_seen_ms', ARGV[3])
+if ARGV[4] == '1' then
+	redis.call('HINCRBY', key, 'corrected', 1)
+	redis.call('HSET', key, 'last_corrected_ms', ARGV[3])
+end
+if skew > tonumber(redis.call('HGET', key, 'max_ahead_ms') or '0') then
+	redis.call('HSET', key, 'max_ahead_ms', skew)
+end
+if -skew > tonumber(redis.call('HGET', key, 'max_behind_ms') or '0') then
+	redis.call('HSET', key, 'max_behind_ms', -skew)
+end
+redis.call('PEXPIRE', key, ARGV[5])
+return 1
+`)
+
+func clockKey(deviceID string) string {
+	return fmt.Sprintf("electricity:clock:%s", deviceID)
+}
+
+func (r *RedisRealtimeRepo) RecordClockSample(ctx context.Context, deviceID string, deviceTime time.Time, receivedAt time.Time, corrected bool) error {
+	flag := "0"
+	if corrected {
+		flag = "1"
+	}
+
+	err := recordClockSampleScript.Run(ctx, r.client, []string{clockKey(deviceID)},
+		deviceTime.Sub(receivedAt).Milliseconds(),
+		deviceTime.UnixMilli(),
+		receivedAt.UnixMilli(),
+		flag,
+		clockStatsTTL.Milliseconds(),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to record clock sample: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisRealtimeRepo) GetClockStats(ctx context.Context, deviceID string) (*entity.ClockStats, error) {
+	values, err := r.client.HGetAll(ctx, clockKey(deviceID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clock stats: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	field := func(name string) int64 {
+		v, _ := strconv.ParseInt(values[name], 10, 64)
+		return v
+	}
+	timeField := func(name string) *utils.TimeData {
+		if values[name] == "" {
+			return nil
+		}
+		t := utils.NewTimeData(time.UnixMilli(field(name)))
+		return &t
+	}
+
+	stats := &entity.ClockStats{
+		DeviceID:        deviceID,
+		Samples:         field("samples"),
+		Corrected:       field("corrected"),
+		LastSkewMs:      field("last_skew_ms"),
+		MaxAheadMs:      field("max_ahead_ms"),
+		MaxBehindMs:     field("max_behind_ms"),
+		LastDeviceTime:  timeField("last_device_ms"),
+		LastSeenAt:      timeField("last_seen_ms"),
+		LastCorrectedAt: timeField("last_corrected_ms"),
+	}
+	if stats.Samples > 0 {
+		stats.AvgSkewMs = float64(field("sum_skew_ms")) / float64(stats.Samples)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const streamChannelPrefix = "electricity:stream:"
+
+func streamChannel(deviceID string) string {
+	return streamChannelPrefix + deviceID
+}
+
+func (r *RedisRealtimeRepo) PublishElectricity(ctx context.Context, deviceID string, electricity *entity.RealTimeElectricity) error {
+	data, err := json.Marshal(electricity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream electricity: %w", err)
+	}
+
+	if err := r.client.Publish(ctx, streamChannel(deviceID), data).Err(); err != nil {
+		return fmt.Errorf("failed to publish electricity: %w", err)
+	}
+
+	return nil
+}
+
+// ElectricitySubscriberRedis memakai satu koneksi pub/sub untuk semua device;
+// channel ditambah/dikurangi secara dinamis lewat Subscribe/Unsubscribe.
+type ElectricitySubscriberRedis struct {
+	pubsub   *redis.PubSub
+	messages chan *entity.RealTimeElectricity
+}
+
+func NewElectricitySubscriberRedis(ctx context.Context, client *redis.Client) repository.ElectricitySubscriber {
+	s := &ElectricitySubscriberRedis{
+		pubsub:   client.Subscribe(ctx),
+		messages: make(chan *entity.RealTimeElectricity, 256),
+	}
+
+	go s.receive()
+
+	return s
+}
+
+func (s *ElectricitySubscriberRedis) Subscribe(ctx context.Context, deviceIDs ...string) error {
+	if err := s.pubsub.Subscribe(ctx, channels(deviceIDs)...); err != nil {
+		return fmt.Errorf("failed to subscribe electricity stream: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ElectricitySubscriberRedis) Unsubscribe(ctx context.Context, deviceIDs ...string) error {
+	if err := s.pubsub.Unsubscribe(ctx, channels(deviceIDs)...); err != nil {
+		return fmt.Errorf("failed to unsubscribe electricity stream: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ElectricitySubscriberRedis) Messages() <-chan *entity.RealTimeElectricity {
+	return s.messages
+}
+
+func (s *ElectricitySubscriberRedis) Close() error {
+	return s.pubsub.Close()
+}
+
+func (s *ElectricitySubscriberRedis) receive() {
+	defer close(s.messages)
+
+	for msg := range s.pubsub.Channel() {
+		var electricity entity.RealTimeElectricity
+		if err := json.Unmarshal([]byte(msg.Payload), &electricity); err != nil {
+			log.Printf("Failed to unmarshal stream message on %s: %v", msg.Channel, err)
+			continue
+		}
+
+		if electricity.DeviceID == "" {
+			electricity.DeviceID = strings.TrimPrefix(msg.Channel, streamChannelPrefix)
+		}
+
+		s.messages <- &electricity
+	}
+}
+
+func channels(deviceIDs []string) []string {
+	names := make([]string, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		names = append(names, streamChannel(deviceID))
+	}
+	return names
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"metertronik/internal/domain/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// renewScript dan releaseScript hanya mengubah lease jika masih dipegang
+// holder yang sama, sehingga instance lama tidak bisa mengambil alih lagi.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type LeaderLeaseRedis struct {
+	client *redis.Client
+	key    string
+}
+
+func NewLeaderLeaseRedis(client *redis.Client, key string) repository.LeaderLease {
+	return &LeaderLeaseRedis{
+		client: client,
+		key:    key,
+	}
+}
+
+func (r *LeaderLeaseRedis) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.key, holder, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", r.key, err)
+	}
+	if ok {
+		return true, nil
+	}
+
+	// Lease mungkin masih milik holder ini (mis. setelah restart cepat).
+	return r.Renew(ctx, holder, ttl)
+}
+
+func (r *LeaderLeaseRedis) Renew(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	renewed, err := renewScript.Run(ctx, r.client, []string{r.key}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease %s: %w", r.key, err)
+	}
+
+	return renewed == 1, nil
+}
+
+func (r *LeaderLeaseRedis) Release(ctx context.Context, holder string) error {
+	if err := releaseScript.Run(ctx, r.client, []string{r.key}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", r.key, err)
+	}
+
+	return nil
+}
+
+func (r *LeaderLeaseRedis) Holder(ctx context.Context) (string, error) {
+	holder, err := r.client.Get(ctx, r.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read lease %s: %w", r.key, err)
+	}
+
+	return holder, nil
+}
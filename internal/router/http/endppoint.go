@@ -3,12 +3,13 @@ package http
 import (
 	handler "metertronik/internal/handler/api"
 	"metertronik/internal/middleware"
+	"metertronik/internal/service"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, apiHandler *handler.ApiHandler, authHandler *handler.AuthHandler) {
+func SetupRoutes(r *gin.Engine, apiHandler *handler.ApiHandler, authHandler *handler.AuthHandler, deviceHandler *handler.DeviceHandler, policyHandler *handler.ThresholdPolicyHandler, eventHandler *handler.EventHandler, authorizer *service.DeviceAuthorizer) {
 	rest := r.Group("/v1")
 
 	auth := rest.Group("/api/auth")
@@ -35,10 +36,36 @@ func SetupRoutes(r *gin.Engine, apiHandler *handler.ApiHandler, authHandler *han
 				"version": "1.0.0",
 			})
 		})
-		api.GET("/daily/:id", apiHandler.GetDailyList)
-		api.GET("/daily/:id/detail", apiHandler.GetSpecificDailyActivity)
-		api.GET("/daily/:id/range", apiHandler.GetDailyRange)
-		api.GET("/monthly/:id", apiHandler.GetMonthlyList)
+
+		// Semua route dengan :id device melewati pengecekan akses.
+		deviceAccess := middleware.DeviceAccess(authorizer)
+
+		api.GET("/daily/:id", deviceAccess, apiHandler.GetDailyList)
+		api.GET("/daily/:id/detail", deviceAccess, apiHandler.GetSpecificDailyActivity)
+		api.GET("/daily/:id/range", deviceAccess, apiHandler.GetDailyRange)
+		api.GET("/monthly/:id", deviceAccess, apiHandler.GetMonthlyList)
+		api.GET("/yearly/:id", deviceAccess, apiHandler.GetYearlyList)
+		api.GET("/realtime/:id/history", deviceAccess, apiHandler.GetRealtimeHistory)
+		api.GET("/events/:id", deviceAccess, eventHandler.GetSurgeEvents)
+
+		api.GET("/devices", deviceHandler.ListDevices)
+		api.POST("/devices/claim", deviceHandler.ClaimDevice)
+		api.GET("/devices/:id", deviceAccess, deviceHandler.GetDevice)
+		api.PUT("/devices/:id", deviceAccess, deviceHandler.UpdateDevice)
+		api.DELETE("/devices/:id", deviceAccess, deviceHandler.DeleteDevice)
+		api.POST("/devices/:id/unclaim", deviceAccess, deviceHandler.UnclaimDevice)
+		api.POST("/devices/:id/transfer", deviceAccess, deviceHandler.TransferDevice)
+		api.GET("/devices/:id/clock", deviceAccess, apiHandler.GetClockStats)
+		api.GET("/devices/:id/ingest-keys", deviceAccess, deviceHandler.ListIngestKeys)
+		api.POST("/devices/:id/ingest-keys/rotate", deviceAccess, deviceHandler.RotateIngestKey)
+		api.GET("/devices/:id/members", deviceAccess, deviceHandler.ListMembers)
+		api.POST("/devices/:id/members", deviceAccess, deviceHandler.AddMember)
+		api.DELETE("/devices/:id/members/:user_id", deviceAccess, deviceHandler.RemoveMember)
+
+		api.GET("/threshold-policies", policyHandler.ListPolicies)
+		api.POST("/threshold-policies", policyHandler.CreatePolicy)
+		api.PUT("/threshold-policies/:policy_id", policyHandler.UpdatePolicy)
+		api.DELETE("/threshold-policies/:policy_id", policyHandler.DeletePolicy)
 
 		// api.GET("/daily/summary", func(ctx *gin.Context) {
 
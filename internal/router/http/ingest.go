@@ -0,0 +1,13 @@
+package http
+
+import (
+	"metertronik/internal/handler/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupIngestRoutes dipasang di proses ingestor, bukan di API server, karena
+// hanya ingestor yang menulis ke InfluxDB.
+func SetupIngestRoutes(r *gin.Engine, ingestHandler *ingest.HTTPHandler) {
+	r.POST("/v1/ingest", ingestHandler.Ingest)
+}
@@ -2,19 +2,21 @@ package websocket
 
 import (
 	"net/http"
+	"time"
 
 	"metertronik/internal/domain/repository"
 	wsHandler "metertronik/internal/handler/ws"
+	"metertronik/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func WebSocketRoutes(r *gin.Engine, RedisRealtimeRepo repository.RedisRealtimeRepo) {
-	if RedisRealtimeRepo == nil {
+func WebSocketRoutes(r *gin.Engine, RedisRealtimeRepo repository.RedisRealtimeRepo, hub *wsHandler.Hub, authorizer *service.DeviceAuthorizer, allowedOrigins []string, historyWindow time.Duration, accessRecheck time.Duration) {
+	if RedisRealtimeRepo == nil || hub == nil {
 		return
 	}
 
-	wsStreamHandler := wsHandler.NewStreamHandler(RedisRealtimeRepo)
+	wsStreamHandler := wsHandler.NewStreamHandler(RedisRealtimeRepo, hub, authorizer, allowedOrigins, historyWindow, accessRecheck)
 
 	r.GET("/v1/ws/electricity/:deviceID", func(c *gin.Context) {
 		deviceID := c.Param("deviceID")
@@ -22,6 +24,10 @@ func WebSocketRoutes(r *gin.Engine, RedisRealtimeRepo repository.RedisRealtimeRe
 			c.JSON(http.StatusBadRequest, gin.H{"error": "deviceID is required"})
 			return
 		}
-		wsStreamHandler.HandleWebSocket(c.Writer, c.Request, deviceID)
+		wsStreamHandler.HandleWebSocket(c.Writer, c.Request, deviceID, c.ClientIP())
+	})
+
+	r.GET("/v1/ws/stream", func(c *gin.Context) {
+		wsStreamHandler.HandleMultiplexWebSocket(c.Writer, c.Request, c.ClientIP())
 	})
 }
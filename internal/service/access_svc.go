@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+)
+
+// DeviceAuthorizer memeriksa apakah user boleh membaca data device (pemilik
+// atau anggota) dan mencatat setiap penolakan ke audit log. Dipakai oleh
+// middleware HTTP dan handler WebSocket.
+type DeviceAuthorizer struct {
+	deviceRepo repository.DeviceRepo
+	auditRepo  repository.AuditRepo
+}
+
+func NewDeviceAuthorizer(deviceRepo repository.DeviceRepo, auditRepo repository.AuditRepo) *DeviceAuthorizer {
+	return &DeviceAuthorizer{
+		deviceRepo: deviceRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// Authorize mengembalikan error hanya jika akses tidak bisa diperiksa;
+// penolakan dikembalikan sebagai false.
+func (a *DeviceAuthorizer) Authorize(ctx context.Context, userID int64, deviceID string, resource string, ipAddress string) (bool, error) {
+	ok, err := a.deviceRepo.CanAccessDevice(ctx, deviceID, userID)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	log.Printf("[AUDIT] User %d denied access to device %s (%s)", userID, deviceID, resource)
+
+	if a.auditRepo != nil {
+		entry := &entity.AuditLog{
+			UserID:    userID,
+			Action:    entity.AuditActionDeviceAccessDenied,
+			DeviceID:  deviceID,
+			Resource:  resource,
+			IPAddress: ipAddress,
+		}
+		if err := a.auditRepo.CreateAuditLog(context.WithoutCancel(ctx), entry); err != nil {
+			log.Printf("Failed to write audit log: %v", err)
+		}
+	}
+
+	return false, nil
+}
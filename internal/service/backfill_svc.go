@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+type BackfillRequest struct {
+	DeviceIDs   []string
+	Level       string
+	Start       time.Time
+	End         time.Time
+	Concurrency int
+	DryRun      bool
+}
+
+type BackfillJob struct {
+	DeviceID string
+	Level    string
+	Period   time.Time
+}
+
+type BackfillProgress struct {
+	Done  int
+	Total int
+	Job   BackfillJob
+	Err   error
+}
+
+// BackfillPlan menghasilkan daftar periode yang akan dihitung ulang untuk
+// setiap device, dengan periode dibulatkan ke awal jam/hari/bulan.
+func BackfillPlan(req BackfillRequest) ([]BackfillJob, error) {
+	var step func(time.Time) time.Time
+	var first time.Time
+
+	start := req.Start.UTC()
+
+	switch req.Level {
+	case entity.JobKindHourly:
+		first = start.Truncate(time.Hour)
+		step = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case entity.JobKindDaily:
+		first = utils.NewTimeData(start).StartOfDay().Time
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case entity.JobKindMonthly:
+		first = utils.NewTimeData(start).StartOfMonth().Time
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case entity.JobKindYearly:
+		first = utils.NewTimeData(start).StartOfYear().Time
+		step = func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	default:
+		return nil, fmt.Errorf("unknown level %q (expected hourly, daily, monthly or yearly)", req.Level)
+	}
+
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	periods := periodsBetween(first, req.End.UTC(), step)
+
+	jobs := make([]BackfillJob, 0, len(periods)*len(req.DeviceIDs))
+	for _, deviceID := range req.DeviceIDs {
+		for _, period := range periods {
+			jobs = append(jobs, BackfillJob{DeviceID: deviceID, Level: req.Level, Period: period})
+		}
+	}
+
+	return jobs, nil
+}
+
+// Backfill menghitung ulang agregat lewat RunJob (upsert yang sama dengan
+// scheduler) dengan paling banyak Concurrency job berjalan bersamaan.
+func (s *CronService) Backfill(ctx context.Context, req BackfillRequest, progress func(BackfillProgress)) (int, error) {
+	jobs, err := BackfillPlan(req)
+	if err != nil {
+		return 0, err
+	}
+
+	if req.DryRun {
+		for i, job := range jobs {
+			progress(BackfillProgress{Done: i + 1, Total: len(jobs), Job: job})
+		}
+		return 0, nil
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	queue := make(chan BackfillJob)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done, failed := 0, 0
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				err := s.RunJob(ctx, job.Level, job.DeviceID, job.Period, 0)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failed++
+				}
+				progress(BackfillProgress{Done: done, Total: len(jobs), Job: job, Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return failed, err
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+var ingestClockCorrectedTotal = expvar.NewInt("ingest_clock_corrected_total")
+
+// ClockValidator memeriksa timestamp device terhadap waktu terima. Timestamp
+// yang lebih cepat dari maxAhead atau lebih lambat dari maxBehind (mis. RTC
+// mati yang melapor tahun 2000) diganti waktu terima dan ditandai.
+type ClockValidator struct {
+	realtimeRepo repository.RedisRealtimeRepo
+	maxAhead     time.Duration
+	maxBehind    time.Duration
+}
+
+// NewClockValidator membuat validator jam device. Toleransi 0 berarti tidak
+// dibatasi ke arah tersebut.
+func NewClockValidator(realtimeRepo repository.RedisRealtimeRepo, maxAhead time.Duration, maxBehind time.Duration) *ClockValidator {
+	return &ClockValidator{
+		realtimeRepo: realtimeRepo,
+		maxAhead:     maxAhead,
+		maxBehind:    maxBehind,
+	}
+}
+
+// Apply menormalkan data.CreatedAt dan mencatat sampel skew device.
+// receivedAt adalah waktu terima menurut jam server.
+func (v *ClockValidator) Apply(ctx context.Context, data *entity.RealTimeElectricity, receivedAt time.Time) {
+	v.ApplyBatch(ctx, []*entity.RealTimeElectricity{data}, receivedAt)
+}
+
+// ApplyBatch seperti Apply untuk reading yang diterima bersamaan (satu
+// request HTTP). Reading tanpa timestamp atau yang dikoreksi tidak diberi
+// receivedAt yang sama, karena point InfluxDB-nya akan saling menimpa:
+// masing-masing mundur dari receivedAt sejauh selisih jam device-nya terhadap
+// reading terbaru di batch (selisih di luar maxBehind diabaikan), dan waktu
+// yang bentrok digeser mundur 1ms dengan reading terakhir tetap di receivedAt.
+func (v *ClockValidator) ApplyBatch(ctx context.Context, readings []*entity.RealTimeElectricity, receivedAt time.Time) {
+	var assigned []*entity.RealTimeElectricity
+	var newest time.Time
+
+	for _, data := range readings {
+		if !v.check(ctx, data, receivedAt) {
+			continue
+		}
+		assigned = append(assigned, data)
+		if data.DeviceTime != nil && data.DeviceTime.Time.After(newest) {
+			newest = data.DeviceTime.Time
+		}
+	}
+
+	used := make(map[int64]bool, len(assigned))
+	for i := len(assigned) - 1; i >= 0; i-- {
+		data := assigned[i]
+
+		var delta time.Duration
+		if data.DeviceTime != nil {
+			delta = newest.Sub(data.DeviceTime.Time)
+			if v.maxBehind > 0 && delta > v.maxBehind {
+				delta = 0
+			}
+		}
+
+		at := receivedAt.Add(-delta)
+		for used[at.UnixNano()] {
+			at = at.Add(-time.Millisecond)
+		}
+		used[at.UnixNano()] = true
+
+		data.CreatedAt = utils.NewTimeData(at)
+	}
+}
+
+// check memeriksa timestamp satu reading. Hasil true berarti reading tidak
+// punya timestamp atau timestamp-nya dikoreksi, sehingga CreatedAt harus
+// diisi dari waktu terima.
+func (v *ClockValidator) check(ctx context.Context, data *entity.RealTimeElectricity, receivedAt time.Time) bool {
+	data.ClockCorrected = false
+	data.DeviceTime = nil
+
+	if data.CreatedAt.Time.IsZero() {
+		return true
+	}
+
+	deviceTime := data.CreatedAt.Time
+	skew := deviceTime.Sub(receivedAt)
+
+	corrected := (v.maxAhead > 0 && skew > v.maxAhead) || (v.maxBehind > 0 && -skew > v.maxBehind)
+	if corrected {
+		original := data.CreatedAt
+		data.DeviceTime = &original
+		data.ClockCorrected = true
+
+		ingestClockCorrectedTotal.Add(1)
+		log.Printf("Device %s clock out of tolerance (device %s, skew %s), using receive time", data.DeviceID, original.Format(), skew)
+	}
+
+	if err := v.realtimeRepo.RecordClockSample(ctx, data.DeviceID, deviceTime, receivedAt, corrected); err != nil {
+		log.Printf("Failed recording clock sample: %v", err)
+	}
+
+	return corrected
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+type clockSample struct {
+	deviceTime time.Time
+	corrected  bool
+}
+
+// fakeClockRepo hanya mengimplementasikan RecordClockSample; method lain panic.
+type fakeClockRepo struct {
+	repository.RedisRealtimeRepo
+	samples []clockSample
+}
+
+func (r *fakeClockRepo) RecordClockSample(ctx context.Context, deviceID string, deviceTime time.Time, receivedAt time.Time, corrected bool) error {
+	r.samples = append(r.samples, clockSample{deviceTime: deviceTime, corrected: corrected})
+	return nil
+}
+
+func TestClockValidatorApply(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		maxAhead      time.Duration
+		maxBehind     time.Duration
+		deviceTime    time.Time
+		wantCreatedAt time.Time
+		wantCorrected bool
+		wantSample    bool
+	}{
+		{
+			name:          "missing timestamp uses receive time",
+			maxAhead:      time.Minute,
+			maxBehind:     time.Hour,
+			wantCreatedAt: receivedAt,
+		},
+		{
+			name:          "within tolerance",
+			maxAhead:      time.Minute,
+			maxBehind:     time.Hour,
+			deviceTime:    receivedAt.Add(-10 * time.Minute),
+			wantCreatedAt: receivedAt.Add(-10 * time.Minute),
+			wantSample:    true,
+		},
+		{
+			name:          "too far ahead",
+			maxAhead:      time.Minute,
+			maxBehind:     time.Hour,
+			deviceTime:    receivedAt.Add(5 * time.Minute),
+			wantCreatedAt: receivedAt,
+			wantCorrected: true,
+			wantSample:    true,
+		},
+		{
+			name:          "dead RTC far behind",
+			maxAhead:      time.Minute,
+			maxBehind:     time.Hour,
+			deviceTime:    time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantCreatedAt: receivedAt,
+			wantCorrected: true,
+			wantSample:    true,
+		},
+		{
+			name:          "unbounded behind",
+			maxAhead:      time.Minute,
+			deviceTime:    receivedAt.Add(-48 * time.Hour),
+			wantCreatedAt: receivedAt.Add(-48 * time.Hour),
+			wantSample:    true,
+		},
+		{
+			name:          "unbounded ahead",
+			maxBehind:     time.Hour,
+			deviceTime:    receivedAt.Add(48 * time.Hour),
+			wantCreatedAt: receivedAt.Add(48 * time.Hour),
+			wantSample:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeClockRepo{}
+			validator := NewClockValidator(repo, tt.maxAhead, tt.maxBehind)
+
+			data := &entity.RealTimeElectricity{DeviceID: "dev-1"}
+			if !tt.deviceTime.IsZero() {
+				data.CreatedAt = utils.NewTimeData(tt.deviceTime)
+			}
+
+			validator.Apply(context.Background(), data, receivedAt)
+
+			if !data.CreatedAt.Time.Equal(tt.wantCreatedAt) {
+				t.Errorf("CreatedAt = %s, want %s", data.CreatedAt.Time, tt.wantCreatedAt)
+			}
+			if data.ClockCorrected != tt.wantCorrected {
+				t.Errorf("ClockCorrected = %t, want %t", data.ClockCorrected, tt.wantCorrected)
+			}
+
+			if tt.wantCorrected {
+				if data.DeviceTime == nil || !data.DeviceTime.Time.Equal(tt.deviceTime) {
+					t.Errorf("DeviceTime = %v, want %s", data.DeviceTime, tt.deviceTime)
+				}
+			} else if data.DeviceTime != nil {
+				t.Errorf("DeviceTime = %v, want nil", data.DeviceTime)
+			}
+
+			if (len(repo.samples) == 1) != tt.wantSample || len(repo.samples) > 1 {
+				t.Fatalf("recorded %d clock sample(s), want sample %t", len(repo.samples), tt.wantSample)
+			}
+			if tt.wantSample && repo.samples[0].corrected != tt.wantCorrected {
+				t.Errorf("sample corrected = %t, want %t", repo.samples[0].corrected, tt.wantCorrected)
+			}
+		})
+	}
+}
+
+func TestClockValidatorApplyBatch(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deadRTC := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		deviceTimes []time.Time // zero berarti reading tanpa timestamp
+		want        []time.Time
+	}{
+		{
+			name:        "dead RTC keeps device spacing",
+			deviceTimes: []time.Time{deadRTC.Add(10 * time.Second), deadRTC.Add(20 * time.Second), deadRTC.Add(30 * time.Second)},
+			want:        []time.Time{receivedAt.Add(-20 * time.Second), receivedAt.Add(-10 * time.Second), receivedAt},
+		},
+		{
+			name:        "equal device times spread by position",
+			deviceTimes: []time.Time{deadRTC, deadRTC, deadRTC},
+			want:        []time.Time{receivedAt.Add(-2 * time.Millisecond), receivedAt.Add(-time.Millisecond), receivedAt},
+		},
+		{
+			name:        "missing timestamps spread by position",
+			deviceTimes: []time.Time{{}, {}},
+			want:        []time.Time{receivedAt.Add(-time.Millisecond), receivedAt},
+		},
+		{
+			name:        "valid timestamps left alone",
+			deviceTimes: []time.Time{receivedAt.Add(-2 * time.Minute), deadRTC, receivedAt.Add(-time.Minute), deadRTC.Add(5 * time.Second)},
+			want:        []time.Time{receivedAt.Add(-2 * time.Minute), receivedAt.Add(-5 * time.Second), receivedAt.Add(-time.Minute), receivedAt},
+		},
+		{
+			name:        "delta beyond tolerance ignored",
+			deviceTimes: []time.Time{deadRTC, receivedAt.Add(10 * time.Minute)},
+			want:        []time.Time{receivedAt.Add(-time.Millisecond), receivedAt},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewClockValidator(&fakeClockRepo{}, time.Minute, time.Hour)
+
+			readings := make([]*entity.RealTimeElectricity, len(tt.deviceTimes))
+			for i, deviceTime := range tt.deviceTimes {
+				readings[i] = &entity.RealTimeElectricity{DeviceID: "dev-1", CreatedAt: utils.NewTimeData(deviceTime)}
+				if deviceTime.IsZero() {
+					readings[i].CreatedAt = utils.TimeData{}
+				}
+			}
+
+			validator.ApplyBatch(context.Background(), readings, receivedAt)
+
+			for i, data := range readings {
+				if !data.CreatedAt.Time.Equal(tt.want[i]) {
+					t.Errorf("reading %d CreatedAt = %s, want %s", i, data.CreatedAt.Time, tt.want[i])
+				}
+			}
+		})
+	}
+}
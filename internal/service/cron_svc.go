@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"math"
 	"time"
 
 	"metertronik/internal/domain/entity"
@@ -10,15 +13,38 @@ import (
 	"metertronik/pkg/utils"
 )
 
+var (
+	ErrNoRealtimeData = errors.New("no realtime data for hour")
+	ErrNoHourlyData   = errors.New("no hourly data for day")
+	ErrNoDailyData    = errors.New("no daily data for month")
+	ErrNoMonthlyData  = errors.New("no monthly data for year")
+)
+
+// maxSampleGap adalah durasi terpanjang yang boleh diwakili satu sampel;
+// jeda yang lebih panjang dihitung sebagai data yang hilang.
+const maxSampleGap = 2 * time.Minute
+
+// registerLookback membatasi seberapa jauh ke belakang pembacaan register
+// terakhir dicari sebagai titik awal perhitungan energi meter kumulatif.
+const registerLookback = 24 * time.Hour
+
+// minRecheckInterval adalah jarak minimum sebelum periode hourly yang sudah
+// selesai diperiksa ulang untuk data terlambat.
+const minRecheckInterval = 15 * time.Minute
+
 type CronService struct {
 	influxRepo   repository.InfluxRepo
 	postgresRepo repository.PostgresRepo
+	jobRepo      repository.AggregationJobRepo
+	deviceCache  *DeviceCache
 }
 
-func NewCronService(influxRepo repository.InfluxRepo, postgresRepo repository.PostgresRepo) *CronService {
+func NewCronService(influxRepo repository.InfluxRepo, postgresRepo repository.PostgresRepo, jobRepo repository.AggregationJobRepo, deviceCache *DeviceCache) *CronService {
 	return &CronService{
 		influxRepo:   influxRepo,
 		postgresRepo: postgresRepo,
+		jobRepo:      jobRepo,
+		deviceCache:  deviceCache,
 	}
 }
 
@@ -33,108 +59,251 @@ func (s *CronService) HourlyAggregation(
 
 	realtimeDataList, err := s.influxRepo.
 		GetRealTimeElectricityRange(ctx, deviceID, start, end)
-	if err != nil || realtimeDataList == nil {
-		return nil, errors.New("no realtime data for hour")
+	if err != nil {
+		return nil, err
+	}
+	if realtimeDataList == nil {
+		return nil, ErrNoRealtimeData
 	}
 
-	tarrifs, err := s.postgresRepo.GetTarrifs(ctx)
+	device := s.deviceCache.Get(ctx, deviceID)
+
+	var previous *entity.RealTimeElectricity
+	if device.IsCumulative() {
+		previous, err = s.influxRepo.GetLastRealTimeElectricityBefore(ctx, deviceID, start, registerLookback)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tarrifs, err := s.postgresRepo.GetTarrifs(ctx, device.TariffClass)
 	if err != nil {
 		return nil, err
 	}
 
-	dataList := *realtimeDataList
+	summary := SummarizeRealtime(*realtimeDataList, previous, start.Time, end.Time, device)
+
+	hourly := entity.HourlyElectricity{
+		DeviceID:        deviceID,
+		Energy:          summary.Energy,
+		TotalCost:       (summary.Energy * tarrifs.PricePerKwh) * 1.10,
+		AvgVoltage:      summary.AvgVoltage,
+		AvgCurrent:      summary.AvgCurrent,
+		AvgPower:        summary.AvgPower,
+		MinPower:        summary.MinPower,
+		MaxPower:        summary.MaxPower,
+		CoveragePercent: summary.CoveragePercent,
+		SampleCount:     summary.SampleCount,
+		TS:              start,
+		CreatedAt:       utils.TimeNow(),
+	}
+
+	return &hourly, s.postgresRepo.UpsertHourlyElectricity(ctx, &hourly)
+}
+
+// SummarizeRealtime meringkas sampel realtime dalam [start, end). Setiap
+// sampel mewakili durasi sampai sampel berikutnya (maksimal maxSampleGap),
+// sehingga perangkat yang melapor lebih sering saat beban tinggi tidak
+// mendominasi rata-rata. Untuk meter kumulatif, energi adalah selisih
+// register sejak previous (pembacaan terakhir sebelum start, boleh nil).
+func SummarizeRealtime(
+	dataList []entity.RealTimeElectricity,
+	previous *entity.RealTimeElectricity,
+	start time.Time,
+	end time.Time,
+	device *entity.Device,
+) HourlySummary {
+	var summary HourlySummary
 	count := len(dataList)
+	if count == 0 {
+		return summary
+	}
 
-	var totalVoltage, totalCurrent, totalPower, totalFrequency, energy float64
-	minPower := dataList[0].Power
-	maxPower := dataList[0].Power
+	var covered time.Duration
+	var totalVoltage, totalCurrent, totalPower float64
+	summary.MinPower = dataList[0].Power
+	summary.MaxPower = dataList[0].Power
 
-	for _, d := range dataList {
-		totalVoltage += d.Voltage
-		totalCurrent += d.Current
-		totalPower += d.Power
-		totalFrequency += d.Frequency
-		energy += d.Energy
+	for i, d := range dataList {
+		next := end
+		if i+1 < count {
+			next = dataList[i+1].CreatedAt.Time
+		}
 
-		if d.Power < minPower {
-			minPower = d.Power
+		weight := next.Sub(d.CreatedAt.Time)
+		if weight > maxSampleGap {
+			weight = maxSampleGap
 		}
-		if d.Power > maxPower {
-			maxPower = d.Power
+		if weight < 0 {
+			weight = 0
+		}
+
+		seconds := weight.Seconds()
+		totalVoltage += d.Voltage * seconds
+		totalCurrent += d.Current * seconds
+		totalPower += d.Power * seconds
+		covered += weight
+
+		if d.Power < summary.MinPower {
+			summary.MinPower = d.Power
+		}
+		if d.Power > summary.MaxPower {
+			summary.MaxPower = d.Power
 		}
 	}
 
-	hourly := entity.HourlyElectricity{
-		DeviceID:   deviceID,
-		Energy:     energy,
-		TotalCost:  (energy * tarrifs.PricePerKwh) * 1.10,
-		AvgVoltage: totalVoltage / float64(count),
-		AvgCurrent: totalCurrent / float64(count),
-		AvgPower:   totalPower / float64(count),
-		MinPower:   minPower,
-		MaxPower:   maxPower,
-		TS:         start,
-		CreatedAt:  utils.TimeNow(),
+	if covered > 0 {
+		summary.AvgVoltage = totalVoltage / covered.Seconds()
+		summary.AvgCurrent = totalCurrent / covered.Seconds()
+		summary.AvgPower = totalPower / covered.Seconds()
+	} else {
+		for _, d := range dataList {
+			summary.AvgVoltage += d.Voltage / float64(count)
+			summary.AvgCurrent += d.Current / float64(count)
+			summary.AvgPower += d.Power / float64(count)
+		}
 	}
 
-	return &hourly, s.postgresRepo.UpsertHourlyElectricity(ctx, &hourly)
+	if device.IsCumulative() {
+		registers := make([]float64, 0, count+1)
+		if previous != nil {
+			registers = append(registers, previous.Energy)
+		}
+		for _, d := range dataList {
+			registers = append(registers, d.Energy)
+		}
+		summary.Energy = utils.RegisterEnergy(registers, device.EnergyRegisterMax)
+	} else {
+		for _, d := range dataList {
+			summary.Energy += d.Energy
+		}
+	}
+
+	summary.CoveragePercent = coveragePercent(covered, end.Sub(start))
+	summary.SampleCount = count
+
+	return summary
 }
 
+// DailyAggregation menghitung tanggal kalender targetDay menurut zona waktu
+// device, yaitu jam-jam dari tengah malam lokal sampai tengah malam berikutnya.
 func (s *CronService) DailyAggregation(
 	ctx context.Context,
 	targetDay time.Time,
 	deviceID string,
 ) (*entity.DailyElectricity, error) {
 
-	start := utils.NewTimeData(targetDay)
-	end := utils.NewTimeData(targetDay.AddDate(0, 0, 1))
+	device := s.deviceCache.Get(ctx, deviceID)
+	loc := device.Location()
+	day := utils.NewTimeData(targetDay)
+
+	start := day.StartIn(loc)
+	end := day.AddDays(1).StartIn(loc)
 
 	hourlyDataList, err := s.postgresRepo.
 		GetHourlyElectricityRange(ctx, deviceID, start, end)
-	if err != nil || hourlyDataList == nil {
-		return nil, errors.New("no hourly data for day")
+	if err != nil {
+		return nil, err
+	}
+	if hourlyDataList == nil {
+		return nil, ErrNoHourlyData
 	}
 
-	dataList := *hourlyDataList
-	count := len(dataList)
+	summary := SummarizeHourly(*hourlyDataList, end.Time.Sub(start.Time))
 
-	var totalVoltage, totalCurrent, totalPower, energy float64
-	minPower := dataList[0].MinPower
-	maxPower := dataList[0].MaxPower
+	tarrifs, err := s.postgresRepo.GetTarrifs(ctx, device.TariffClass)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, d := range dataList {
-		totalVoltage += d.AvgVoltage
-		totalCurrent += d.AvgCurrent
-		totalPower += d.AvgPower
-		energy += d.Energy
+	daily := entity.DailyElectricity{
+		DeviceID:        deviceID,
+		Energy:          summary.Energy,
+		TotalCost:       (summary.Energy * tarrifs.PricePerKwh) * 1.10,
+		AvgVoltage:      summary.AvgVoltage,
+		AvgCurrent:      summary.AvgCurrent,
+		AvgPower:        summary.AvgPower,
+		MinPower:        summary.MinPower,
+		MaxPower:        summary.MaxPower,
+		CoveragePercent: summary.CoveragePercent,
+		SampleCount:     summary.SampleCount,
+		Day:             day,
+		Timezone:        loc.String(),
+		CreatedAt:       utils.TimeNow(),
+	}
+
+	return &daily, s.postgresRepo.UpsertDailyElectricity(ctx, &daily)
+}
+
+type HourlySummary struct {
+	Energy          float64
+	AvgVoltage      float64
+	AvgCurrent      float64
+	AvgPower        float64
+	MinPower        float64
+	MaxPower        float64
+	CoveragePercent float64
+	SampleCount     int
+}
+
+// SummarizeHourly menggabungkan baris hourly menjadi ringkasan untuk periode
+// sepanjang period. Rata-rata diberi bobot sesuai coverage setiap jam; baris
+// lama tanpa coverage dianggap berbobot sama.
+func SummarizeHourly(list []entity.HourlyElectricity, period time.Duration) HourlySummary {
+	var summary HourlySummary
+	if len(list) == 0 {
+		return summary
+	}
+
+	summary.MinPower = list[0].MinPower
+	summary.MaxPower = list[0].MaxPower
+
+	var totalWeight, totalCoverage float64
+	for _, d := range list {
+		totalCoverage += d.CoveragePercent
+	}
+
+	for _, d := range list {
+		weight := d.CoveragePercent
+		if totalCoverage == 0 {
+			weight = 1
+		}
+
+		summary.AvgVoltage += d.AvgVoltage * weight
+		summary.AvgCurrent += d.AvgCurrent * weight
+		summary.AvgPower += d.AvgPower * weight
+		totalWeight += weight
 
-		if d.MinPower < minPower {
-			minPower = d.MinPower
+		summary.Energy += d.Energy
+		summary.SampleCount += d.SampleCount
+
+		if d.MinPower < summary.MinPower {
+			summary.MinPower = d.MinPower
 		}
-		if d.MaxPower > maxPower {
-			maxPower = d.MaxPower
+		if d.MaxPower > summary.MaxPower {
+			summary.MaxPower = d.MaxPower
 		}
 	}
 
-	tarrifs, err := s.postgresRepo.GetTarrifs(ctx)
-	if err != nil {
-		return nil, err
+	if totalWeight > 0 {
+		summary.AvgVoltage /= totalWeight
+		summary.AvgCurrent /= totalWeight
+		summary.AvgPower /= totalWeight
 	}
 
-	daily := entity.DailyElectricity{
-		DeviceID:   deviceID,
-		Energy:     energy,
-		TotalCost:  (energy * tarrifs.PricePerKwh) * 1.10,
-		AvgVoltage: totalVoltage / float64(count),
-		AvgCurrent: totalCurrent / float64(count),
-		AvgPower:   totalPower / float64(count),
-		MinPower:   minPower,
-		MaxPower:   maxPower,
-		Day:        start,
-		CreatedAt:  utils.TimeNow(),
+	hours := period.Hours()
+	if hours > 0 {
+		summary.CoveragePercent = math.Min(totalCoverage/hours, 100)
 	}
 
-	return &daily, s.postgresRepo.UpsertDailyElectricity(ctx, &daily)
+	return summary
+}
+
+func coveragePercent(covered time.Duration, period time.Duration) float64 {
+	if period <= 0 {
+		return 0
+	}
+	return math.Min(covered.Seconds()/period.Seconds()*100, 100)
 }
 
 func (s *CronService) MonthlyAggregation(
@@ -146,8 +315,10 @@ func (s *CronService) MonthlyAggregation(
 	var dailyList *[]entity.DailyElectricity
 	var err error
 
+	loc := s.deviceCache.Get(ctx, deviceID).Location()
+
 	if targetMonth.IsFirstDayOfMonth() {
-		dailyElectricity, _, err := s.postgresRepo.GetDailyElectricity(ctx, deviceID, targetMonth)
+		dailyElectricity, _, err := s.postgresRepo.GetDailyElectricity(ctx, deviceID, targetMonth, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -155,19 +326,19 @@ func (s *CronService) MonthlyAggregation(
 		if dailyElectricity != nil {
 			dailyList = &[]entity.DailyElectricity{*dailyElectricity}
 		} else {
-			return nil, errors.New("no daily data for month")
+			return nil, ErrNoDailyData
 		}
 	} else {
 		startDate, endDate := targetMonth.GetMonthlyRangeDates()
 
-		dailyList, err = s.postgresRepo.GetDailyRange(ctx, deviceID, startDate, endDate, nil, 32)
+		dailyList, err = s.postgresRepo.GetDailyRange(ctx, deviceID, startDate, endDate, nil, 32, loc)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if dailyList == nil || len(*dailyList) == 0 {
-		return nil, errors.New("no daily data for month")
+		return nil, ErrNoDailyData
 	}
 
 	dataList := *dailyList
@@ -183,8 +354,288 @@ func (s *CronService) MonthlyAggregation(
 		Month:     targetMonth.StartOfMonth(),
 		Energy:    totalEnergy,
 		TotalCost: totalCost,
+		Timezone:  loc.String(),
 		CreatedAt: utils.TimeNow(),
 	}
 
 	return &monthly, s.postgresRepo.UpsertMonthlyElectricity(ctx, &monthly)
 }
+
+func (s *CronService) YearlyAggregation(
+	ctx context.Context,
+	targetYear utils.TimeData,
+	deviceID string,
+) (*entity.YearlyElectricity, error) {
+
+	loc := s.deviceCache.Get(ctx, deviceID).Location()
+
+	start := targetYear.StartOfYear()
+	end := utils.NewTimeData(start.Time.AddDate(1, 0, 0))
+
+	monthlyList, err := s.postgresRepo.GetMonthlyRange(ctx, deviceID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if monthlyList == nil || len(*monthlyList) == 0 {
+		return nil, ErrNoMonthlyData
+	}
+
+	var totalEnergy, totalCost float64
+
+	for _, m := range *monthlyList {
+		totalEnergy += m.Energy
+		totalCost += m.TotalCost
+	}
+
+	yearly := entity.YearlyElectricity{
+		DeviceID:  deviceID,
+		Year:      start,
+		Energy:    totalEnergy,
+		TotalCost: totalCost,
+		Timezone:  loc.String(),
+		CreatedAt: utils.TimeNow(),
+	}
+
+	return &yearly, s.postgresRepo.UpsertYearlyElectricity(ctx, &yearly)
+}
+
+type CatchUpConfig struct {
+	Lookback    time.Duration
+	MaxAttempts int
+}
+
+// CatchUp mencari periode hourly dan daily dalam jendela Lookback yang belum
+// tercatat selesai di ledger (atau gagal dengan attempt tersisa), lalu
+// menjalankannya berurutan. Jam yang sudah selesai juga diperiksa ulang
+// (lihat dueForRecheck) agar reading terlambat ikut teragregasi, dan hari,
+// bulan, serta tahun di atasnya ikut diperbarui. Dipanggil saat startup dan
+// di setiap tick.
+// Periode daily ke atas adalah tanggal kalender di zona waktu device, jadi
+// sebuah hari baru diagregasi setelah tengah malam lokalnya lewat.
+func (s *CronService) CatchUp(ctx context.Context, deviceIDs []string, now utils.TimeData, cfg CatchUpConfig) {
+	lastHour := now.TruncateHour()
+	firstHour := lastHour.Add(-cfg.Lookback)
+
+	for _, deviceID := range deviceIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		hours := periodsBetween(firstHour.Time, lastHour.Time, func(t time.Time) time.Time {
+			return t.Add(time.Hour)
+		})
+		ranHours := s.catchUpKind(ctx, entity.JobKindHourly, deviceID, hours, cfg.MaxAttempts, nil, func(job entity.AggregationJob, period time.Time) bool {
+			return dueForRecheck(job, period.Add(time.Hour), now.Time)
+		})
+
+		loc := s.deviceCache.Get(ctx, deviceID).Location()
+
+		// Hari yang jam-jamnya baru saja terisi harus diagregasi ulang
+		// walaupun ledger daily-nya sudah selesai.
+		staleDays := make(map[time.Time]bool)
+		for _, hour := range ranHours {
+			staleDays[utils.NewTimeData(hour).DateIn(loc).Time] = true
+		}
+
+		firstDay := firstHour.DateIn(loc)
+		today := lastHour.DateIn(loc)
+		days := periodsBetween(firstDay.Time, today.Time, func(t time.Time) time.Time {
+			return t.AddDate(0, 0, 1)
+		})
+		ranDays := s.catchUpKind(ctx, entity.JobKindDaily, deviceID, days, cfg.MaxAttempts, staleDays, nil)
+
+		s.rollUp(ctx, deviceID, firstDay, today, ranDays, cfg.MaxAttempts)
+	}
+}
+
+// rollUp memperbarui monthly dan yearly setelah daily berjalan. Bulan/tahun
+// yang masih berjalan hanya di-refresh; yang sudah tutup difinalisasi lewat
+// ledger sehingga tetap dikerjakan walaupun cron sempat mati saat pergantian.
+func (s *CronService) rollUp(ctx context.Context, deviceID string, firstDay utils.TimeData, today utils.TimeData, ranDays []time.Time, maxAttempts int) {
+	currentMonth := today.StartOfMonth()
+	currentYear := today.StartOfYear()
+
+	staleMonths := make(map[time.Time]bool)
+	for _, day := range ranDays {
+		staleMonths[utils.NewTimeData(day).StartOfMonth().Time] = true
+	}
+
+	if staleMonths[currentMonth.Time] {
+		if _, err := s.MonthlyAggregation(ctx, monthClosingDay(currentMonth.Time, today), deviceID); err != nil {
+			log.Printf("[ERROR] Monthly refresh for device %s: %v", deviceID, err)
+		}
+	}
+
+	months := periodsBetween(firstDay.StartOfMonth().Time, currentMonth.Time, func(t time.Time) time.Time {
+		return t.AddDate(0, 1, 0)
+	})
+	ranMonths := s.catchUpKind(ctx, entity.JobKindMonthly, deviceID, months, maxAttempts, staleMonths, nil)
+
+	staleYears := make(map[time.Time]bool)
+	for _, month := range ranMonths {
+		staleYears[utils.NewTimeData(month).StartOfYear().Time] = true
+	}
+
+	if staleYears[currentYear.Time] || staleMonths[currentMonth.Time] {
+		if _, err := s.YearlyAggregation(ctx, currentYear, deviceID); err != nil && !errors.Is(err, ErrNoMonthlyData) {
+			log.Printf("[ERROR] Yearly refresh for device %s: %v", deviceID, err)
+		}
+	}
+
+	years := periodsBetween(firstDay.StartOfYear().Time, currentYear.Time, func(t time.Time) time.Time {
+		return t.AddDate(1, 0, 0)
+	})
+	s.catchUpKind(ctx, entity.JobKindYearly, deviceID, years, maxAttempts, staleYears, nil)
+}
+
+// catchUpKind menjalankan periode jobKind yang belum selesai, yang ada di
+// force, atau yang sudah selesai tetapi menurut recheck (boleh nil) perlu
+// dijalankan ulang.
+func (s *CronService) catchUpKind(ctx context.Context, jobKind string, deviceID string, periods []time.Time, maxAttempts int, force map[time.Time]bool, recheck func(job entity.AggregationJob, period time.Time) bool) []time.Time {
+	if len(periods) == 0 {
+		return nil
+	}
+
+	start := utils.NewTimeData(periods[0])
+	end := utils.NewTimeData(periods[len(periods)-1].Add(time.Nanosecond))
+
+	jobs, err := s.jobRepo.GetJobs(ctx, jobKind, deviceID, start, end)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read %s ledger for device %s: %v", jobKind, deviceID, err)
+		return nil
+	}
+
+	ledger := make(map[time.Time]entity.AggregationJob, len(jobs))
+	for _, job := range jobs {
+		ledger[job.Period.Time.UTC()] = job
+	}
+
+	var ran []time.Time
+
+	for _, period := range periods {
+		job, exists := ledger[period]
+		attempts := job.Attempts
+
+		if exists && (job.Status == entity.JobStatusDone || job.Status == entity.JobStatusEmpty) {
+			// Periode yang dijalankan ulang setelah selesai dihitung sebagai
+			// run baru agar kegagalannya tetap mendapat jatah retry penuh.
+			attempts = 0
+		}
+
+		if exists && !force[period] {
+			if job.Status == entity.JobStatusDone || job.Status == entity.JobStatusEmpty {
+				if recheck == nil || !recheck(job, period) {
+					continue
+				}
+			}
+			if job.Status == entity.JobStatusFailed && job.Attempts >= maxAttempts {
+				continue
+			}
+		}
+
+		log.Printf("[CATCHUP] %s aggregation for device %s at %s (attempt %d)",
+			jobKind, deviceID, utils.NewTimeData(period).Format(), attempts+1)
+
+		if err := s.RunJob(ctx, jobKind, deviceID, period, attempts); err != nil {
+			log.Printf("[ERROR] %s aggregation for device %s at %s: %v",
+				jobKind, deviceID, utils.NewTimeData(period).Format(), err)
+			continue
+		}
+		ran = append(ran, period)
+	}
+
+	return ran
+}
+
+// dueForRecheck menentukan apakah jam yang sudah selesai (done atau empty)
+// perlu diagregasi ulang karena reading bisa datang terlambat (replay spill
+// file, buffer device, sesi MQTT persisten). Jam diperiksa ulang setelah
+// umurnya berlipat dua sejak run terakhir (minimal minRecheckInterval),
+// sehingga data terlambat tetap masuk selama jendela lookback tanpa
+// menjalankan ulang semua jam di setiap tick.
+func dueForRecheck(job entity.AggregationJob, periodEnd time.Time, now time.Time) bool {
+	lastAge := job.UpdatedAt.Time.Sub(periodEnd)
+	if lastAge < minRecheckInterval {
+		lastAge = minRecheckInterval
+	}
+
+	return now.Sub(job.UpdatedAt.Time) >= lastAge
+}
+
+// RunJob menjalankan satu agregasi dan mencatat hasilnya ke ledger.
+// previousAttempts adalah jumlah attempt yang sudah tercatat sebelumnya.
+func (s *CronService) RunJob(ctx context.Context, jobKind string, deviceID string, period time.Time, previousAttempts int) error {
+	job := &entity.AggregationJob{
+		JobKind:  jobKind,
+		DeviceID: deviceID,
+		Period:   utils.NewTimeData(period),
+		Status:   entity.JobStatusRunning,
+		Attempts: previousAttempts + 1,
+	}
+
+	if err := s.jobRepo.SaveJob(ctx, job); err != nil {
+		return fmt.Errorf("failed to record job start: %w", err)
+	}
+
+	var err error
+	switch jobKind {
+	case entity.JobKindHourly:
+		_, err = s.HourlyAggregation(ctx, period, deviceID)
+	case entity.JobKindDaily:
+		_, err = s.DailyAggregation(ctx, period, deviceID)
+	case entity.JobKindMonthly:
+		today := utils.TimeNowDailyIn(s.deviceCache.Get(ctx, deviceID).Location())
+		closingDay := monthClosingDay(period, today)
+		if closingDay.Time.Before(period) {
+			err = ErrNoDailyData
+		} else {
+			_, err = s.MonthlyAggregation(ctx, closingDay, deviceID)
+		}
+	case entity.JobKindYearly:
+		_, err = s.YearlyAggregation(ctx, utils.NewTimeData(period), deviceID)
+	default:
+		err = fmt.Errorf("unknown job kind: %s", jobKind)
+	}
+
+	switch {
+	case err == nil:
+		job.Status = entity.JobStatusDone
+		job.Error = ""
+	case errors.Is(err, ErrNoRealtimeData), errors.Is(err, ErrNoHourlyData),
+		errors.Is(err, ErrNoDailyData), errors.Is(err, ErrNoMonthlyData):
+		job.Status = entity.JobStatusEmpty
+		job.Error = err.Error()
+		err = nil
+	default:
+		job.Status = entity.JobStatusFailed
+		job.Error = err.Error()
+	}
+
+	if saveErr := s.jobRepo.SaveJob(ctx, job); saveErr != nil {
+		log.Printf("[ERROR] Failed to record %s job result for device %s: %v", jobKind, deviceID, saveErr)
+	}
+
+	return err
+}
+
+// monthClosingDay mengembalikan hari terakhir bulan period yang sudah
+// lengkap: hari terakhir bulan itu, atau kemarin jika bulan masih berjalan.
+// today adalah tanggal kalender lokal device.
+func monthClosingDay(period time.Time, today utils.TimeData) utils.TimeData {
+	lastDay := utils.NewTimeData(period).StartOfMonth().Time.AddDate(0, 1, -1)
+	yesterday := today.Time.AddDate(0, 0, -1)
+
+	if lastDay.After(yesterday) {
+		return utils.NewTimeData(yesterday)
+	}
+	return utils.NewTimeData(lastDay)
+}
+
+func periodsBetween(first time.Time, end time.Time, next func(time.Time) time.Time) []time.Time {
+	var periods []time.Time
+	for t := first.UTC(); t.Before(end); t = next(t) {
+		periods = append(periods, t)
+	}
+	return periods
+}
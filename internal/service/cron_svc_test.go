@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+func TestDueForRecheck(t *testing.T) {
+	periodEnd := time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		lastRun time.Duration // umur periode saat run terakhir
+		now     time.Duration // umur periode sekarang
+		want    bool
+	}{
+		{name: "just ran", lastRun: time.Minute, now: 5 * time.Minute, want: false},
+		{name: "minimum interval passed", lastRun: time.Minute, now: 16 * time.Minute, want: true},
+		{name: "age not yet doubled", lastRun: 2 * time.Hour, now: 3 * time.Hour, want: false},
+		{name: "age doubled", lastRun: 2 * time.Hour, now: 4 * time.Hour, want: true},
+		{name: "late first run", lastRun: 48 * time.Hour, now: 72 * time.Hour, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := entity.AggregationJob{
+				Status:    entity.JobStatusDone,
+				UpdatedAt: utils.NewTimeData(periodEnd.Add(tt.lastRun)),
+			}
+
+			if got := dueForRecheck(job, periodEnd, periodEnd.Add(tt.now)); got != tt.want {
+				t.Errorf("dueForRecheck = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
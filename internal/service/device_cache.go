@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+)
+
+const defaultDeviceCacheTTL = time.Minute
+
+type cachedDevice struct {
+	device    *entity.Device
+	expiresAt time.Time
+}
+
+// DeviceCache menyimpan pengaturan device di memori agar ingestion tidak
+// membaca Postgres untuk setiap pesan.
+type DeviceCache struct {
+	deviceRepo      repository.DeviceRepo
+	ttl             time.Duration
+	defaultTimezone string
+
+	mu      sync.RWMutex
+	devices map[string]cachedDevice
+}
+
+// NewDeviceCache membuat cache device. defaultTimezone dipakai untuk device
+// yang belum terdaftar atau belum punya zona waktu.
+func NewDeviceCache(deviceRepo repository.DeviceRepo, ttl time.Duration, defaultTimezone string) *DeviceCache {
+	if ttl <= 0 {
+		ttl = defaultDeviceCacheTTL
+	}
+
+	return &DeviceCache{
+		deviceRepo:      deviceRepo,
+		ttl:             ttl,
+		defaultTimezone: defaultTimezone,
+		devices:         make(map[string]cachedDevice),
+	}
+}
+
+// Get selalu mengembalikan device; device yang belum terdaftar atau gagal
+// dibaca memakai pengaturan default (mode energi delta).
+func (c *DeviceCache) Get(ctx context.Context, deviceID string) *entity.Device {
+	if c == nil {
+		return defaultDevice(deviceID, "")
+	}
+	if c.deviceRepo == nil {
+		return defaultDevice(deviceID, c.defaultTimezone)
+	}
+
+	now := time.Now()
+
+	c.mu.RLock()
+	cached, ok := c.devices[deviceID]
+	c.mu.RUnlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.device
+	}
+
+	device, err := c.deviceRepo.GetDevice(ctx, deviceID)
+	if err != nil {
+		log.Printf("Failed to load device %s, using cached/default settings: %v", deviceID, err)
+		if ok {
+			return cached.device
+		}
+		return defaultDevice(deviceID, c.defaultTimezone)
+	}
+
+	if device == nil {
+		device = defaultDevice(deviceID, c.defaultTimezone)
+	}
+	if device.Timezone == "" {
+		device.Timezone = c.defaultTimezone
+	}
+
+	c.mu.Lock()
+	c.devices[deviceID] = cachedDevice{device: device, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return device
+}
+
+func defaultDevice(deviceID string, timezone string) *entity.Device {
+	return &entity.Device{
+		DeviceID:   deviceID,
+		EnergyMode: entity.EnergyModeDelta,
+		Timezone:   timezone,
+	}
+}
+
+// Invalidate membuang cache device setelah pengaturannya diubah lewat API.
+func (c *DeviceCache) Invalidate(deviceID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.devices, deviceID)
+	c.mu.Unlock()
+}
@@ -6,18 +6,32 @@ import (
 	"log"
 	"metertronik/internal/domain/entity"
 	"metertronik/internal/domain/repository"
+	aggregate "metertronik/internal/service"
 	"metertronik/pkg/utils"
+	"time"
 )
 
 type ApiService struct {
-	postgresRepo   repository.PostgresRepo
-	redisBatchRepo repository.RedisBatchRepo
+	postgresRepo      repository.PostgresRepo
+	redisBatchRepo    repository.RedisBatchRepo
+	influxRepo        repository.InfluxRepo
+	redisRealtimeRepo repository.RedisRealtimeRepo
+	deviceCache       *aggregate.DeviceCache
+	historyWindow     time.Duration
+	historyRetention  time.Duration
 }
 
-func NewApiService(postgresRepo repository.PostgresRepo, redisBatchRepo repository.RedisBatchRepo) *ApiService {
+// NewApiService membuat service API. historyWindow adalah rentang default
+// endpoint history realtime, historyRetention batas maksimalnya.
+func NewApiService(postgresRepo repository.PostgresRepo, redisBatchRepo repository.RedisBatchRepo, influxRepo repository.InfluxRepo, redisRealtimeRepo repository.RedisRealtimeRepo, deviceCache *aggregate.DeviceCache, historyWindow time.Duration, historyRetention time.Duration) *ApiService {
 	return &ApiService{
-		postgresRepo:   postgresRepo,
-		redisBatchRepo: redisBatchRepo,
+		postgresRepo:      postgresRepo,
+		redisBatchRepo:    redisBatchRepo,
+		influxRepo:        influxRepo,
+		redisRealtimeRepo: redisRealtimeRepo,
+		deviceCache:       deviceCache,
+		historyWindow:     historyWindow,
+		historyRetention:  historyRetention,
 	}
 }
 
@@ -33,8 +47,21 @@ type MonthlyResponse struct {
 	Monthly *[]entity.MonthlyElectricity `json:"monthly"`
 }
 
+type YearlyResponse struct {
+	Year    *entity.YearlyElectricity    `json:"year"`
+	Monthly *[]entity.MonthlyElectricity `json:"monthly"`
+	Yearly  *[]entity.YearlyElectricity  `json:"yearly"`
+}
+
+// location mengembalikan zona waktu device; tanggal di API adalah tanggal
+// kalender lokal device tersebut.
+func (s *ApiService) location(ctx context.Context, deviceID string) *time.Location {
+	return s.deviceCache.Get(ctx, deviceID).Location()
+}
+
 func (s *ApiService) DailyActivity(ctx context.Context, deviceID string, dateStr string) (*DailyActivityResponse, error) {
-	date, err := utils.ParseDate(dateStr)
+	loc := s.location(ctx, deviceID)
+	date, err := utils.ParseDateIn(dateStr, loc)
 
 	var dailyElectricity *entity.DailyElectricity
 	var hourlyElectricityList *[]entity.HourlyElectricity
@@ -58,7 +85,7 @@ func (s *ApiService) DailyActivity(ctx context.Context, deviceID string, dateStr
 		}
 	}
 
-	dailyElectricity, hourlyElectricityList, err = s.postgresRepo.GetDailyElectricity(ctx, deviceID, date)
+	dailyElectricity, hourlyElectricityList, err = s.postgresRepo.GetDailyElectricity(ctx, deviceID, date, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +93,7 @@ func (s *ApiService) DailyActivity(ctx context.Context, deviceID string, dateStr
 	if s.redisBatchRepo != nil {
 		duration := utils.Days(0)
 
-		if date == utils.TimeNowDaily() {
+		if date == utils.TimeNowDailyIn(loc) {
 			duration = utils.Minutes(5)
 		} else {
 			duration = utils.Days(30)
@@ -103,10 +130,12 @@ func (s *ApiService) DailyList(ctx context.Context, deviceID string, time string
 		}
 	}
 
+	loc := s.location(ctx, deviceID)
+
 	var lastDate *utils.TimeData
 
 	if last != "" {
-		lastDateData, err := utils.ParseDate(last)
+		lastDateData, err := utils.ParseDateIn(last, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -125,7 +154,7 @@ func (s *ApiService) DailyList(ctx context.Context, deviceID string, time string
 	}
 
 	log.Println("Getting List from Postgres")
-	dailyElectricityList, err = s.postgresRepo.GetDailyElectricityList(ctx, deviceID, sortBy, lastDate)
+	dailyElectricityList, err = s.postgresRepo.GetDailyElectricityList(ctx, deviceID, sortBy, lastDate, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +171,9 @@ func (s *ApiService) DailyList(ctx context.Context, deviceID string, time string
 }
 
 func (s *ApiService) DailyRange(ctx context.Context, deviceID string, startStr string, endStr string, last string, limit int) (*[]entity.DailyElectricity, error) {
-	start, err := utils.ParseDate(startStr)
+	loc := s.location(ctx, deviceID)
+
+	start, err := utils.ParseDateIn(startStr, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +182,7 @@ func (s *ApiService) DailyRange(ctx context.Context, deviceID string, startStr s
 		return nil, errors.New("start date parameter is required")
 	}
 
-	end, err := utils.ParseDate(endStr)
+	end, err := utils.ParseDateIn(endStr, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +194,7 @@ func (s *ApiService) DailyRange(ctx context.Context, deviceID string, startStr s
 	var lastDate *utils.TimeData
 
 	if last != "" {
-		lastDateData, err := utils.ParseDate(last)
+		lastDateData, err := utils.ParseDateIn(last, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -179,7 +210,7 @@ func (s *ApiService) DailyRange(ctx context.Context, deviceID string, startStr s
 		}
 	}
 
-	dailyElectricityList, err = s.postgresRepo.GetDailyRange(ctx, deviceID, start, end, lastDate, limit)
+	dailyElectricityList, err = s.postgresRepo.GetDailyRange(ctx, deviceID, start, end, lastDate, limit, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -195,7 +226,7 @@ func (s *ApiService) DailyRange(ctx context.Context, deviceID string, startStr s
 }
 
 func (s *ApiService) MonthlyList(ctx context.Context, deviceID string, dateStr string) (*MonthlyResponse, error) {
-	date, err := utils.ParseDate(dateStr)
+	date, err := utils.ParseDateIn(dateStr, s.location(ctx, deviceID))
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +235,7 @@ func (s *ApiService) MonthlyList(ctx context.Context, deviceID string, dateStr s
 
 	if date.IsFirstDayOfMonth() {
 
-		dailyActivity, err := s.DailyActivity(ctx, deviceID, dateStr)
+		dailyActivity, err := s.DailyActivity(ctx, deviceID, date.FormatLayout("2006-01-02"))
 		if err != nil {
 			return nil, err
 		}
@@ -217,8 +248,8 @@ func (s *ApiService) MonthlyList(ctx context.Context, deviceID string, dateStr s
 	} else {
 		startDate, endDate := date.GetMonthlyRangeDates()
 
-		startStr := startDate.Format()
-		endStr := endDate.Format()
+		startStr := startDate.FormatLayout("2006-01-02")
+		endStr := endDate.FormatLayout("2006-01-02")
 
 		dailyList, err = s.DailyRange(ctx, deviceID, startStr, endStr, "", 32)
 		if err != nil {
@@ -276,45 +307,194 @@ func (s *ApiService) MonthlyList(ctx context.Context, deviceID string, dateStr s
 	}, nil
 }
 
+func (s *ApiService) YearlyList(ctx context.Context, deviceID string, dateStr string) (*YearlyResponse, error) {
+	var date utils.TimeData
+	var err error
+
+	if len(dateStr) == 4 {
+		date, err = utils.ParseLayout("2006", dateStr)
+	} else {
+		date, err = utils.ParseDateIn(dateStr, s.location(ctx, deviceID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	startOfYear := date.StartOfYear()
+	endOfYear := utils.NewTimeData(startOfYear.Time.AddDate(1, 0, 0))
+
+	monthlyList, err := s.postgresRepo.GetMonthlyRange(ctx, deviceID, startOfYear, endOfYear)
+	if err != nil {
+		return nil, err
+	}
+
+	yearlyList, err := s.postgresRepo.GetYearlyElectricity(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var yearly *entity.YearlyElectricity
+	otherYears := []entity.YearlyElectricity{}
+
+	for i := range *yearlyList {
+		yearData := &(*yearlyList)[i]
+
+		if yearData.Year.StartOfYear().Time.Equal(startOfYear.Time) {
+			yearly = yearData
+		} else {
+			otherYears = append(otherYears, *yearData)
+		}
+	}
+
+	return &YearlyResponse{
+		Year:    yearly,
+		Monthly: monthlyList,
+		Yearly:  &otherYears,
+	}, nil
+}
+
 func (s *ApiService) DayNowActivity(ctx context.Context, deviceID string) (*DailyActivityResponse, error) {
+	loc := s.location(ctx, deviceID)
+	today := utils.TimeNowDailyIn(loc)
+
 	endTime := utils.TimeNowHourly()
-	startTime := endTime.StartOfDay()
+	startTime := today.StartIn(loc)
 
 	hourlyDataList, err := s.postgresRepo.GetHourlyElectricityRange(ctx, deviceID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
 
-	count := len(*hourlyDataList)
-
-	var totalVoltage, totalCurrent, totalPower, energy float64
-	minPower := (*hourlyDataList)[0].MinPower
-	maxPower := (*hourlyDataList)[0].MaxPower
-
-	for _, d := range *hourlyDataList {
-		totalVoltage += d.AvgVoltage
-		totalCurrent += d.AvgCurrent
-		totalPower += d.AvgPower
-		energy += d.Energy
+	if hourlyDataList == nil {
+		hourlyDataList = &[]entity.HourlyElectricity{}
 	}
 
-	tarrifs, err := s.postgresRepo.GetTarrifs(ctx)
+	tarrifs, err := s.postgresRepo.GetTarrifs(ctx, s.deviceCache.Get(ctx, deviceID).TariffClass)
 	if err != nil {
 		return nil, err
 	}
 
+	now := utils.TimeNow()
+
+	current, err := s.currentHour(ctx, deviceID, endTime, now, tarrifs.PricePerKwh)
+	if err != nil {
+		log.Printf("Failed to summarize current hour for device %s: %v", deviceID, err)
+	} else if current != nil {
+		*hourlyDataList = append(*hourlyDataList, *current)
+	}
+
+	summary := aggregate.SummarizeHourly(*hourlyDataList, now.Time.Sub(startTime.Time))
+
 	daily := entity.DailyElectricity{
-		DeviceID:   deviceID,
-		Energy:     energy,
-		TotalCost:  (energy * tarrifs.PricePerKwh) * 1.10,
-		AvgVoltage: totalVoltage / float64(count),
-		AvgCurrent: totalCurrent / float64(count),
-		AvgPower:   totalPower / float64(count),
-		MinPower:   minPower,
-		MaxPower:   maxPower,
-		Day:        startTime,
-		CreatedAt:  utils.TimeNow(),
+		DeviceID:        deviceID,
+		Energy:          summary.Energy,
+		TotalCost:       (summary.Energy * tarrifs.PricePerKwh) * 1.10,
+		AvgVoltage:      summary.AvgVoltage,
+		AvgCurrent:      summary.AvgCurrent,
+		AvgPower:        summary.AvgPower,
+		MinPower:        summary.MinPower,
+		MaxPower:        summary.MaxPower,
+		CoveragePercent: summary.CoveragePercent,
+		SampleCount:     summary.SampleCount,
+		Day:             today,
+		Timezone:        loc.String(),
+		CreatedAt:       utils.TimeNow(),
 	}
 
 	return &DailyActivityResponse{
 		Daily:  &daily,
 		Hourly: hourlyDataList,
 	}, nil
-}
\ No newline at end of file
+}
+
+// currentHour meringkas jam yang sedang berjalan langsung dari InfluxDB,
+// karena agregasi per jam baru tersimpan setelah jam tersebut selesai.
+func (s *ApiService) currentHour(ctx context.Context, deviceID string, hourStart utils.TimeData, now utils.TimeData, pricePerKwh float64) (*entity.HourlyElectricity, error) {
+	if s.influxRepo == nil || !now.Time.After(hourStart.Time) {
+		return nil, nil
+	}
+
+	dataList, err := s.influxRepo.GetRealTimeElectricityRange(ctx, deviceID, hourStart, now)
+	if err != nil {
+		return nil, err
+	}
+	if dataList == nil {
+		return nil, nil
+	}
+
+	device := s.deviceCache.Get(ctx, deviceID)
+
+	var previous *entity.RealTimeElectricity
+	if device.IsCumulative() {
+		previous, err = s.influxRepo.GetLastRealTimeElectricityBefore(ctx, deviceID, hourStart, 24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	summary := aggregate.SummarizeRealtime(*dataList, previous, hourStart.Time, now.Time, device)
+
+	return &entity.HourlyElectricity{
+		DeviceID:        deviceID,
+		Energy:          summary.Energy,
+		TotalCost:       (summary.Energy * pricePerKwh) * 1.10,
+		AvgVoltage:      summary.AvgVoltage,
+		AvgCurrent:      summary.AvgCurrent,
+		AvgPower:        summary.AvgPower,
+		MinPower:        summary.MinPower,
+		MaxPower:        summary.MaxPower,
+		CoveragePercent: summary.CoveragePercent,
+		SampleCount:     summary.SampleCount,
+		TS:              hourStart,
+		CreatedAt:       now,
+	}, nil
+}
+
+var ErrInvalidWindow = errors.New("window must be a positive duration, e.g. 15m")
+
+// RealtimeHistory mengembalikan data realtime dalam window terakhir, sama
+// dengan backfill yang dikirim WebSocket saat connect. window kosong memakai
+// default, dan dibatasi retensi history.
+func (s *ApiService) RealtimeHistory(ctx context.Context, deviceID string, window string) ([]entity.RealTimeElectricity, time.Duration, error) {
+	if s.redisRealtimeRepo == nil {
+		return nil, 0, errors.New("realtime history is not available")
+	}
+
+	duration := s.historyWindow
+	if window != "" {
+		parsed, err := time.ParseDuration(window)
+		if err != nil || parsed <= 0 {
+			return nil, 0, ErrInvalidWindow
+		}
+		duration = parsed
+	}
+
+	if s.historyRetention > 0 && duration > s.historyRetention {
+		duration = s.historyRetention
+	}
+
+	since := utils.TimeNow().Time.Add(-duration)
+	history, err := s.redisRealtimeRepo.GetElectricityHistory(ctx, deviceID, since)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return history, duration, nil
+}
+
+// ClockStats mengembalikan statistik skew jam device untuk teknisi lapangan.
+func (s *ApiService) ClockStats(ctx context.Context, deviceID string) (*entity.ClockStats, error) {
+	if s.redisRealtimeRepo == nil {
+		return nil, errors.New("clock stats are not available")
+	}
+
+	stats, err := s.redisRealtimeRepo.GetClockStats(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		stats = &entity.ClockStats{DeviceID: deviceID}
+	}
+
+	return stats, nil
+}
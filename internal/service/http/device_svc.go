@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	aggregate "metertronik/internal/service"
+	"metertronik/pkg/utils"
+	"metertronik/pkg/validator"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrNotDeviceOwner = errors.New("only the device owner can do this")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrMemberNotFound = errors.New("user is not a member of this device")
+
+	ErrInvalidClaim         = errors.New("invalid device id or claim secret")
+	ErrDeviceAlreadyClaimed = errors.New("device is already claimed by another account")
+)
+
+// maxIngestBodyBytes adalah batas atas ukuran request ingest HTTP yang bisa
+// diatur per device.
+const maxIngestBodyBytes = 10 << 20
+
+// dummyClaimHash dipakai saat device tidak dikenal agar waktu respons klaim
+// tidak membocorkan device mana yang sudah diprovisioning.
+var dummyClaimHash, _ = bcrypt.GenerateFromPassword([]byte("metertronik-dummy-claim-secret"), bcrypt.DefaultCost)
+
+type DeviceService struct {
+	deviceRepo     repository.DeviceRepo
+	memberRepo     repository.DeviceMemberRepo
+	credentialRepo repository.DeviceCredentialRepo
+	usersRepo      repository.UsersRepoPostgres
+	ingestKeys     *aggregate.IngestKeyService
+	deviceCache    *aggregate.DeviceCache
+	policies       *ThresholdPolicyService
+}
+
+func NewDeviceService(deviceRepo repository.DeviceRepo, memberRepo repository.DeviceMemberRepo, credentialRepo repository.DeviceCredentialRepo, usersRepo repository.UsersRepoPostgres, ingestKeys *aggregate.IngestKeyService, deviceCache *aggregate.DeviceCache, policies *ThresholdPolicyService) *DeviceService {
+	return &DeviceService{
+		deviceRepo:     deviceRepo,
+		memberRepo:     memberRepo,
+		credentialRepo: credentialRepo,
+		usersRepo:      usersRepo,
+		ingestKeys:     ingestKeys,
+		deviceCache:    deviceCache,
+		policies:       policies,
+	}
+}
+
+// DeviceInput adalah field device yang bisa diubah pemilik. Field nil tidak
+// diubah. Device hanya dibuat lewat provisioning dan dimiliki lewat klaim.
+type DeviceInput struct {
+	DeviceName        *string  `json:"device_name"`
+	DeviceType        *string  `json:"device_type"`
+	DeviceStatus      *string  `json:"device_status"`
+	DeviceLocation    *string  `json:"device_location"`
+	EnergyMode        *string  `json:"energy_mode"`
+	EnergyRegisterMax *float64 `json:"energy_register_max"`
+	Timezone          *string  `json:"timezone"`
+	TariffClass       *string  `json:"tariff_class"`
+	// ThresholdPolicyID 0 melepas policy yang terpasang.
+	ThresholdPolicyID *int64 `json:"threshold_policy_id"`
+	// IngestMaxBodyBytes 0 kembali ke batas default ingest HTTP.
+	IngestMaxBodyBytes *int64 `json:"ingest_max_body_bytes"`
+}
+
+// ValidationError menandai input device yang tidak valid (HTTP 400).
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (s *DeviceService) List(ctx context.Context, userID int64) ([]entity.Device, error) {
+	devices, err := s.deviceRepo.ListDevicesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		devices = []entity.Device{}
+	}
+
+	return devices, nil
+}
+
+// Get membaca device; akses baca sudah diperiksa middleware DeviceAccess.
+func (s *DeviceService) Get(ctx context.Context, deviceID string) (*entity.Device, error) {
+	device, err := s.deviceRepo.GetDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	return device, nil
+}
+
+// getOwned membaca device yang hanya boleh diubah oleh pemiliknya.
+func (s *DeviceService) getOwned(ctx context.Context, userID int64, deviceID string) (*entity.Device, error) {
+	device, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device.OwnerID == nil || *device.OwnerID != userID {
+		return nil, ErrNotDeviceOwner
+	}
+
+	return device, nil
+}
+
+func (s *DeviceService) Update(ctx context.Context, userID int64, deviceID string, input DeviceInput) (*entity.Device, error) {
+	device, err := s.getOwned(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyDeviceInput(device, input); err != nil {
+		return nil, err
+	}
+	if err := s.applyThresholdPolicy(ctx, userID, device, input); err != nil {
+		return nil, err
+	}
+	device.DeviceUpdatedAt = utils.TimeNow()
+
+	if err := s.deviceRepo.UpdateDevice(ctx, device); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+
+	s.deviceCache.Invalidate(deviceID)
+
+	return device, nil
+}
+
+// Delete melepas device dari akun pemiliknya, sama seperti Unclaim. Baris
+// device fisik tetap ada agar meter masih bisa diklaim ulang; penghapusan
+// dari registry hanya lewat `cron provision -delete`.
+func (s *DeviceService) Delete(ctx context.Context, userID int64, deviceID string) error {
+	return s.Unclaim(ctx, userID, deviceID)
+}
+
+// Claim mengikat device yang sudah diprovisioning ke akun userID dengan
+// secret yang tercetak pada device. Klaim ulang oleh pemilik yang sama
+// tidak mengubah apa pun.
+func (s *DeviceService) Claim(ctx context.Context, userID int64, deviceID string, secret string) (*entity.Device, error) {
+	if err := validator.ValidateControllerID(deviceID); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+
+	credential, err := s.credentialRepo.GetCredential(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := dummyClaimHash
+	if credential != nil {
+		hash = []byte(credential.ClaimSecretHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(aggregate.NormalizeClaimSecret(secret))) != nil || credential == nil {
+		log.Printf("[CLAIM] User %d failed to claim device %s", userID, deviceID)
+		return nil, ErrInvalidClaim
+	}
+
+	device, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.OwnerID != nil {
+		if *device.OwnerID == userID {
+			return device, nil
+		}
+		return nil, ErrDeviceAlreadyClaimed
+	}
+
+	// Update bersyarat owner_id IS NULL mencegah dua klaim bersamaan sama-sama
+	// berhasil.
+	changed, err := s.deviceRepo.SetOwner(ctx, deviceID, nil, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrDeviceAlreadyClaimed
+	}
+
+	s.deviceCache.Invalidate(deviceID)
+	log.Printf("[CLAIM] User %d claimed device %s", userID, deviceID)
+
+	device.OwnerID = &userID
+	return device, nil
+}
+
+// Unclaim melepas kepemilikan sehingga device bisa diklaim lagi dengan
+// secret-nya. Semua anggota dan kunci ingest ikut dicabut.
+func (s *DeviceService) Unclaim(ctx context.Context, userID int64, deviceID string) error {
+	if _, err := s.getOwned(ctx, userID, deviceID); err != nil {
+		return err
+	}
+
+	if err := s.revokeIngestKeys(ctx, deviceID); err != nil {
+		return err
+	}
+
+	changed, err := s.deviceRepo.SetOwner(ctx, deviceID, &userID, nil)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrNotDeviceOwner
+	}
+
+	s.deviceCache.Invalidate(deviceID)
+	log.Printf("[CLAIM] User %d unclaimed device %s", userID, deviceID)
+
+	return nil
+}
+
+// Transfer memindahkan kepemilikan ke user dengan email atau username
+// tersebut. Anggota lama dan kunci ingest dicabut; pemilik baru mengatur
+// akses dan menerbitkan kuncinya sendiri.
+func (s *DeviceService) Transfer(ctx context.Context, userID int64, deviceID string, identifier string) (*entity.Device, error) {
+	if _, err := s.getOwned(ctx, userID, deviceID); err != nil {
+		return nil, err
+	}
+
+	newOwner, err := s.findUser(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if newOwner.ID == userID {
+		return nil, &ValidationError{Message: "device is already owned by this user"}
+	}
+
+	if err := s.revokeIngestKeys(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.deviceRepo.SetOwner(ctx, deviceID, &userID, &newOwner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrNotDeviceOwner
+	}
+
+	s.deviceCache.Invalidate(deviceID)
+	log.Printf("[CLAIM] User %d transferred device %s to user %d", userID, deviceID, newOwner.ID)
+
+	// Dibaca ulang karena threshold policy pemilik lama ikut dilepas.
+	return s.Get(ctx, deviceID)
+}
+
+// revokeIngestKeys dijalankan sebelum kepemilikan berpindah agar pemilik
+// lama tidak bisa terus mengirim data atas nama pemilik baru. Jika
+// perpindahan kemudian gagal, pemilik cukup menerbitkan kunci baru.
+func (s *DeviceService) revokeIngestKeys(ctx context.Context, deviceID string) error {
+	if err := s.ingestKeys.Revoke(ctx, deviceID); err != nil {
+		return err
+	}
+
+	log.Printf("[INGEST-KEY] Revoked ingest keys of device %s before ownership change", deviceID)
+	return nil
+}
+
+func (s *DeviceService) findUser(ctx context.Context, identifier string) (*entity.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return nil, &ValidationError{Message: "email or username is required"}
+	}
+
+	user, err := s.usersRepo.GetUser(ctx, strings.ToLower(identifier), identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// IngestKeys mengembalikan metadata kunci ingest yang masih berlaku tanpa
+// secret-nya.
+func (s *DeviceService) IngestKeys(ctx context.Context, userID int64, deviceID string) ([]entity.DeviceIngestKey, error) {
+	if _, err := s.getOwned(ctx, userID, deviceID); err != nil {
+		return nil, err
+	}
+
+	return s.ingestKeys.List(ctx, deviceID)
+}
+
+// RotateIngestKey menerbitkan kunci ingest baru untuk device milik userID.
+func (s *DeviceService) RotateIngestKey(ctx context.Context, userID int64, deviceID string) (*aggregate.IssuedIngestKey, error) {
+	if _, err := s.getOwned(ctx, userID, deviceID); err != nil {
+		return nil, err
+	}
+
+	issued, err := s.ingestKeys.Rotate(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INGEST-KEY] User %d rotated ingest key of device %s to key %d", userID, deviceID, issued.KeyID)
+
+	return issued, nil
+}
+
+func (s *DeviceService) ListMembers(ctx context.Context, deviceID string) ([]entity.DeviceMember, error) {
+	members, err := s.memberRepo.ListMembers(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []entity.DeviceMember{}
+	}
+
+	return members, nil
+}
+
+// AddMember memberi akses baca ke user dengan email atau username tersebut.
+func (s *DeviceService) AddMember(ctx context.Context, userID int64, deviceID string, identifier string) (*entity.DeviceMember, error) {
+	device, err := s.getOwned(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findUser(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if device.OwnerID != nil && user.ID == *device.OwnerID {
+		return nil, &ValidationError{Message: "the owner already has access to this device"}
+	}
+
+	member := &entity.DeviceMember{
+		DeviceID:  deviceID,
+		UserID:    user.ID,
+		Role:      entity.DeviceMemberRoleViewer,
+		CreatedAt: utils.TimeNow(),
+	}
+
+	if err := s.memberRepo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember mencabut akses anggota. Pemilik bisa mencabut siapa pun,
+// anggota hanya bisa mencabut dirinya sendiri.
+func (s *DeviceService) RemoveMember(ctx context.Context, userID int64, deviceID string, memberID int64) error {
+	if memberID != userID {
+		if _, err := s.getOwned(ctx, userID, deviceID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.memberRepo.RemoveMember(ctx, deviceID, memberID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// applyThresholdPolicy memasang policy milik userID atau policy global.
+func (s *DeviceService) applyThresholdPolicy(ctx context.Context, userID int64, device *entity.Device, input DeviceInput) error {
+	if input.ThresholdPolicyID == nil {
+		return nil
+	}
+	if *input.ThresholdPolicyID == 0 {
+		device.ThresholdPolicyID = nil
+		return nil
+	}
+
+	policy, err := s.policies.Usable(ctx, userID, *input.ThresholdPolicyID)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNotFound) {
+			return &ValidationError{Message: fmt.Sprintf("threshold policy %d not found", *input.ThresholdPolicyID)}
+		}
+		return err
+	}
+
+	device.ThresholdPolicyID = &policy.ID
+	return nil
+}
+
+func applyDeviceInput(device *entity.Device, input DeviceInput) error {
+	if input.DeviceName != nil {
+		device.DeviceName = strings.TrimSpace(*input.DeviceName)
+	}
+	if input.DeviceType != nil {
+		device.DeviceType = strings.TrimSpace(*input.DeviceType)
+	}
+	if input.DeviceLocation != nil {
+		device.DeviceLocation = strings.TrimSpace(*input.DeviceLocation)
+	}
+	if input.TariffClass != nil {
+		device.TariffClass = strings.TrimSpace(*input.TariffClass)
+	}
+
+	if input.DeviceStatus != nil {
+		switch *input.DeviceStatus {
+		case entity.DeviceStatusActive, entity.DeviceStatusDisabled:
+			device.DeviceStatus = *input.DeviceStatus
+		default:
+			return &ValidationError{Message: fmt.Sprintf("device_status must be %q or %q", entity.DeviceStatusActive, entity.DeviceStatusDisabled)}
+		}
+	}
+
+	if input.EnergyMode != nil {
+		switch *input.EnergyMode {
+		case entity.EnergyModeDelta, entity.EnergyModeCumulative:
+			device.EnergyMode = *input.EnergyMode
+		default:
+			return &ValidationError{Message: fmt.Sprintf("energy_mode must be %q or %q", entity.EnergyModeDelta, entity.EnergyModeCumulative)}
+		}
+	}
+
+	if input.EnergyRegisterMax != nil {
+		if *input.EnergyRegisterMax < 0 {
+			return &ValidationError{Message: "energy_register_max must not be negative"}
+		}
+		device.EnergyRegisterMax = *input.EnergyRegisterMax
+	}
+
+	if input.IngestMaxBodyBytes != nil {
+		if *input.IngestMaxBodyBytes < 0 || *input.IngestMaxBodyBytes > maxIngestBodyBytes {
+			return &ValidationError{Message: fmt.Sprintf("ingest_max_body_bytes must be between 0 and %d", maxIngestBodyBytes)}
+		}
+		device.IngestMaxBodyBytes = *input.IngestMaxBodyBytes
+	}
+
+	if input.Timezone != nil {
+		tz := strings.TrimSpace(*input.Timezone)
+		if tz != "" {
+			if _, err := utils.ParseLocation(tz); err != nil {
+				return &ValidationError{Message: fmt.Sprintf("unknown timezone %q", tz)}
+			}
+		}
+		device.Timezone = tz
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	aggregate "metertronik/internal/service"
+	"metertronik/pkg/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultEventLimit = 50
+	maxEventLimit     = 200
+)
+
+type EventService struct {
+	surgeRepo   repository.SurgeEventRepo
+	deviceCache *aggregate.DeviceCache
+}
+
+func NewEventService(surgeRepo repository.SurgeEventRepo, deviceCache *aggregate.DeviceCache) *EventService {
+	return &EventService{
+		surgeRepo:   surgeRepo,
+		deviceCache: deviceCache,
+	}
+}
+
+// EventQuery adalah filter query string endpoint event. start/end berupa
+// RFC3339 atau tanggal "2006-01-02" di zona waktu device (end inklusif).
+type EventQuery struct {
+	Start        string
+	End          string
+	MinMagnitude string
+	Status       string
+	Last         string
+	Limit        string
+}
+
+// SurgeEvents mengembalikan surge event device, terbaru lebih dulu.
+func (s *EventService) SurgeEvents(ctx context.Context, deviceID string, query EventQuery) ([]entity.SurgeEvent, error) {
+	loc := s.deviceCache.Get(ctx, deviceID).Location()
+
+	filter := entity.SurgeEventFilter{
+		DeviceID: deviceID,
+		Limit:    defaultEventLimit,
+	}
+
+	if query.Start != "" {
+		from, err := parseEventTime(query.Start, loc, false)
+		if err != nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("invalid start %q", query.Start)}
+		}
+		filter.From = &from
+	}
+	if query.End != "" {
+		to, err := parseEventTime(query.End, loc, true)
+		if err != nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("invalid end %q", query.End)}
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Time.Before(filter.From.Time) {
+		return nil, &ValidationError{Message: "end must not be before start"}
+	}
+
+	if query.MinMagnitude != "" {
+		magnitude, err := strconv.ParseFloat(query.MinMagnitude, 64)
+		if err != nil || magnitude < 0 {
+			return nil, &ValidationError{Message: "min_magnitude must be a non-negative number"}
+		}
+		filter.MinMagnitude = magnitude
+	}
+
+	switch query.Status {
+	case "", entity.SurgeEventStatusOpen, entity.SurgeEventStatusClosed:
+		filter.Status = query.Status
+	default:
+		return nil, &ValidationError{Message: fmt.Sprintf("status must be %q or %q", entity.SurgeEventStatusOpen, entity.SurgeEventStatusClosed)}
+	}
+
+	if query.Last != "" {
+		lastID, err := strconv.ParseInt(query.Last, 10, 64)
+		if err != nil || lastID <= 0 {
+			return nil, &ValidationError{Message: "last must be an event id"}
+		}
+		filter.LastID = lastID
+	}
+
+	if query.Limit != "" {
+		limit, err := strconv.Atoi(query.Limit)
+		if err != nil || limit <= 0 {
+			return nil, &ValidationError{Message: "limit must be a positive number"}
+		}
+		filter.Limit = min(limit, maxEventLimit)
+	}
+
+	events, err := s.surgeRepo.ListSurgeEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []entity.SurgeEvent{}
+	}
+
+	return events, nil
+}
+
+func parseEventTime(value string, loc *time.Location, endOfDay bool) (utils.TimeData, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return utils.NewTimeData(t), nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return utils.TimeData{}, err
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return utils.NewTimeData(day), nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEventTime(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name     string
+		value    string
+		loc      *time.Location
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:  "RFC3339 ignores location",
+			value: "2026-03-01T10:00:00+02:00",
+			loc:   jakarta,
+			want:  time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339 ignores end of day",
+			value:    "2026-03-01T10:00:00Z",
+			loc:      time.UTC,
+			endOfDay: true,
+			want:     time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "date starts at local midnight",
+			value: "2026-03-01",
+			loc:   jakarta,
+			want:  time.Date(2026, 2, 28, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date ends before next local midnight",
+			value:    "2026-03-01",
+			loc:      jakarta,
+			endOfDay: true,
+			want:     time.Date(2026, 3, 1, 16, 59, 59, 999999999, time.UTC),
+		},
+		{
+			name:     "date in UTC",
+			value:    "2026-12-31",
+			loc:      time.UTC,
+			endOfDay: true,
+			want:     time.Date(2026, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		},
+		{name: "invalid value", value: "01/03/2026", loc: time.UTC, wantErr: true},
+		{name: "empty value", value: "", loc: time.UTC, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEventTime(tt.value, tt.loc, tt.endOfDay)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseEventTime(%q) = %s, want error", tt.value, got.Time)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEventTime(%q) error = %v", tt.value, err)
+			}
+			if !got.Time.Equal(tt.want) {
+				t.Errorf("parseEventTime(%q) = %s, want %s", tt.value, got.Time, tt.want)
+			}
+		})
+	}
+}
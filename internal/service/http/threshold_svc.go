@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPolicyNotFound = errors.New("threshold policy not found")
+	ErrPolicyReadOnly = errors.New("global threshold policies cannot be modified")
+	ErrPolicyExists   = errors.New("a threshold policy for this device type already exists")
+)
+
+// ThresholdPolicyService mengelola threshold policy milik user. Perubahan
+// dibaca ulang oleh ingestor secara berkala (INGEST_POLICY_RELOAD_SECONDS).
+type ThresholdPolicyService struct {
+	policyRepo repository.ThresholdPolicyRepo
+}
+
+func NewThresholdPolicyService(policyRepo repository.ThresholdPolicyRepo) *ThresholdPolicyService {
+	return &ThresholdPolicyService{
+		policyRepo: policyRepo,
+	}
+}
+
+// ThresholdPolicyInput adalah field policy yang bisa diisi; field nil tidak
+// diubah saat update dan memakai nilai default saat create.
+type ThresholdPolicyInput struct {
+	Name               *string  `json:"name"`
+	DeviceType         *string  `json:"device_type"`
+	VoltagePercent     *float64 `json:"voltage_percent"`
+	CurrentPercent     *float64 `json:"current_percent"`
+	PowerPercent       *float64 `json:"power_percent"`
+	EnergyPercent      *float64 `json:"energy_percent"`
+	PowerFactorPercent *float64 `json:"power_factor_percent"`
+	FrequencyPercent   *float64 `json:"frequency_percent"`
+	SurgeWatts         *float64 `json:"surge_watts"`
+	SurgePercent       *float64 `json:"surge_percent"`
+	SurgeBaselineWatts *float64 `json:"surge_baseline_watts"`
+}
+
+func (s *ThresholdPolicyService) List(ctx context.Context, userID int64) ([]entity.ThresholdPolicy, error) {
+	policies, err := s.policyRepo.ListThresholdPoliciesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if policies == nil {
+		policies = []entity.ThresholdPolicy{}
+	}
+
+	return policies, nil
+}
+
+// Usable mengembalikan policy yang boleh dipasang userID ke device-nya:
+// policy miliknya sendiri atau policy global.
+func (s *ThresholdPolicyService) Usable(ctx context.Context, userID int64, id int64) (*entity.ThresholdPolicy, error) {
+	policy, err := s.policyRepo.GetThresholdPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil || (policy.OwnerID != nil && *policy.OwnerID != userID) {
+		return nil, ErrPolicyNotFound
+	}
+
+	return policy, nil
+}
+
+func (s *ThresholdPolicyService) getOwned(ctx context.Context, userID int64, id int64) (*entity.ThresholdPolicy, error) {
+	policy, err := s.Usable(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy.OwnerID == nil {
+		return nil, ErrPolicyReadOnly
+	}
+
+	return policy, nil
+}
+
+func (s *ThresholdPolicyService) Create(ctx context.Context, userID int64, input ThresholdPolicyInput) (*entity.ThresholdPolicy, error) {
+	now := utils.TimeNow()
+
+	policy := entity.DefaultThresholdPolicy()
+	policy.Name = ""
+	policy.OwnerID = &userID
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	if err := applyThresholdPolicyInput(policy, input); err != nil {
+		return nil, err
+	}
+	if policy.Name == "" {
+		return nil, &ValidationError{Message: "name is required"}
+	}
+	if err := s.checkDeviceType(ctx, userID, policy); err != nil {
+		return nil, err
+	}
+
+	if err := s.policyRepo.CreateThresholdPolicy(ctx, policy); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrPolicyExists
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (s *ThresholdPolicyService) Update(ctx context.Context, userID int64, id int64, input ThresholdPolicyInput) (*entity.ThresholdPolicy, error) {
+	policy, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyThresholdPolicyInput(policy, input); err != nil {
+		return nil, err
+	}
+	if policy.Name == "" {
+		return nil, &ValidationError{Message: "name must not be empty"}
+	}
+	if err := s.checkDeviceType(ctx, userID, policy); err != nil {
+		return nil, err
+	}
+	policy.UpdatedAt = utils.TimeNow()
+
+	if err := s.policyRepo.UpdateThresholdPolicy(ctx, policy); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPolicyNotFound
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrPolicyExists
+		}
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Delete menghapus policy; device yang memakainya kembali ke policy tipe
+// device atau default.
+func (s *ThresholdPolicyService) Delete(ctx context.Context, userID int64, id int64) error {
+	if _, err := s.getOwned(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := s.policyRepo.DeleteThresholdPolicy(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPolicyNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkDeviceType memastikan user hanya punya satu policy per tipe device.
+func (s *ThresholdPolicyService) checkDeviceType(ctx context.Context, userID int64, policy *entity.ThresholdPolicy) error {
+	if policy.DeviceType == "" {
+		return nil
+	}
+
+	policies, err := s.policyRepo.ListThresholdPoliciesForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range policies {
+		if existing.OwnerID != nil && existing.ID != policy.ID && existing.DeviceType == policy.DeviceType {
+			return ErrPolicyExists
+		}
+	}
+
+	return nil
+}
+
+func applyThresholdPolicyInput(policy *entity.ThresholdPolicy, input ThresholdPolicyInput) error {
+	if input.Name != nil {
+		policy.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.DeviceType != nil {
+		policy.DeviceType = strings.TrimSpace(*input.DeviceType)
+	}
+
+	percents := []struct {
+		name   string
+		input  *float64
+		target *float64
+	}{
+		{"voltage_percent", input.VoltagePercent, &policy.VoltagePercent},
+		{"current_percent", input.CurrentPercent, &policy.CurrentPercent},
+		{"power_percent", input.PowerPercent, &policy.PowerPercent},
+		{"energy_percent", input.EnergyPercent, &policy.EnergyPercent},
+		{"power_factor_percent", input.PowerFactorPercent, &policy.PowerFactorPercent},
+		{"frequency_percent", input.FrequencyPercent, &policy.FrequencyPercent},
+		{"surge_percent", input.SurgePercent, &policy.SurgePercent},
+	}
+	for _, p := range percents {
+		if p.input == nil {
+			continue
+		}
+		if *p.input <= 0 || *p.input > 1000 {
+			return &ValidationError{Message: fmt.Sprintf("%s must be greater than 0 and at most 1000", p.name)}
+		}
+		*p.target = *p.input
+	}
+
+	// surge_watts 0 membuat setiap reading dianggap surge; baseline 0 boleh
+	// (persentase surge dihitung dari daya berapa pun).
+	if input.SurgeWatts != nil {
+		if *input.SurgeWatts <= 0 {
+			return &ValidationError{Message: "surge_watts must be greater than 0"}
+		}
+		policy.SurgeWatts = *input.SurgeWatts
+	}
+	if input.SurgeBaselineWatts != nil {
+		if *input.SurgeBaselineWatts < 0 {
+			return &ValidationError{Message: "surge_baseline_watts must not be negative"}
+		}
+		policy.SurgeBaselineWatts = *input.SurgeBaselineWatts
+	}
+
+	return nil
+}
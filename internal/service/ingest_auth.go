@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+const (
+	ingestSecretBytes = 32
+	ingestAPIKeyBytes = 32
+
+	defaultIngestKeyCacheTTL = time.Minute
+	// ingestKeyMinRefresh membatasi reload kunci saat pesan membawa key_id
+	// yang belum ada di cache (mis. tepat setelah rotasi).
+	ingestKeyMinRefresh = 5 * time.Second
+)
+
+// Alasan penolakan pesan ingest, juga dipakai sebagai key metrik
+// ingest_rejected_total.
+const (
+	RejectMalformed      = "malformed"
+	RejectUnsigned       = "unsigned"
+	RejectNoCredentials  = "no_credentials"
+	RejectUnknownKey     = "unknown_key"
+	RejectBadSignature   = "bad_signature"
+	RejectDeviceMismatch = "device_mismatch"
+	RejectBadAPIKey      = "bad_api_key"
+)
+
+var (
+	ingestVerifiedTotal = expvar.NewInt("ingest_verified_total")
+	ingestUnsignedTotal = expvar.NewInt("ingest_unsigned_total")
+	ingestRejectedTotal = expvar.NewMap("ingest_rejected_total")
+)
+
+var ErrIngestKeysDisabled = errors.New("ingest key encryption is not configured")
+
+// RejectError menandai pesan yang tidak akan pernah lolos verifikasi;
+// consumer mengirimnya langsung ke dead-letter queue tanpa retry.
+type RejectError struct {
+	Reason string
+	Err    error
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("ingest message rejected (%s): %v", e.Reason, e.Err)
+}
+
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+func reject(reason string, format string, args ...interface{}) error {
+	ingestRejectedTotal.Add(reason, 1)
+	return &RejectError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// IngestEnvelope adalah pesan ingest bertanda tangan:
+//
+//	{"device_id":"...","key_id":1,"payload":"<base64 JSON reading>","signature":"<hex>"}
+//
+// signature = hex(HMAC-SHA256(secret, device_id + "." + key_id + "." + payload)),
+// dengan payload berupa string base64 apa adanya.
+type IngestEnvelope struct {
+	DeviceID  string `json:"device_id"`
+	KeyID     int    `json:"key_id"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+func (e *IngestEnvelope) signedContent() []byte {
+	return []byte(e.DeviceID + "." + strconv.Itoa(e.KeyID) + "." + e.Payload)
+}
+
+// IssuedIngestKey dikembalikan sekali saat rotasi; secret dan API key tidak
+// bisa dibaca lagi lewat API setelahnya. Secret menandatangani pesan AMQP/MQTT,
+// API key dipakai header X-API-Key pada ingest HTTP.
+type IssuedIngestKey struct {
+	DeviceID        string          `json:"device_id"`
+	KeyID           int             `json:"key_id"`
+	Secret          string          `json:"secret"`
+	APIKey          string          `json:"api_key"`
+	PreviousExpires *utils.TimeData `json:"previous_keys_expire_at,omitempty"`
+}
+
+// IngestKeyService menerbitkan dan membaca kunci HMAC ingest per device.
+type IngestKeyService struct {
+	repo        repository.DeviceIngestKeyRepo
+	revocations repository.IngestKeyRevocationRepo
+	box         *utils.SecretBox
+	grace       time.Duration
+}
+
+// NewIngestKeyService membuat service kunci ingest. box nil berarti
+// enkripsi belum dikonfigurasi sehingga kunci tidak bisa diterbitkan.
+// revocations nil berarti pencabutan tidak disiarkan ke ingestor.
+func NewIngestKeyService(repo repository.DeviceIngestKeyRepo, revocations repository.IngestKeyRevocationRepo, box *utils.SecretBox, grace time.Duration) *IngestKeyService {
+	return &IngestKeyService{
+		repo:        repo,
+		revocations: revocations,
+		box:         box,
+		grace:       grace,
+	}
+}
+
+// Rotate menerbitkan kunci baru; kunci aktif sebelumnya masih diterima
+// selama masa tenggang agar firmware sempat diperbarui.
+func (s *IngestKeyService) Rotate(ctx context.Context, deviceID string) (*IssuedIngestKey, error) {
+	if s.box == nil {
+		return nil, ErrIngestKeysDisabled
+	}
+
+	secret := make([]byte, ingestSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate ingest secret: %w", err)
+	}
+
+	ciphertext, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt ingest secret: %w", err)
+	}
+
+	apiKey := make([]byte, ingestAPIKeyBytes)
+	if _, err := rand.Read(apiKey); err != nil {
+		return nil, fmt.Errorf("failed to generate ingest API key: %w", err)
+	}
+	encodedAPIKey := base64.RawURLEncoding.EncodeToString(apiKey)
+
+	now := utils.TimeNow()
+	graceUntil := now.Add(s.grace)
+
+	key := &entity.DeviceIngestKey{
+		DeviceID:         deviceID,
+		SecretCiphertext: ciphertext,
+		APIKeyHash:       hashAPIKey(encodedAPIKey),
+		CreatedAt:        now,
+	}
+	if err := s.repo.RotateIngestKey(ctx, key, graceUntil.Time); err != nil {
+		return nil, err
+	}
+
+	issued := &IssuedIngestKey{
+		DeviceID: deviceID,
+		KeyID:    key.KeyID,
+		Secret:   base64.StdEncoding.EncodeToString(secret),
+		APIKey:   encodedAPIKey,
+	}
+	if key.KeyID > 1 {
+		issued.PreviousExpires = &graceUntil
+	}
+
+	return issued, nil
+}
+
+// Revoke membuat semua kunci device langsung kedaluwarsa tanpa masa tenggang,
+// lalu memberi tahu ingestor agar membuang cache kuncinya. Dipakai saat
+// kepemilikan device berpindah, sehingga pemilik baru harus menerbitkan
+// kunci sendiri.
+func (s *IngestKeyService) Revoke(ctx context.Context, deviceID string) error {
+	if err := s.repo.ExpireIngestKeys(ctx, deviceID, utils.TimeNow().Time); err != nil {
+		return err
+	}
+
+	if s.revocations != nil {
+		if err := s.revocations.PublishRevocation(ctx, deviceID); err != nil {
+			log.Printf("Failed to announce ingest key revocation for device %s, ingestors drop it when their cache expires: %v", deviceID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *IngestKeyService) List(ctx context.Context, deviceID string) ([]entity.DeviceIngestKey, error) {
+	keys, err := s.repo.ListActiveIngestKeys(ctx, deviceID, utils.TimeNow().Time)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []entity.DeviceIngestKey{}
+	}
+
+	return keys, nil
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// activeKeys adalah secret HMAC per key_id dan hash API key yang masih
+// berlaku untuk satu device.
+type activeKeys struct {
+	secrets      map[int][]byte
+	apiKeyHashes [][]byte
+}
+
+func (k *activeKeys) empty() bool {
+	return len(k.secrets) == 0 && len(k.apiKeyHashes) == 0
+}
+
+// matchAPIKey membandingkan hash API key dengan waktu konstan.
+func (k *activeKeys) matchAPIKey(apiKey string) bool {
+	sum := sha256.Sum256([]byte(apiKey))
+	matched := false
+	for _, hash := range k.apiKeyHashes {
+		if subtle.ConstantTimeCompare(hash, sum[:]) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// activeKeys mengembalikan kunci device yang masih berlaku.
+func (s *IngestKeyService) activeKeys(ctx context.Context, deviceID string) (*activeKeys, error) {
+	if s.box == nil {
+		return nil, ErrIngestKeysDisabled
+	}
+
+	keys, err := s.repo.ListActiveIngestKeys(ctx, deviceID, utils.TimeNow().Time)
+	if err != nil {
+		return nil, err
+	}
+
+	active := &activeKeys{secrets: make(map[int][]byte, len(keys))}
+	for _, key := range keys {
+		if key.APIKeyHash != "" {
+			if hash, err := hex.DecodeString(key.APIKeyHash); err == nil {
+				active.apiKeyHashes = append(active.apiKeyHashes, hash)
+			}
+		}
+
+		secret, err := s.box.Open(key.SecretCiphertext)
+		if err != nil {
+			log.Printf("Failed to decrypt ingest key %d of device %s: %v", key.KeyID, deviceID, err)
+			continue
+		}
+		active.secrets[key.KeyID] = secret
+	}
+
+	return active, nil
+}
+
+type cachedKeys struct {
+	keys      *activeKeys
+	loadedAt  time.Time
+	expiresAt time.Time
+}
+
+// IngestVerifier memverifikasi pesan ingest sebelum diproses. Dengan
+// requireSignature=false pesan tanpa tanda tangan tetap diterima (untuk masa
+// migrasi firmware), tetapi pesan bertanda tangan tetap diverifikasi.
+type IngestVerifier struct {
+	keys             *IngestKeyService
+	requireSignature bool
+	ttl              time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedKeys
+}
+
+func NewIngestVerifier(keys *IngestKeyService, requireSignature bool) *IngestVerifier {
+	return &IngestVerifier{
+		keys:             keys,
+		requireSignature: requireSignature,
+		ttl:              defaultIngestKeyCacheTTL,
+		cache:            make(map[string]cachedKeys),
+	}
+}
+
+// VerifiedMessage adalah payload reading yang lolos verifikasi. DeviceID
+// adalah device yang menandatangani pesan, kosong untuk pesan tanpa tanda
+// tangan.
+type VerifiedMessage struct {
+	DeviceID string
+	Payload  []byte
+}
+
+// Verify memeriksa tanda tangan body pesan dan mengembalikan payload reading.
+// Error bertipe *RejectError bersifat permanen; error lain (mis. database)
+// boleh dicoba ulang.
+func (v *IngestVerifier) Verify(ctx context.Context, body []byte) (*VerifiedMessage, error) {
+	var envelope IngestEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, reject(RejectMalformed, "invalid JSON: %v", err)
+	}
+
+	if envelope.Signature == "" && envelope.Payload == "" {
+		return v.verifyUnsigned(body)
+	}
+
+	if envelope.DeviceID == "" || envelope.Payload == "" || envelope.Signature == "" {
+		return nil, reject(RejectMalformed, "envelope requires device_id, payload and signature")
+	}
+
+	secret, err := v.secret(ctx, envelope.DeviceID, envelope.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := hex.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, reject(RejectMalformed, "signature is not hex")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(envelope.signedContent())
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, reject(RejectBadSignature, "signature mismatch for device %s key %d", envelope.DeviceID, envelope.KeyID)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, reject(RejectMalformed, "payload is not base64")
+	}
+
+	ingestVerifiedTotal.Add(1)
+	return &VerifiedMessage{DeviceID: envelope.DeviceID, Payload: payload}, nil
+}
+
+func (v *IngestVerifier) verifyUnsigned(body []byte) (*VerifiedMessage, error) {
+	if v.requireSignature {
+		return nil, reject(RejectUnsigned, "unsigned message")
+	}
+
+	ingestUnsignedTotal.Add(1)
+	return &VerifiedMessage{Payload: body}, nil
+}
+
+// AuthenticateAPIKey dipakai ingest HTTP: API key harus cocok dengan salah
+// satu kunci device yang masih berlaku, termasuk kunci lama dalam masa
+// tenggang. Secret HMAC tidak pernah diterima sebagai API key.
+func (v *IngestVerifier) AuthenticateAPIKey(ctx context.Context, deviceID string, apiKey string) error {
+	matches := func(keys *activeKeys) bool {
+		return keys.matchAPIKey(apiKey)
+	}
+
+	keys, err := v.lookup(ctx, deviceID, matches)
+	if err != nil {
+		return err
+	}
+	if len(keys.apiKeyHashes) == 0 {
+		return reject(RejectNoCredentials, "device %s has no active ingest API key", deviceID)
+	}
+	if !matches(keys) {
+		return reject(RejectBadAPIKey, "invalid API key for device %s", deviceID)
+	}
+
+	return nil
+}
+
+func (v *IngestVerifier) secret(ctx context.Context, deviceID string, keyID int) ([]byte, error) {
+	keys, err := v.lookup(ctx, deviceID, func(keys *activeKeys) bool {
+		_, found := keys.secrets[keyID]
+		return found
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if secret, found := keys.secrets[keyID]; found {
+		return secret, nil
+	}
+
+	return nil, v.missingKey(deviceID, keyID, keys.secrets)
+}
+
+// lookup membaca secret device dari cache. Cache dimuat ulang jika sudah
+// kedaluwarsa, atau jika found bernilai false dan cache lebih tua dari
+// ingestKeyMinRefresh (kunci mungkin baru dirotasi). Hanya device yang punya
+// kunci aktif yang di-cache, sehingga device ID acak dari pesan tanpa
+// autentikasi tidak menambah isi cache.
+func (v *IngestVerifier) lookup(ctx context.Context, deviceID string, found func(*activeKeys) bool) (*activeKeys, error) {
+	now := time.Now()
+
+	v.mu.Lock()
+	cached, ok := v.cache[deviceID]
+	v.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		if found(cached.keys) || now.Sub(cached.loadedAt) < ingestKeyMinRefresh {
+			return cached.keys, nil
+		}
+	}
+
+	keys, err := v.keys.activeKeys(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	if !keys.empty() {
+		v.cache[deviceID] = cachedKeys{keys: keys, loadedAt: now, expiresAt: now.Add(v.ttl)}
+	} else {
+		delete(v.cache, deviceID)
+	}
+	v.mu.Unlock()
+
+	return keys, nil
+}
+
+// Invalidate membuang kunci device dari cache sehingga pesan berikutnya
+// membaca ulang kunci dari database.
+func (v *IngestVerifier) Invalidate(deviceID string) {
+	v.mu.Lock()
+	delete(v.cache, deviceID)
+	v.mu.Unlock()
+}
+
+// WatchRevocations membuang cache device yang kuncinya dicabut lewat API
+// sampai ctx selesai. Pencabutan yang terlewat (mis. Redis sempat putus)
+// tetap berlaku setelah TTL cache habis.
+func (v *IngestVerifier) WatchRevocations(ctx context.Context, revocations repository.IngestKeyRevocationRepo) {
+	if revocations == nil {
+		return
+	}
+
+	go func() {
+		for deviceID := range revocations.Revocations(ctx) {
+			v.Invalidate(deviceID)
+			log.Printf("Ingest keys of device %s revoked, cache dropped", deviceID)
+		}
+	}()
+}
+
+func (v *IngestVerifier) missingKey(deviceID string, keyID int, secrets map[int][]byte) error {
+	if len(secrets) == 0 {
+		return reject(RejectNoCredentials, "device %s has no active ingest key", deviceID)
+	}
+	return reject(RejectUnknownKey, "device %s has no active key %d", deviceID, keyID)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+type fakeIngestKeyRepo struct {
+	keys []entity.DeviceIngestKey
+}
+
+func (r *fakeIngestKeyRepo) ListActiveIngestKeys(ctx context.Context, deviceID string, at time.Time) ([]entity.DeviceIngestKey, error) {
+	var active []entity.DeviceIngestKey
+	for _, key := range r.keys {
+		if key.DeviceID != deviceID {
+			continue
+		}
+		if key.ExpiresAt != nil && !key.ExpiresAt.Time.After(at) {
+			continue
+		}
+		active = append(active, key)
+	}
+	return active, nil
+}
+
+func (r *fakeIngestKeyRepo) RotateIngestKey(ctx context.Context, key *entity.DeviceIngestKey, graceUntil time.Time) error {
+	key.KeyID = 1
+	for i := range r.keys {
+		if r.keys[i].DeviceID != key.DeviceID {
+			continue
+		}
+		if r.keys[i].KeyID >= key.KeyID {
+			key.KeyID = r.keys[i].KeyID + 1
+		}
+		if r.keys[i].ExpiresAt == nil {
+			expires := utils.NewTimeData(graceUntil)
+			r.keys[i].ExpiresAt = &expires
+		}
+	}
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func (r *fakeIngestKeyRepo) ExpireIngestKeys(ctx context.Context, deviceID string, at time.Time) error {
+	expires := utils.NewTimeData(at)
+	for i := range r.keys {
+		if r.keys[i].DeviceID == deviceID && (r.keys[i].ExpiresAt == nil || r.keys[i].ExpiresAt.Time.After(at)) {
+			r.keys[i].ExpiresAt = &expires
+		}
+	}
+	return nil
+}
+
+func newTestSecretBox(t *testing.T) *utils.SecretBox {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	box, err := utils.NewSecretBox(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func signEnvelope(t *testing.T, secret string, envelope IngestEnvelope) []byte {
+	t.Helper()
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(envelope.DeviceID + "." + strconv.Itoa(envelope.KeyID) + "." + envelope.Payload))
+	envelope.Signature = hex.EncodeToString(mac.Sum(nil))
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestIngestVerifierVerify(t *testing.T) {
+	ctx := context.Background()
+	keys := NewIngestKeyService(&fakeIngestKeyRepo{}, nil, newTestSecretBox(t), time.Hour)
+
+	first, err := keys.Rotate(ctx, "dev-1")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	second, err := keys.Rotate(ctx, "dev-1")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	reading := []byte(`{"device_id":"dev-1","power":120}`)
+	payload := base64.StdEncoding.EncodeToString(reading)
+
+	tests := []struct {
+		name             string
+		body             []byte
+		requireSignature bool
+		wantDevice       string
+		wantPayload      []byte
+		wantReject       string
+	}{
+		{
+			name:        "current key",
+			body:        signEnvelope(t, second.Secret, IngestEnvelope{DeviceID: "dev-1", KeyID: second.KeyID, Payload: payload}),
+			wantDevice:  "dev-1",
+			wantPayload: reading,
+		},
+		{
+			name:        "previous key within grace period",
+			body:        signEnvelope(t, first.Secret, IngestEnvelope{DeviceID: "dev-1", KeyID: first.KeyID, Payload: payload}),
+			wantDevice:  "dev-1",
+			wantPayload: reading,
+		},
+		{
+			name:       "signed with another key id",
+			body:       signEnvelope(t, first.Secret, IngestEnvelope{DeviceID: "dev-1", KeyID: second.KeyID, Payload: payload}),
+			wantReject: RejectBadSignature,
+		},
+		{
+			name:       "unknown key id",
+			body:       signEnvelope(t, second.Secret, IngestEnvelope{DeviceID: "dev-1", KeyID: 9, Payload: payload}),
+			wantReject: RejectUnknownKey,
+		},
+		{
+			name:       "device without keys",
+			body:       signEnvelope(t, second.Secret, IngestEnvelope{DeviceID: "dev-2", KeyID: 1, Payload: payload}),
+			wantReject: RejectNoCredentials,
+		},
+		{
+			name:       "signature is not hex",
+			body:       []byte(`{"device_id":"dev-1","key_id":2,"payload":"` + payload + `","signature":"zz"}`),
+			wantReject: RejectMalformed,
+		},
+		{
+			name:       "missing signature",
+			body:       []byte(`{"device_id":"dev-1","key_id":2,"payload":"` + payload + `"}`),
+			wantReject: RejectMalformed,
+		},
+		{
+			name:       "payload is not base64",
+			body:       signEnvelope(t, second.Secret, IngestEnvelope{DeviceID: "dev-1", KeyID: second.KeyID, Payload: "not base64!"}),
+			wantReject: RejectMalformed,
+		},
+		{
+			name:       "invalid JSON",
+			body:       []byte(`{"device_id":`),
+			wantReject: RejectMalformed,
+		},
+		{
+			name:        "unsigned allowed during migration",
+			body:        reading,
+			wantPayload: reading,
+		},
+		{
+			name:             "unsigned rejected",
+			body:             reading,
+			requireSignature: true,
+			wantReject:       RejectUnsigned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewIngestVerifier(keys, tt.requireSignature)
+
+			msg, err := verifier.Verify(ctx, tt.body)
+
+			if tt.wantReject != "" {
+				var rejectErr *RejectError
+				if !errors.As(err, &rejectErr) {
+					t.Fatalf("Verify() error = %v, want reject %q", err, tt.wantReject)
+				}
+				if rejectErr.Reason != tt.wantReject {
+					t.Fatalf("reject reason = %q, want %q", rejectErr.Reason, tt.wantReject)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if msg.DeviceID != tt.wantDevice {
+				t.Errorf("device = %q, want %q", msg.DeviceID, tt.wantDevice)
+			}
+			if string(msg.Payload) != string(tt.wantPayload) {
+				t.Errorf("payload = %s, want %s", msg.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestIngestVerifierAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	keys := NewIngestKeyService(&fakeIngestKeyRepo{}, nil, newTestSecretBox(t), time.Hour)
+
+	issued, err := keys.Rotate(ctx, "dev-1")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		deviceID   string
+		apiKey     string
+		wantReject string
+	}{
+		{name: "valid key", deviceID: "dev-1", apiKey: issued.APIKey},
+		{name: "wrong key", deviceID: "dev-1", apiKey: "wrong", wantReject: RejectBadAPIKey},
+		{name: "HMAC secret is not an API key", deviceID: "dev-1", apiKey: issued.Secret, wantReject: RejectBadAPIKey},
+		{name: "device without keys", deviceID: "dev-2", apiKey: issued.APIKey, wantReject: RejectNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewIngestVerifier(keys, true)
+
+			err := verifier.AuthenticateAPIKey(ctx, tt.deviceID, tt.apiKey)
+
+			if tt.wantReject == "" {
+				if err != nil {
+					t.Fatalf("AuthenticateAPIKey() error = %v", err)
+				}
+				return
+			}
+
+			var rejectErr *RejectError
+			if !errors.As(err, &rejectErr) || rejectErr.Reason != tt.wantReject {
+				t.Fatalf("AuthenticateAPIKey() error = %v, want reject %q", err, tt.wantReject)
+			}
+		})
+	}
+}
+
+// fakeRevocations meneruskan pencabutan langsung ke subscriber seperti
+// pub/sub Redis.
+type fakeRevocations struct {
+	revoked chan string
+}
+
+func (r *fakeRevocations) PublishRevocation(ctx context.Context, deviceID string) error {
+	r.revoked <- deviceID
+	return nil
+}
+
+func (r *fakeRevocations) Revocations(ctx context.Context) <-chan string {
+	return r.revoked
+}
+
+func TestIngestKeyServiceRevoke(t *testing.T) {
+	ctx := context.Background()
+	revocations := &fakeRevocations{revoked: make(chan string)}
+	keys := NewIngestKeyService(&fakeIngestKeyRepo{}, revocations, newTestSecretBox(t), time.Hour)
+
+	first, err := keys.Rotate(ctx, "dev-1")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	// Kunci lama masih dalam masa tenggang saat kepemilikan berpindah.
+	second, err := keys.Rotate(ctx, "dev-1")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	verifier := NewIngestVerifier(keys, true)
+	verifier.WatchRevocations(ctx, revocations)
+
+	for _, apiKey := range []string{first.APIKey, second.APIKey} {
+		if err := verifier.AuthenticateAPIKey(ctx, "dev-1", apiKey); err != nil {
+			t.Fatalf("AuthenticateAPIKey() before revoke error = %v", err)
+		}
+	}
+
+	if err := keys.Revoke(ctx, "dev-1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	// Tunggu sampai subscriber selesai memproses pencabutan.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		verifier.mu.Lock()
+		_, cached := verifier.cache["dev-1"]
+		verifier.mu.Unlock()
+		if !cached {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("verifier cache not invalidated after revoke")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, apiKey := range []string{first.APIKey, second.APIKey} {
+		err := verifier.AuthenticateAPIKey(ctx, "dev-1", apiKey)
+
+		var rejectErr *RejectError
+		if !errors.As(err, &rejectErr) || rejectErr.Reason != RejectNoCredentials {
+			t.Fatalf("AuthenticateAPIKey() after revoke error = %v, want reject %q", err, RejectNoCredentials)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+// IngestPipeline adalah jalur pemrosesan yang dipakai bersama oleh semua
+// transport ingest (AMQP, MQTT, HTTP): verifikasi, decode schema, validasi,
+// koreksi jam, lalu simpan. Reading yang tidak valid dikarantina, bukan
+// disimpan.
+type IngestPipeline struct {
+	svc            *IngestService
+	verifier       *IngestVerifier
+	validator      *ReadingValidator
+	clock          *ClockValidator
+	quarantineRepo repository.QuarantineRepo
+}
+
+func NewIngestPipeline(svc *IngestService, verifier *IngestVerifier, validator *ReadingValidator, clock *ClockValidator, quarantineRepo repository.QuarantineRepo) *IngestPipeline {
+	return &IngestPipeline{
+		svc:            svc,
+		verifier:       verifier,
+		validator:      validator,
+		clock:          clock,
+		quarantineRepo: quarantineRepo,
+	}
+}
+
+// Process memproses body lalu menunggu point-nya tersimpan.
+func (p *IngestPipeline) Process(ctx context.Context, body []byte, deviceID string, receivedAt time.Time) (*entity.RealTimeElectricity, error) {
+	pending, err := p.Submit(ctx, body, deviceID, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := pending.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return pending.Data, nil
+}
+
+// Submit memverifikasi body lalu memproses payload-nya tanpa menunggu flush
+// InfluxDB. deviceID yang tidak kosong (mis. dari topic MQTT) harus sama
+// dengan device penanda tangan. receivedAt adalah waktu terima menurut jam
+// server (zero berarti sekarang). Error bertipe *RejectError atau
+// *QuarantineError bersifat permanen dan tidak perlu diulang.
+func (p *IngestPipeline) Submit(ctx context.Context, body []byte, deviceID string, receivedAt time.Time) (*PendingReading, error) {
+	msg, err := p.verifier.Verify(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.DeviceID != "" {
+		if deviceID != "" && msg.DeviceID != deviceID {
+			return nil, reject(RejectDeviceMismatch, "message device %s does not match transport device %s", msg.DeviceID, deviceID)
+		}
+		deviceID = msg.DeviceID
+	}
+
+	return p.SubmitPayload(ctx, msg.Payload, deviceID, receivedAt)
+}
+
+// SubmitPayload memproses payload reading yang sudah diautentikasi untuk
+// deviceID oleh transport (kosong untuk pesan tanpa tanda tangan).
+func (p *IngestPipeline) SubmitPayload(ctx context.Context, payload []byte, deviceID string, receivedAt time.Time) (*PendingReading, error) {
+	pendings, errs := p.SubmitPayloads(ctx, [][]byte{payload}, deviceID, receivedAt)
+	return pendings[0], errs[0]
+}
+
+// SubmitPayloads seperti SubmitPayload untuk payload yang diterima bersamaan
+// (satu request HTTP). Koreksi jam diterapkan ke seluruh batch sekaligus agar
+// reading-nya tidak mendapat timestamp yang sama. Hasil dan error sejajar
+// dengan payloads.
+func (p *IngestPipeline) SubmitPayloads(ctx context.Context, payloads [][]byte, deviceID string, receivedAt time.Time) ([]*PendingReading, []error) {
+	pendings := make([]*PendingReading, len(payloads))
+	errs := make([]error, len(payloads))
+	readings := make([]*entity.RealTimeElectricity, len(payloads))
+
+	valid := make([]*entity.RealTimeElectricity, 0, len(payloads))
+	for i, payload := range payloads {
+		readings[i], errs[i] = p.decode(ctx, payload, deviceID)
+		if errs[i] == nil {
+			valid = append(valid, readings[i])
+		}
+	}
+
+	if receivedAt.IsZero() {
+		receivedAt = utils.TimeNow().Time
+	}
+	p.clock.ApplyBatch(ctx, valid, receivedAt)
+
+	for i, data := range readings {
+		if errs[i] != nil {
+			continue
+		}
+		pendings[i], errs[i] = p.svc.SubmitRealTimeElectricity(ctx, data)
+	}
+
+	return pendings, errs
+}
+
+// decode membaca dan memvalidasi satu payload. Payload yang tidak valid
+// dikarantina.
+func (p *IngestPipeline) decode(ctx context.Context, payload []byte, deviceID string) (*entity.RealTimeElectricity, error) {
+	data, version, v := DecodeReading(payload)
+	if v != nil {
+		return nil, p.quarantine(ctx, deviceID, version, payload, v)
+	}
+
+	// device_id di payload harus sama dengan device yang terautentikasi agar
+	// kunci satu device tidak bisa dipakai untuk menulis data device lain.
+	if data.DeviceID == "" {
+		data.DeviceID = deviceID
+	} else if deviceID != "" && data.DeviceID != deviceID {
+		return nil, reject(RejectDeviceMismatch, "payload device %s does not match authenticated device %s", data.DeviceID, deviceID)
+	}
+
+	if v := p.validator.Validate(ctx, data); v != nil {
+		return nil, p.quarantine(ctx, data.DeviceID, version, payload, v)
+	}
+
+	return data, nil
+}
+
+// quarantine menyimpan reading yang ditolak. Jika penyimpanan gagal, error
+// biasa dikembalikan agar transport mencoba ulang pesan.
+func (p *IngestPipeline) quarantine(ctx context.Context, deviceID string, version int, payload []byte, v *ReadingViolation) error {
+	reading := &entity.QuarantinedReading{
+		DeviceID:      deviceID,
+		SchemaVersion: version,
+		Reason:        v.Reason,
+		Detail:        v.Detail,
+		Payload:       string(payload),
+	}
+
+	if err := p.quarantineRepo.SaveQuarantinedReading(ctx, reading); err != nil {
+		return fmt.Errorf("failed to quarantine reading (%s): %w", v.Reason, err)
+	}
+
+	ingestQuarantinedTotal.Add(v.Reason, 1)
+	log.Printf("Quarantined reading from device %q (%s): %s", deviceID, v.Reason, v.Detail)
+
+	return &QuarantineError{Violation: v}
+}
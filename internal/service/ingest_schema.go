@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+// Versi schema payload reading. Firmware lama tidak mengirim schema_version
+// dan diperlakukan sebagai versi 1.
+const (
+	SchemaVersionLegacy  = 1
+	SchemaVersionCurrent = 2
+)
+
+type payloadDecoder func(payload []byte) (*entity.RealTimeElectricity, error)
+
+var payloadDecoders = map[int]payloadDecoder{
+	SchemaVersionLegacy:  decodeLegacyPayload,
+	SchemaVersionCurrent: decodeCurrentPayload,
+}
+
+// DecodeReading memilih decoder berdasarkan schema_version payload dan
+// mengembalikan versi yang terdeteksi (0 jika payload tidak bisa dibaca).
+func DecodeReading(payload []byte) (*entity.RealTimeElectricity, int, *ReadingViolation) {
+	var probe struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, 0, violation(entity.QuarantineMalformed, "invalid payload JSON: %v", err)
+	}
+
+	version := SchemaVersionLegacy
+	if probe.SchemaVersion != nil {
+		version = *probe.SchemaVersion
+	}
+
+	decode, ok := payloadDecoders[version]
+	if !ok {
+		return nil, version, violation(entity.QuarantineUnsupportedSchema, "schema_version %d is not supported", version)
+	}
+
+	data, err := decode(payload)
+	if err != nil {
+		return nil, version, violation(entity.QuarantineMalformed, "%v", err)
+	}
+
+	return data, version, nil
+}
+
+// decodeLegacyPayload membaca format firmware lama: field datar tanpa
+// schema_version, field yang hilang bernilai 0.
+func decodeLegacyPayload(payload []byte) (*entity.RealTimeElectricity, error) {
+	var data entity.RealTimeElectricity
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("invalid v1 payload: %w", err)
+	}
+
+	return &data, nil
+}
+
+type currentPayload struct {
+	DeviceID    string          `json:"device_id"`
+	Voltage     *float64        `json:"voltage"`
+	Current     *float64        `json:"current"`
+	Power       *float64        `json:"power"`
+	Energy      *float64        `json:"energy"`
+	PowerFactor *float64        `json:"power_factor"`
+	Frequency   *float64        `json:"frequency"`
+	CreatedAt   *utils.TimeData `json:"created_at"`
+}
+
+// decodeCurrentPayload membaca schema v2: field yang sama dengan v1, tetapi
+// semua besaran wajib ada sehingga field yang hilang tidak terbaca sebagai 0.
+func decodeCurrentPayload(payload []byte) (*entity.RealTimeElectricity, error) {
+	var p currentPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("invalid v2 payload: %w", err)
+	}
+
+	fields := []struct {
+		name  string
+		value *float64
+	}{
+		{entity.ReadingFieldVoltage, p.Voltage},
+		{entity.ReadingFieldCurrent, p.Current},
+		{entity.ReadingFieldPower, p.Power},
+		{entity.ReadingFieldEnergy, p.Energy},
+		{entity.ReadingFieldPowerFactor, p.PowerFactor},
+		{entity.ReadingFieldFrequency, p.Frequency},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			return nil, fmt.Errorf("v2 payload is missing %s", field.name)
+		}
+	}
+
+	data := &entity.RealTimeElectricity{
+		DeviceID:    p.DeviceID,
+		Voltage:     *p.Voltage,
+		Current:     *p.Current,
+		Power:       *p.Power,
+		Energy:      *p.Energy,
+		PowerFactor: *p.PowerFactor,
+		Frequency:   *p.Frequency,
+	}
+	if p.CreatedAt != nil {
+		data.CreatedAt = *p.CreatedAt
+	}
+
+	return data, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"log"
 	"math"
 	"metertronik/internal/domain/entity"
@@ -10,23 +12,127 @@ import (
 	"time"
 )
 
+var (
+	ingestDuplicateTotal  = expvar.NewInt("ingest_duplicate_total")
+	ingestOutOfOrderTotal = expvar.NewInt("ingest_out_of_order_total")
+)
+
 type IngestService struct {
 	influxRepo        repository.InfluxRepo
 	RedisRealtimeRepo repository.RedisRealtimeRepo
+	deviceCache       *DeviceCache
+	policies          *ThresholdPolicyCache
+	surges            *SurgeDetector
+	historyRetention  time.Duration
+	dedupTTL          time.Duration
 }
 
-func NewIngestService(influxRepo repository.InfluxRepo, RedisRealtimeRepo repository.RedisRealtimeRepo) *IngestService {
+// NewIngestService membuat service ingest. historyRetention adalah lama data
+// realtime disimpan di history Redis untuk backfill chart; dedupTTL adalah
+// jendela (dihitung dari timestamp terbaru device) tempat kiriman ulang
+// reading (device, timestamp) masih dikenali dan dibuang.
+func NewIngestService(influxRepo repository.InfluxRepo, RedisRealtimeRepo repository.RedisRealtimeRepo, deviceCache *DeviceCache, policies *ThresholdPolicyCache, surges *SurgeDetector, historyRetention time.Duration, dedupTTL time.Duration) *IngestService {
 	return &IngestService{
 		influxRepo:        influxRepo,
 		RedisRealtimeRepo: RedisRealtimeRepo,
+		deviceCache:       deviceCache,
+		policies:          policies,
+		surges:            surges,
+		historyRetention:  historyRetention,
+		dedupTTL:          dedupTTL,
+	}
+}
+
+// PendingReading adalah reading yang sudah diproses tetapi point InfluxDB-nya
+// masih menunggu flush batch. Pesan asalnya baru boleh di-ack setelah Wait
+// mengembalikan nil.
+type PendingReading struct {
+	Data *entity.RealTimeElectricity
+
+	svc    *IngestService
+	wait   repository.PendingWrite
+	marked bool
+}
+
+// Wait menunggu point tersimpan. Jika gagal, tanda dedup dilepas agar
+// transport bisa mencoba ulang pesan.
+func (p *PendingReading) Wait(ctx context.Context) error {
+	if p == nil || p.wait == nil {
+		return nil
+	}
+
+	if err := p.wait(ctx); err != nil {
+		log.Printf("Error saving real time electricity to influx: %v", err)
+		p.svc.forget(ctx, p.Data, p.marked)
+		return fmt.Errorf("failed to save real time electricity: %w", err)
 	}
+
+	return nil
 }
 
+// ProcessRealTimeElectricity memproses reading dan menunggu point-nya
+// tersimpan.
 func (s *IngestService) ProcessRealTimeElectricity(ctx context.Context, data *entity.RealTimeElectricity) error {
+	pending, err := s.SubmitRealTimeElectricity(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	return pending.Wait(ctx)
+}
+
+// SubmitRealTimeElectricity memproses reading dan mengantrekan point-nya ke
+// batch InfluxDB tanpa menunggu flush. Reading milik satu device harus
+// disubmit berurutan; menunggu hasilnya boleh dilakukan belakangan.
+//
+// Pemrosesan bersifat idempoten untuk reading yang membawa timestamp device:
+// kiriman ulang dengan (device, timestamp) yang sama diabaikan. Reading yang
+// jamnya dikoreksi dideduplikasi dengan waktu hasil koreksi, sehingga
+// kiriman ulangnya tidak dikenali sebagai duplikat.
+func (s *IngestService) SubmitRealTimeElectricity(ctx context.Context, data *entity.RealTimeElectricity) (*PendingReading, error) {
 	log.Printf("\n\nProcessing electricity data for device: %s", data.DeviceID)
 
+	device := s.deviceCache.Get(ctx, data.DeviceID)
+	policy := s.policies.Resolve(ctx, device)
+
+	marked := false
+	// newest adalah timestamp reading terbaru yang sudah diproses untuk
+	// device ini, diambil dari set dedup (zero jika tidak diketahui).
+	var newest time.Time
+	if data.CreatedAt.Time.IsZero() {
+		data.CreatedAt = utils.TimeNow()
+	} else if s.dedupTTL > 0 {
+		// Reading yang jamnya dikoreksi ditandai dengan waktu hasil koreksi,
+		// karena timestamp asli device (mis. 2000-01-01 setelah reboot) berulang.
+		first, seenNewest, err := s.RedisRealtimeRepo.MarkReadingSeen(ctx, data.DeviceID, data.CreatedAt.Time, s.dedupTTL)
+		if err != nil {
+			log.Printf("Failed checking duplicate reading, processing anyway: %v", err)
+		} else if !first {
+			ingestDuplicateTotal.Add(1)
+			log.Printf("Duplicate reading for device %s at %s, skipping", data.DeviceID, data.CreatedAt.Format())
+			return &PendingReading{Data: data}, nil
+		} else {
+			marked = true
+			newest = seenNewest
+		}
+	}
+
 	previousData, err := s.RedisRealtimeRepo.GetLatestElectricity(ctx, data.DeviceID)
 
+	// Cache latest hanya ditulis ulang saat perubahannya signifikan, jadi
+	// bisa lebih tua dari reading terakhir; ia hanya dipakai bila timestamp
+	// terbaru dari set dedup tidak tersedia.
+	if newest.IsZero() && err == nil && previousData != nil {
+		newest = previousData.CreatedAt.Time
+	}
+
+	// Reading yang lebih tua dari reading terbaru device (kiriman buffer
+	// setelah reconnect) hanya disimpan ke InfluxDB dan history, tanpa
+	// menyentuh cache latest, baseline surge, maupun stream realtime.
+	if data.CreatedAt.Time.Before(newest) {
+		return s.saveOutOfOrder(ctx, data, marked)
+	}
+
 	if err != nil {
 		log.Printf("Error getting previous electricity data: %v", err)
 		data.PowerSurge = 0
@@ -36,26 +142,35 @@ func (s *IngestService) ProcessRealTimeElectricity(ctx context.Context, data *en
 		data.PSPercent = 0
 	} else {
 		data.PowerSurge = math.Abs(data.Power - previousData.Power)
-		minBaseline := 50.0
 
-		if previousData.Power >= minBaseline {
+		if previousData.Power >= policy.SurgeBaselineWatts && previousData.Power > 0 {
 			data.PSPercent = math.Abs((data.PowerSurge / previousData.Power) * 100)
 		} else {
-			log.Printf("Previous power is 0, setting PSPercent to 0")
+			log.Printf("Previous power is below surge baseline (%.2f W), setting PSPercent to 0", policy.SurgeBaselineWatts)
 			data.PSPercent = 0
 		}
 	}
 
-	if data.CreatedAt.Time.IsZero() {
-		data.CreatedAt = utils.TimeNow()
+	if device.IsCumulative() && previousData != nil && data.Energy < previousData.Energy {
+		log.Printf("Energy register of device %s went backwards (%.3f -> %.3f), delta %.3f kWh",
+			data.DeviceID, previousData.Energy, data.Energy,
+			utils.RegisterDelta(previousData.Energy, data.Energy, device.EnergyRegisterMax))
 	}
 
-	errInflux := s.influxRepo.SaveRealTimeElectricity(ctx, data)
+	pending, err := s.queue(ctx, data, marked)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Queued data for influxDB : ", data)
 
-	if errInflux != nil {
-		log.Printf("Error saving real time electricity to influx: %v", errInflux)
-	} else {
-		log.Println("Saving data to influxDB : ", data)
+	s.surges.Observe(ctx, data, previousData, policy)
+
+	if err := s.RedisRealtimeRepo.PublishElectricity(ctx, data.DeviceID, data); err != nil {
+		log.Printf("Failed publishing realtime stream: %v", err)
+	}
+
+	if err := s.RedisRealtimeRepo.SaveElectricityHistory(ctx, data.DeviceID, data, s.historyRetention); err != nil {
+		log.Printf("Failed saving history cache: %v", err)
 	}
 
 	// Jika previousData == nil, ini adalah data pertama, selalu cache
@@ -66,11 +181,7 @@ func (s *IngestService) ProcessRealTimeElectricity(ctx context.Context, data *en
 		} else {
 			log.Println("Updated latest cache data")
 		}
-
-		if err := s.RedisRealtimeRepo.SaveElectricityHistory(ctx, data.DeviceID, data, 5*time.Minute); err != nil {
-			log.Printf("Failed saving history cache: %v", err)
-		}
-		return nil
+		return pending, nil
 	}
 
 	changed, _, err := s.RedisRealtimeRepo.HasChanged(ctx, data.DeviceID, data)
@@ -80,13 +191,13 @@ func (s *IngestService) ProcessRealTimeElectricity(ctx context.Context, data *en
 
 	if !changed {
 		log.Printf("No change for device %s (skip caching)", data.DeviceID)
-		return nil
+		return pending, nil
 	}
 
-	proximityValue := ProximityValue(previousData, data)
+	proximityValue := ProximityValue(previousData, data, device.EnergyMode, policy)
 	if !proximityValue {
 		log.Printf("No significant change for device %s, skipping caching", data.DeviceID)
-		return nil
+		return pending, nil
 	}
 
 	if err := s.RedisRealtimeRepo.SetLatestElectricity(ctx, data.DeviceID, data); err != nil {
@@ -95,11 +206,48 @@ func (s *IngestService) ProcessRealTimeElectricity(ctx context.Context, data *en
 		log.Println("Updated latest cache data")
 	}
 
-	if err := s.RedisRealtimeRepo.SaveElectricityHistory(ctx, data.DeviceID, data, 5*time.Minute); err != nil {
+	return pending, nil
+}
+
+func (s *IngestService) saveOutOfOrder(ctx context.Context, data *entity.RealTimeElectricity, marked bool) (*PendingReading, error) {
+	ingestOutOfOrderTotal.Add(1)
+	log.Printf("Out-of-order reading for device %s at %s, storing without updating realtime cache", data.DeviceID, data.CreatedAt.Format())
+
+	data.PowerSurge = 0
+	data.PSPercent = 0
+
+	pending, err := s.queue(ctx, data, marked)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RedisRealtimeRepo.SaveElectricityHistory(ctx, data.DeviceID, data, s.historyRetention); err != nil {
 		log.Printf("Failed saving history cache: %v", err)
 	}
 
-	return nil
+	return pending, nil
+}
+
+func (s *IngestService) queue(ctx context.Context, data *entity.RealTimeElectricity, marked bool) (*PendingReading, error) {
+	wait, err := s.influxRepo.QueueRealTimeElectricity(ctx, data)
+	if err != nil {
+		log.Printf("Error queueing real time electricity for influx: %v", err)
+		s.forget(ctx, data, marked)
+		return nil, fmt.Errorf("failed to save real time electricity: %w", err)
+	}
+
+	return &PendingReading{Data: data, svc: s, wait: wait, marked: marked}, nil
+}
+
+// forget melepas tanda dedup agar reading yang gagal disimpan bisa dicoba
+// ulang oleh transport.
+func (s *IngestService) forget(ctx context.Context, data *entity.RealTimeElectricity, marked bool) {
+	if !marked {
+		return
+	}
+	if err := s.RedisRealtimeRepo.ForgetReading(ctx, data.DeviceID, data.CreatedAt.Time); err != nil {
+		log.Printf("Failed releasing dedup marker: %v", err)
+	}
 }
 
 func percentageDiff(current, previous float64) float64 {
@@ -109,30 +257,37 @@ func percentageDiff(current, previous float64) float64 {
 	return math.Abs(((current - previous) / previous) * 100)
 }
 
-func ProximityValue(previousData *entity.RealTimeElectricity, data *entity.RealTimeElectricity) bool {
+// ProximityValue menentukan apakah perubahan data cukup signifikan untuk
+// di-cache menurut policy device. Energi meter kumulatif selalu naik,
+// sehingga tidak dibandingkan.
+func ProximityValue(previousData *entity.RealTimeElectricity, data *entity.RealTimeElectricity, energyMode string, policy *entity.ThresholdPolicy) bool {
 	if previousData == nil || data == nil {
 		return false
 	}
-
-	threshold := 10.0
+	if policy == nil {
+		policy = entity.DefaultThresholdPolicy()
+	}
 
 	diffPower := percentageDiff(data.Power, previousData.Power)
 	diffVoltage := percentageDiff(data.Voltage, previousData.Voltage)
 	diffCurrent := percentageDiff(data.Current, previousData.Current)
-	diffEnergy := percentageDiff(data.Energy, previousData.Energy)
+	diffEnergy := 0.0
+	if energyMode != entity.EnergyModeCumulative {
+		diffEnergy = percentageDiff(data.Energy, previousData.Energy)
+	}
 	diffPF := percentageDiff(data.PowerFactor, previousData.PowerFactor)
 	diffFreq := percentageDiff(data.Frequency, previousData.Frequency)
 
-	if data.PowerSurge > 500.0 || data.PSPercent > 15.0 {
+	if data.PowerSurge > policy.SurgeWatts || data.PSPercent > policy.SurgePercent {
 		return true
 	}
 
-	if diffPower >= threshold ||
-		diffVoltage >= threshold ||
-		diffCurrent >= threshold ||
-		diffEnergy >= threshold ||
-		diffPF >= threshold ||
-		diffFreq >= threshold {
+	if diffPower >= policy.PowerPercent ||
+		diffVoltage >= policy.VoltagePercent ||
+		diffCurrent >= policy.CurrentPercent ||
+		(energyMode != entity.EnergyModeCumulative && diffEnergy >= policy.EnergyPercent) ||
+		diffPF >= policy.PowerFactorPercent ||
+		diffFreq >= policy.FrequencyPercent {
 		return true
 	}
 
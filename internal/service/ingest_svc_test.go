@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+
+	"metertronik/internal/domain/entity"
+)
+
+func TestProximityValue(t *testing.T) {
+	previous := &entity.RealTimeElectricity{
+		Voltage:     220,
+		Current:     2,
+		Power:       400,
+		Energy:      100,
+		PowerFactor: 0.9,
+		Frequency:   50,
+	}
+
+	strict := &entity.ThresholdPolicy{
+		VoltagePercent:     1,
+		CurrentPercent:     1,
+		PowerPercent:       1,
+		EnergyPercent:      1,
+		PowerFactorPercent: 1,
+		FrequencyPercent:   1,
+		SurgeWatts:         100,
+		SurgePercent:       5,
+	}
+	loose := &entity.ThresholdPolicy{
+		VoltagePercent:     50,
+		CurrentPercent:     50,
+		PowerPercent:       50,
+		EnergyPercent:      50,
+		PowerFactorPercent: 50,
+		FrequencyPercent:   50,
+		SurgeWatts:         5000,
+		SurgePercent:       90,
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(*entity.RealTimeElectricity)
+		previous   *entity.RealTimeElectricity
+		energyMode string
+		policy     *entity.ThresholdPolicy
+		want       bool
+	}{
+		{name: "no previous reading", previous: nil, policy: strict, want: false},
+		{name: "unchanged", previous: previous, policy: strict, want: false},
+		{
+			name:     "small power change under default policy",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.Power = 420 },
+			policy:   nil,
+			want:     false,
+		},
+		{
+			name:     "same change is significant under strict policy",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.Power = 420 },
+			policy:   strict,
+			want:     true,
+		},
+		{
+			name:     "large change ignored by loose policy",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.Voltage = 250; d.Power = 550 },
+			policy:   loose,
+			want:     false,
+		},
+		{
+			name:     "surge watts exceeded",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.PowerSurge = 150 },
+			policy:   strict,
+			want:     true,
+		},
+		{
+			name:     "surge percent exceeded",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.PSPercent = 95 },
+			policy:   loose,
+			want:     true,
+		},
+		{
+			name:     "energy change in delta mode",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.Energy = 150 },
+			policy:   strict,
+			want:     true,
+		},
+		{
+			name:       "energy change ignored for cumulative meter",
+			previous:   previous,
+			modify:     func(d *entity.RealTimeElectricity) { d.Energy = 150 },
+			energyMode: entity.EnergyModeCumulative,
+			policy:     strict,
+			want:       false,
+		},
+		{
+			name:     "frequency change",
+			previous: previous,
+			modify:   func(d *entity.RealTimeElectricity) { d.Frequency = 51 },
+			policy:   strict,
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := *previous
+			if tt.modify != nil {
+				tt.modify(&data)
+			}
+
+			if got := ProximityValue(tt.previous, &data, tt.energyMode, tt.policy); got != tt.want {
+				t.Errorf("ProximityValue() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
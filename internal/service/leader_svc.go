@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/repository"
+)
+
+var (
+	leaderGauge       = expvar.NewInt("cron_is_leader")
+	leaderTransitions = expvar.NewInt("cron_leader_transitions_total")
+	leaderHolder      = expvar.NewString("cron_leader_holder")
+)
+
+// LeaderElector menjaga agar hanya satu instance cron yang menjalankan
+// agregasi. Lease diperpanjang setiap ttl/3; jika leader berhenti
+// memperpanjang, instance lain mengambil alih setelah ttl habis.
+type LeaderElector struct {
+	lease    repository.LeaderLease
+	identity string
+	ttl      time.Duration
+
+	mu        sync.RWMutex
+	leader    bool
+	leaderCtx context.Context
+	cancel    context.CancelFunc
+
+	changes chan bool
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// NewLeaderElector membuat elector. Instance hanya menjadi leader selama
+// lease benar-benar dipegang; error lease berarti standby.
+func NewLeaderElector(lease repository.LeaderLease, identity string, ttl time.Duration) *LeaderElector {
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return &LeaderElector{
+		lease:     lease,
+		identity:  identity,
+		ttl:       ttl,
+		leaderCtx: leaderCtx,
+		cancel:    cancel,
+		changes:   make(chan bool, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start mencoba mengambil lease sekali secara sinkron, lalu terus
+// memperpanjang/mengambil lease di background sampai Stop dipanggil.
+func (e *LeaderElector) Start(ctx context.Context) {
+	ctx, e.stop = context.WithCancel(ctx)
+
+	e.tick(ctx)
+
+	go func() {
+		defer close(e.done)
+
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				e.resign()
+				return
+			case <-ticker.C:
+				e.tick(ctx)
+			}
+		}
+	}()
+}
+
+// Stop menghentikan elector dan melepas lease agar instance lain bisa
+// langsung mengambil alih tanpa menunggu ttl habis.
+func (e *LeaderElector) Stop() {
+	if e.stop == nil {
+		return
+	}
+	e.stop()
+	<-e.done
+}
+
+// IsLeader melaporkan apakah instance ini memegang lease saat ini.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Context dibatalkan saat leadership hilang, sehingga agregasi yang sedang
+// berjalan berhenti sebelum bertabrakan dengan leader baru.
+func (e *LeaderElector) Context() context.Context {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderCtx
+}
+
+// Changes mengirim true saat menjadi leader dan false saat kehilangan
+// leadership. Hanya perubahan terakhir yang disimpan.
+func (e *LeaderElector) Changes() <-chan bool {
+	return e.changes
+}
+
+func (e *LeaderElector) tick(ctx context.Context) {
+	opCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	defer cancel()
+
+	var ok bool
+	var err error
+
+	if e.IsLeader() {
+		ok, err = e.lease.Renew(opCtx, e.identity, e.ttl)
+	} else {
+		ok, err = e.lease.Acquire(opCtx, e.identity, e.ttl)
+	}
+
+	if err != nil {
+		log.Printf("[LEADER] Lease error for %s: %v", e.identity, err)
+		ok = false
+	}
+
+	if holder, err := e.lease.Holder(opCtx); err == nil {
+		leaderHolder.Set(holder)
+	}
+
+	e.setLeader(ctx, ok)
+}
+
+func (e *LeaderElector) setLeader(ctx context.Context, leader bool) {
+	e.mu.Lock()
+	if e.leader == leader {
+		e.mu.Unlock()
+		return
+	}
+
+	e.leader = leader
+	if leader {
+		e.leaderCtx, e.cancel = context.WithCancel(ctx)
+		leaderGauge.Set(1)
+	} else {
+		e.cancel()
+		leaderGauge.Set(0)
+	}
+	e.mu.Unlock()
+
+	leaderTransitions.Add(1)
+	if leader {
+		log.Printf("[LEADER] %s became leader", e.identity)
+	} else {
+		log.Printf("[LEADER] %s lost leadership", e.identity)
+	}
+
+	select {
+	case <-e.changes:
+	default:
+	}
+	e.changes <- leader
+}
+
+func (e *LeaderElector) resign() {
+	if !e.IsLeader() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := e.lease.Release(ctx, e.identity); err != nil {
+		log.Printf("[LEADER] Failed to release lease for %s: %v", e.identity, err)
+	}
+	e.setLeader(context.Background(), false)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+type PartitionIndex struct {
+	Suffix string
+	Column string
+}
+
+// PartitionSpec menjelaskan satu tabel ber-partisi tahunan, mengikuti pola
+// nama di migration.sql (hourly_data_2025, idx_hourly_2025_device, ...).
+type PartitionSpec struct {
+	Table       string
+	IndexPrefix string
+	Timestamp   bool
+	Indexes     []PartitionIndex
+}
+
+var DefaultPartitionSpecs = []PartitionSpec{
+	{
+		Table:       "hourly_data",
+		IndexPrefix: "hourly",
+		Timestamp:   true,
+		Indexes:     []PartitionIndex{{Suffix: "device", Column: "device_id"}, {Suffix: "ts", Column: "ts"}},
+	},
+	{
+		Table:       "daily_data",
+		IndexPrefix: "daily",
+		Indexes:     []PartitionIndex{{Suffix: "device", Column: "device_id"}, {Suffix: "day", Column: "day"}},
+	},
+	{
+		Table:       "monthly_data",
+		IndexPrefix: "monthly",
+		Indexes:     []PartitionIndex{{Suffix: "device", Column: "device_id"}, {Suffix: "month", Column: "month"}},
+	},
+}
+
+type PartitionService struct {
+	partitionRepo repository.PartitionRepo
+	specs         []PartitionSpec
+}
+
+func NewPartitionService(partitionRepo repository.PartitionRepo, specs []PartitionSpec) *PartitionService {
+	return &PartitionService{
+		partitionRepo: partitionRepo,
+		specs:         specs,
+	}
+}
+
+// EnsureAhead membuat partisi untuk tahun berjalan hingga aheadYears tahun ke
+// depan.
+func (s *PartitionService) EnsureAhead(ctx context.Context, now utils.TimeData, aheadYears int) ([]string, error) {
+	year := now.Time.UTC().Year()
+	return s.EnsureYears(ctx, year, year+aheadYears)
+}
+
+// EnsureYears membuat partisi (beserta index-nya) untuk tahun fromYear hingga
+// toYear, mis. sebelum backfill ke tahun-tahun lalu. Partisi yang sudah ada
+// dilewati.
+func (s *PartitionService) EnsureYears(ctx context.Context, fromYear int, toYear int) ([]string, error) {
+	var created []string
+
+	for _, spec := range s.specs {
+		existing, err := s.partitionRepo.ListPartitions(ctx, spec.Table)
+		if err != nil {
+			return created, err
+		}
+
+		for year := fromYear; year <= toYear; year++ {
+			partition := yearlyPartition(spec, year)
+
+			if covered(existing, partition) {
+				continue
+			}
+
+			if err := s.partitionRepo.CreatePartition(ctx, partition, spec.Timestamp); err != nil {
+				return created, err
+			}
+
+			for _, idx := range spec.Indexes {
+				indexName := fmt.Sprintf("idx_%s_%d_%s", spec.IndexPrefix, year, idx.Suffix)
+				if err := s.partitionRepo.CreatePartitionIndex(ctx, partition.Name, indexName, idx.Column); err != nil {
+					return created, err
+				}
+			}
+
+			log.Printf("[PARTITION] Created %s [%s, %s)", partition.Name,
+				partition.From.FormatLayout("2006-01-02"), partition.To.FormatLayout("2006-01-02"))
+			created = append(created, partition.Name)
+		}
+	}
+
+	return created, nil
+}
+
+// ReportGaps mengembalikan rentang waktu yang tidak tercakup partisi mana pun,
+// baik di antara partisi yang ada maupun di periode yang akan datang.
+// fromYear adalah tahun paling awal yang wajib tercakup; 0 berarti tahun
+// berjalan.
+func (s *PartitionService) ReportGaps(ctx context.Context, now utils.TimeData, fromYear int, aheadYears int) ([]entity.PartitionGap, error) {
+	var gaps []entity.PartitionGap
+
+	if fromYear == 0 {
+		fromYear = now.Time.UTC().Year()
+	}
+	requiredTo := utils.NewTimeData(time.Date(now.Time.UTC().Year()+aheadYears+1, 1, 1, 0, 0, 0, 0, time.UTC))
+	requiredFrom := utils.NewTimeData(time.Date(fromYear, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, spec := range s.specs {
+		partitions, err := s.partitionRepo.ListPartitions(ctx, spec.Table)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(partitions) == 0 {
+			gaps = append(gaps, entity.PartitionGap{Parent: spec.Table, From: requiredFrom, To: requiredTo})
+			continue
+		}
+
+		for i := 1; i < len(partitions); i++ {
+			prev, next := partitions[i-1], partitions[i]
+			if next.From.Time.After(prev.To.Time) {
+				gaps = append(gaps, entity.PartitionGap{Parent: spec.Table, From: prev.To, To: next.From})
+			}
+		}
+
+		first := partitions[0]
+		if first.From.Time.After(requiredFrom.Time) {
+			gaps = append(gaps, entity.PartitionGap{Parent: spec.Table, From: requiredFrom, To: first.From})
+		}
+
+		last := partitions[len(partitions)-1]
+		if last.To.Time.Before(requiredTo.Time) {
+			from := last.To
+			if from.Time.Before(requiredFrom.Time) {
+				from = requiredFrom
+			}
+			gaps = append(gaps, entity.PartitionGap{Parent: spec.Table, From: from, To: requiredTo})
+		}
+	}
+
+	return gaps, nil
+}
+
+// ApplyRetention melepas partisi yang seluruh rentangnya lebih tua dari
+// retentionYears tahun. Jika drop true, partisi tersebut juga dihapus.
+func (s *PartitionService) ApplyRetention(ctx context.Context, now utils.TimeData, retentionYears int, drop bool) ([]string, error) {
+	if retentionYears <= 0 {
+		return nil, nil
+	}
+
+	cutoff := time.Date(now.Time.UTC().Year()-retentionYears, 1, 1, 0, 0, 0, 0, time.UTC)
+	var removed []string
+
+	for _, spec := range s.specs {
+		partitions, err := s.partitionRepo.ListPartitions(ctx, spec.Table)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, p := range partitions {
+			if p.To.Time.After(cutoff) {
+				continue
+			}
+
+			if err := s.partitionRepo.DetachPartition(ctx, spec.Table, p.Name); err != nil {
+				return removed, err
+			}
+			log.Printf("[PARTITION] Detached %s from %s", p.Name, spec.Table)
+
+			if drop {
+				if err := s.partitionRepo.DropPartition(ctx, p.Name); err != nil {
+					return removed, err
+				}
+				log.Printf("[PARTITION] Dropped %s", p.Name)
+			}
+
+			removed = append(removed, p.Name)
+		}
+	}
+
+	return removed, nil
+}
+
+func yearlyPartition(spec PartitionSpec, year int) entity.Partition {
+	return entity.Partition{
+		Parent: spec.Table,
+		Name:   fmt.Sprintf("%s_%d", spec.Table, year),
+		From:   utils.NewTimeData(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)),
+		To:     utils.NewTimeData(time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func covered(existing []entity.Partition, partition entity.Partition) bool {
+	for _, p := range existing {
+		if p.Name == partition.Name {
+			return true
+		}
+		if p.From.Time.Before(partition.To.Time) && partition.From.Time.Before(p.To.Time) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+	"metertronik/pkg/validator"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// claimSecretBytes menghasilkan secret 20 karakter base32 (100 bit) yang
+// cukup pendek untuk dicetak pada label device.
+const claimSecretBytes = 12
+
+// ProvisionService mendaftarkan device fisik sebelum dikirim ke pengguna:
+// membuat baris device tanpa pemilik dan secret klaim.
+type ProvisionService struct {
+	deviceRepo     repository.DeviceRepo
+	credentialRepo repository.DeviceCredentialRepo
+}
+
+func NewProvisionService(deviceRepo repository.DeviceRepo, credentialRepo repository.DeviceCredentialRepo) *ProvisionService {
+	return &ProvisionService{
+		deviceRepo:     deviceRepo,
+		credentialRepo: credentialRepo,
+	}
+}
+
+// Provision mengembalikan secret klaim dalam bentuk teks. secret kosong
+// berarti dibuatkan secara acak. Provisioning ulang mengganti secret lama
+// tanpa mengubah pemilik device.
+func (s *ProvisionService) Provision(ctx context.Context, deviceID string, deviceType string, secret string) (string, error) {
+	if err := validator.ValidateControllerID(deviceID); err != nil {
+		return "", err
+	}
+
+	if secret == "" {
+		generated, err := GenerateClaimSecret()
+		if err != nil {
+			return "", err
+		}
+		secret = generated
+	}
+
+	device, err := s.deviceRepo.GetDevice(ctx, deviceID)
+	if err != nil {
+		return "", err
+	}
+
+	now := utils.TimeNow()
+
+	if device == nil {
+		device = &entity.Device{
+			DeviceID:        deviceID,
+			DeviceType:      deviceType,
+			DeviceStatus:    entity.DeviceStatusActive,
+			EnergyMode:      entity.EnergyModeDelta,
+			DeviceCreatedAt: now,
+			DeviceUpdatedAt: now,
+		}
+		if err := s.deviceRepo.CreateDevice(ctx, device); err != nil {
+			return "", err
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(NormalizeClaimSecret(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash claim secret: %w", err)
+	}
+
+	if err := s.credentialRepo.SaveCredential(ctx, &entity.DeviceCredential{
+		DeviceID:        deviceID,
+		ClaimSecretHash: string(hash),
+		ProvisionedAt:   now,
+	}); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Delete menghapus device dari registry beserta kredensial klaim dan kunci
+// ingest-nya. Meter harus diprovisioning ulang sebelum bisa diklaim lagi.
+func (s *ProvisionService) Delete(ctx context.Context, deviceID string) error {
+	return s.deviceRepo.DeleteDevice(ctx, deviceID)
+}
+
+// GenerateClaimSecret membuat secret acak berformat XXXXX-XXXXX-XXXXX-XXXXX.
+func GenerateClaimSecret() (string, error) {
+	buf := make([]byte, claimSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate claim secret: %w", err)
+	}
+
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)[:20]
+
+	var groups []string
+	for i := 0; i < len(encoded); i += 5 {
+		groups = append(groups, encoded[i:i+5])
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+// NormalizeClaimSecret mengabaikan huruf besar/kecil, spasi dan tanda
+// hubung agar secret yang diketik ulang dari label tetap cocok.
+func NormalizeClaimSecret(secret string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(secret)))
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+)
+
+const defaultReadingRuleTTL = time.Minute
+
+var ingestQuarantinedTotal = expvar.NewMap("ingest_quarantined_total")
+
+// ReadingViolation menjelaskan kenapa reading tidak layak disimpan. Reason
+// adalah salah satu kode entity.Quarantine*.
+type ReadingViolation struct {
+	Reason string
+	Detail string
+}
+
+func (v *ReadingViolation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Reason, v.Detail)
+}
+
+func violation(reason string, format string, args ...interface{}) *ReadingViolation {
+	return &ReadingViolation{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// QuarantineError dikembalikan pipeline setelah reading berhasil disimpan di
+// karantina. Pesan sudah selesai ditangani dan tidak perlu diulang.
+type QuarantineError struct {
+	Violation *ReadingViolation
+}
+
+func (e *QuarantineError) Error() string {
+	return fmt.Sprintf("reading quarantined (%s): %s", e.Violation.Reason, e.Violation.Detail)
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// defaultReadingRules dipakai bila tabel reading_rules kosong atau tidak
+// bisa dibaca; nilainya sama dengan seed di migration.
+var defaultReadingRules = map[string]entity.ReadingRule{
+	entity.ReadingFieldVoltage:     {Field: entity.ReadingFieldVoltage, MinValue: floatPtr(0), MaxValue: floatPtr(500)},
+	entity.ReadingFieldCurrent:     {Field: entity.ReadingFieldCurrent, MinValue: floatPtr(0), MaxValue: floatPtr(200)},
+	entity.ReadingFieldPower:       {Field: entity.ReadingFieldPower, MinValue: floatPtr(0), MaxValue: floatPtr(100000)},
+	entity.ReadingFieldEnergy:      {Field: entity.ReadingFieldEnergy, MinValue: floatPtr(0)},
+	entity.ReadingFieldPowerFactor: {Field: entity.ReadingFieldPowerFactor, MinValue: floatPtr(0), MaxValue: floatPtr(1)},
+	entity.ReadingFieldFrequency:   {Field: entity.ReadingFieldFrequency, MinValue: floatPtr(45), MaxValue: floatPtr(65)},
+}
+
+// ReadingValidator memeriksa reading terhadap batas fisik per tipe device.
+// Aturan dibaca dari reading_rules dan di-cache selama ttl.
+type ReadingValidator struct {
+	repo        repository.ReadingRuleRepo
+	deviceCache *DeviceCache
+	ttl         time.Duration
+
+	mu       sync.Mutex
+	rules    map[string]map[string]entity.ReadingRule
+	loadedAt time.Time
+}
+
+func NewReadingValidator(repo repository.ReadingRuleRepo, deviceCache *DeviceCache, ttl time.Duration) *ReadingValidator {
+	if ttl <= 0 {
+		ttl = defaultReadingRuleTTL
+	}
+
+	return &ReadingValidator{
+		repo:        repo,
+		deviceCache: deviceCache,
+		ttl:         ttl,
+	}
+}
+
+// Validate mengembalikan nil jika reading layak disimpan.
+func (v *ReadingValidator) Validate(ctx context.Context, data *entity.RealTimeElectricity) *ReadingViolation {
+	if data.DeviceID == "" {
+		return violation(entity.QuarantineMissingDeviceID, "device_id is empty")
+	}
+
+	values := []struct {
+		field string
+		value float64
+	}{
+		{entity.ReadingFieldVoltage, data.Voltage},
+		{entity.ReadingFieldCurrent, data.Current},
+		{entity.ReadingFieldPower, data.Power},
+		{entity.ReadingFieldEnergy, data.Energy},
+		{entity.ReadingFieldPowerFactor, data.PowerFactor},
+		{entity.ReadingFieldFrequency, data.Frequency},
+	}
+
+	rules := v.rulesFor(ctx, v.deviceCache.Get(ctx, data.DeviceID).DeviceType)
+
+	for _, item := range values {
+		if math.IsNaN(item.value) || math.IsInf(item.value, 0) {
+			return violation(entity.QuarantineNotFinite, "%s is not a finite number", item.field)
+		}
+
+		rule, ok := rules[item.field]
+		if !ok {
+			continue
+		}
+		if rule.MinValue != nil && item.value < *rule.MinValue {
+			return violation(entity.QuarantineOutOfRange, "%s %.3f is below minimum %.3f", item.field, item.value, *rule.MinValue)
+		}
+		if rule.MaxValue != nil && item.value > *rule.MaxValue {
+			return violation(entity.QuarantineOutOfRange, "%s %.3f is above maximum %.3f", item.field, item.value, *rule.MaxValue)
+		}
+	}
+
+	return nil
+}
+
+// rulesFor menggabungkan aturan default, aturan umum (device_type kosong),
+// lalu aturan khusus tipe device; yang lebih spesifik menimpa.
+func (v *ReadingValidator) rulesFor(ctx context.Context, deviceType string) map[string]entity.ReadingRule {
+	all := v.load(ctx)
+
+	rules := make(map[string]entity.ReadingRule, len(defaultReadingRules))
+	for field, rule := range defaultReadingRules {
+		rules[field] = rule
+	}
+	for field, rule := range all[""] {
+		rules[field] = rule
+	}
+	if deviceType != "" {
+		for field, rule := range all[deviceType] {
+			rules[field] = rule
+		}
+	}
+
+	return rules
+}
+
+func (v *ReadingValidator) load(ctx context.Context) map[string]map[string]entity.ReadingRule {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.repo == nil || (v.rules != nil && time.Since(v.loadedAt) < v.ttl) {
+		return v.rules
+	}
+
+	list, err := v.repo.ListReadingRules(ctx)
+	if err != nil {
+		log.Printf("Failed to load reading rules, using cached/default rules: %v", err)
+		v.loadedAt = time.Now()
+		return v.rules
+	}
+
+	rules := make(map[string]map[string]entity.ReadingRule)
+	for _, rule := range list {
+		if rules[rule.DeviceType] == nil {
+			rules[rule.DeviceType] = make(map[string]entity.ReadingRule)
+		}
+		rules[rule.DeviceType][rule.Field] = rule
+	}
+
+	v.rules = rules
+	v.loadedAt = time.Now()
+	return v.rules
+}
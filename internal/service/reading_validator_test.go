@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+)
+
+type fakeReadingRuleRepo struct {
+	rules []entity.ReadingRule
+}
+
+func (r *fakeReadingRuleRepo) ListReadingRules(ctx context.Context) ([]entity.ReadingRule, error) {
+	return r.rules, nil
+}
+
+// fakeDeviceRepo hanya mengimplementasikan GetDevice; method lain panic.
+type fakeDeviceRepo struct {
+	repository.DeviceRepo
+	devices map[string]*entity.Device
+}
+
+func (r *fakeDeviceRepo) GetDevice(ctx context.Context, deviceID string) (*entity.Device, error) {
+	return r.devices[deviceID], nil
+}
+
+func validReading(deviceID string) *entity.RealTimeElectricity {
+	return &entity.RealTimeElectricity{
+		DeviceID:    deviceID,
+		Voltage:     220,
+		Current:     1.5,
+		Power:       300,
+		Energy:      12.5,
+		PowerFactor: 0.95,
+		Frequency:   50,
+	}
+}
+
+func TestReadingValidatorValidate(t *testing.T) {
+	rules := &fakeReadingRuleRepo{rules: []entity.ReadingRule{
+		{DeviceType: "", Field: entity.ReadingFieldVoltage, MinValue: floatPtr(100), MaxValue: floatPtr(260)},
+		{DeviceType: "industrial", Field: entity.ReadingFieldVoltage, MinValue: floatPtr(300), MaxValue: floatPtr(450)},
+		{DeviceType: "industrial", Field: entity.ReadingFieldPower, MaxValue: floatPtr(500000)},
+	}}
+	devices := &fakeDeviceRepo{devices: map[string]*entity.Device{
+		"home-1":    {DeviceID: "home-1", DeviceType: "household"},
+		"factory-1": {DeviceID: "factory-1", DeviceType: "industrial"},
+	}}
+	validator := NewReadingValidator(rules, NewDeviceCache(devices, 0, "UTC"), 0)
+
+	tests := []struct {
+		name       string
+		modify     func(*entity.RealTimeElectricity)
+		deviceID   string
+		wantReason string
+	}{
+		{name: "valid reading", deviceID: "home-1"},
+		{name: "missing device id", deviceID: "", wantReason: entity.QuarantineMissingDeviceID},
+		{name: "NaN power", deviceID: "home-1", modify: func(d *entity.RealTimeElectricity) { d.Power = math.NaN() }, wantReason: entity.QuarantineNotFinite},
+		{name: "infinite current", deviceID: "home-1", modify: func(d *entity.RealTimeElectricity) { d.Current = math.Inf(1) }, wantReason: entity.QuarantineNotFinite},
+		{name: "negative energy from default rule", deviceID: "home-1", modify: func(d *entity.RealTimeElectricity) { d.Energy = -1 }, wantReason: entity.QuarantineOutOfRange},
+		{name: "power factor above one", deviceID: "home-1", modify: func(d *entity.RealTimeElectricity) { d.PowerFactor = 1.2 }, wantReason: entity.QuarantineOutOfRange},
+		{name: "general rule overrides default", deviceID: "home-1", modify: func(d *entity.RealTimeElectricity) { d.Voltage = 90 }, wantReason: entity.QuarantineOutOfRange},
+		{name: "unregistered device uses general rule", deviceID: "unknown", modify: func(d *entity.RealTimeElectricity) { d.Voltage = 280 }, wantReason: entity.QuarantineOutOfRange},
+		{name: "device type rule overrides general rule", deviceID: "factory-1", modify: func(d *entity.RealTimeElectricity) { d.Voltage = 400 }},
+		{name: "device type rule rejects household voltage", deviceID: "factory-1", wantReason: entity.QuarantineOutOfRange},
+		{name: "device type rule raises power limit", deviceID: "factory-1", modify: func(d *entity.RealTimeElectricity) { d.Voltage = 400; d.Power = 200000 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := validReading(tt.deviceID)
+			if tt.modify != nil {
+				tt.modify(data)
+			}
+
+			got := validator.Validate(context.Background(), data)
+
+			if tt.wantReason == "" {
+				if got != nil {
+					t.Fatalf("Validate() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Reason != tt.wantReason {
+				t.Fatalf("Validate() = %v, want reason %q", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDecodeReading(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantVersion int
+		wantReason  string
+		wantPower   float64
+	}{
+		{
+			name:        "legacy payload without schema version",
+			payload:     `{"device_id":"dev-1","power":120.5}`,
+			wantVersion: SchemaVersionLegacy,
+			wantPower:   120.5,
+		},
+		{
+			name:        "current payload",
+			payload:     `{"schema_version":2,"device_id":"dev-1","voltage":220,"current":1,"power":220,"energy":3,"power_factor":1,"frequency":50}`,
+			wantVersion: SchemaVersionCurrent,
+			wantPower:   220,
+		},
+		{
+			name:        "current payload with zero power",
+			payload:     `{"schema_version":2,"device_id":"dev-1","voltage":220,"current":0,"power":0,"energy":3,"power_factor":1,"frequency":50}`,
+			wantVersion: SchemaVersionCurrent,
+			wantPower:   0,
+		},
+		{
+			name:        "current payload missing field",
+			payload:     `{"schema_version":2,"device_id":"dev-1","voltage":220,"current":1,"energy":3,"power_factor":1,"frequency":50}`,
+			wantVersion: SchemaVersionCurrent,
+			wantReason:  entity.QuarantineMalformed,
+		},
+		{
+			name:        "unsupported schema version",
+			payload:     `{"schema_version":9,"device_id":"dev-1"}`,
+			wantVersion: 9,
+			wantReason:  entity.QuarantineUnsupportedSchema,
+		},
+		{
+			name:        "invalid JSON",
+			payload:     `{"device_id":`,
+			wantVersion: 0,
+			wantReason:  entity.QuarantineMalformed,
+		},
+		{
+			name:        "wrong field type",
+			payload:     `{"device_id":"dev-1","power":"high"}`,
+			wantVersion: SchemaVersionLegacy,
+			wantReason:  entity.QuarantineMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, version, got := DecodeReading([]byte(tt.payload))
+
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if tt.wantReason != "" {
+				if got == nil || got.Reason != tt.wantReason {
+					t.Fatalf("violation = %v, want reason %q", got, tt.wantReason)
+				}
+				return
+			}
+			if got != nil {
+				t.Fatalf("violation = %v, want nil", got)
+			}
+			if data.DeviceID != "dev-1" || data.Power != tt.wantPower {
+				t.Errorf("decoded = %+v, want device dev-1 with power %v", data, tt.wantPower)
+			}
+		})
+	}
+}
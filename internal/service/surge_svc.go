@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+	"metertronik/pkg/utils"
+)
+
+var (
+	surgeEventsOpenedTotal = expvar.NewInt("surge_events_opened_total")
+	surgeEventsClosedTotal = expvar.NewInt("surge_events_closed_total")
+)
+
+type surgeState struct {
+	mu     sync.Mutex
+	loaded bool
+	event  *entity.SurgeEvent
+	calm   int
+}
+
+// SurgeDetector membuka surge event saat reading melewati threshold surge
+// policy device dan menutupnya setelah settleReadings reading berturut-turut
+// tanpa surge. Event yang tidak menerima reading selama maxGap ditutup pada
+// reading terakhirnya, baik oleh reading berikutnya maupun oleh sweep cron.
+// State hanya disimpan untuk device yang sedang punya event terbuka.
+type SurgeDetector struct {
+	repo           repository.SurgeEventRepo
+	settleReadings int
+	maxGap         time.Duration
+
+	mu      sync.Mutex
+	devices map[string]*surgeState
+}
+
+func NewSurgeDetector(repo repository.SurgeEventRepo, settleReadings int, maxGap time.Duration) *SurgeDetector {
+	if settleReadings <= 0 {
+		settleReadings = 1
+	}
+
+	return &SurgeDetector{
+		repo:           repo,
+		settleReadings: settleReadings,
+		maxGap:         maxGap,
+		devices:        make(map[string]*surgeState),
+	}
+}
+
+// state mengembalikan nil jika device belum punya state dan create false.
+func (d *SurgeDetector) state(deviceID string, create bool) *surgeState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st, ok := d.devices[deviceID]
+	if !ok && create {
+		st = &surgeState{}
+		d.devices[deviceID] = st
+	}
+	return st
+}
+
+// forget menghapus state device yang tidak lagi punya event terbuka. Goroutine
+// yang masih memegang st akan membaca ulang dari database.
+func (d *SurgeDetector) forget(deviceID string, st *surgeState) {
+	st.loaded = false
+	st.event = nil
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.devices[deviceID] == st {
+		delete(d.devices, deviceID)
+	}
+}
+
+// Observe dipanggil untuk setiap reading berurutan yang punya baseline
+// (previous). Reading tanpa surge diabaikan jika device tidak punya state;
+// event yang terbuka sebelum restart ditutup oleh sweep cron. Kegagalan
+// database hanya dicatat agar ingest tetap berjalan.
+func (d *SurgeDetector) Observe(ctx context.Context, data *entity.RealTimeElectricity, previous *entity.RealTimeElectricity, policy *entity.ThresholdPolicy) {
+	if d == nil || previous == nil {
+		return
+	}
+
+	surge := data.PowerSurge > policy.SurgeWatts || data.PSPercent > policy.SurgePercent
+
+	st := d.state(data.DeviceID, surge)
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	defer func() {
+		if st.event == nil {
+			d.forget(data.DeviceID, st)
+		}
+	}()
+
+	if !st.loaded && !d.load(ctx, st, data.DeviceID) {
+		return
+	}
+
+	if st.event != nil && d.maxGap > 0 && data.CreatedAt.Time.Sub(st.event.LastSampleAt.Time) > d.maxGap {
+		d.close(ctx, st, st.event.LastSampleAt)
+		if !st.loaded && !d.load(ctx, st, data.DeviceID) {
+			return
+		}
+	}
+
+	if st.event != nil && !d.addSample(ctx, st, data, surge) {
+		// Event sudah ditutup instance lain; instance tersebut mungkin
+		// sudah membuka event baru.
+		if !d.load(ctx, st, data.DeviceID) {
+			return
+		}
+		if st.event != nil {
+			d.addSample(ctx, st, data, surge)
+			return
+		}
+	}
+
+	if st.loaded && st.event == nil && surge {
+		d.open(ctx, st, data, previous)
+	}
+}
+
+// addSample mengembalikan false jika event sudah ditutup di database.
+func (d *SurgeDetector) addSample(ctx context.Context, st *surgeState, data *entity.RealTimeElectricity, surge bool) bool {
+	event, err := d.repo.AddSurgeSample(ctx, st.event.ID, data)
+	if err != nil {
+		log.Printf("Failed updating surge event %d: %v", st.event.ID, err)
+		st.loaded = false
+		st.event = nil
+		return true
+	}
+	if event == nil {
+		st.event = nil
+		st.calm = 0
+		return false
+	}
+
+	st.event = event
+	if surge {
+		st.calm = 0
+	} else {
+		st.calm++
+	}
+	if st.calm >= d.settleReadings {
+		d.close(ctx, st, data.CreatedAt)
+	}
+	return true
+}
+
+func (d *SurgeDetector) open(ctx context.Context, st *surgeState, data *entity.RealTimeElectricity, previous *entity.RealTimeElectricity) {
+	event := &entity.SurgeEvent{
+		DeviceID:      data.DeviceID,
+		StartedAt:     data.CreatedAt,
+		BaselinePower: previous.Power,
+		PeakPower:     data.Power,
+		Magnitude:     math.Abs(data.Power - previous.Power),
+		PeakSurge:     data.PowerSurge,
+		PeakPercent:   data.PSPercent,
+		Samples:       1,
+		LastSampleAt:  data.CreatedAt,
+	}
+
+	if err := d.repo.CreateSurgeEvent(ctx, event); err != nil {
+		// Bisa terjadi bila instance lain sudah membuka event untuk device
+		// ini; state dibaca ulang dari database pada reading berikutnya.
+		log.Printf("Failed opening surge event for device %s: %v", data.DeviceID, err)
+		st.loaded = false
+		return
+	}
+
+	st.event = event
+	st.calm = 0
+	surgeEventsOpenedTotal.Add(1)
+	log.Printf("Surge event %d opened for device %s (%.2f W -> %.2f W)", event.ID, event.DeviceID, event.BaselinePower, event.PeakPower)
+}
+
+func (d *SurgeDetector) load(ctx context.Context, st *surgeState, deviceID string) bool {
+	event, err := d.repo.GetOpenSurgeEvent(ctx, deviceID)
+	if err != nil {
+		log.Printf("Failed loading open surge event for device %s: %v", deviceID, err)
+		st.loaded = false
+		return false
+	}
+
+	st.event = event
+	st.loaded = true
+	return true
+}
+
+// close menutup event pada endedAt. Jika event sudah ditutup instance lain
+// atau sudah menerima reading lebih baru, state dibaca ulang dari database.
+func (d *SurgeDetector) close(ctx context.Context, st *surgeState, endedAt utils.TimeData) {
+	event := st.event
+	st.event = nil
+	st.calm = 0
+
+	closed, err := d.repo.CloseSurgeEvent(ctx, event.ID, endedAt)
+	if err != nil {
+		log.Printf("Failed closing surge event %d: %v", event.ID, err)
+		st.loaded = false
+		return
+	}
+	if !closed {
+		st.loaded = false
+		return
+	}
+
+	surgeEventsClosedTotal.Add(1)
+	log.Printf("Surge event %d closed for device %s after %s (magnitude %.2f W)",
+		event.ID, event.DeviceID, endedAt.Time.Sub(event.StartedAt.Time), event.Magnitude)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/pkg/utils"
+)
+
+// fakeSurgeEventRepo meniru UPDATE bersyarat di SurgeEventRepoPostgres.
+type fakeSurgeEventRepo struct {
+	events []*entity.SurgeEvent
+}
+
+func (r *fakeSurgeEventRepo) GetOpenSurgeEvent(ctx context.Context, deviceID string) (*entity.SurgeEvent, error) {
+	for _, event := range r.events {
+		if event.DeviceID == deviceID && event.EndedAt == nil {
+			copied := *event
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSurgeEventRepo) CreateSurgeEvent(ctx context.Context, event *entity.SurgeEvent) error {
+	if open, _ := r.GetOpenSurgeEvent(ctx, event.DeviceID); open != nil {
+		return errors.New("duplicate open surge event")
+	}
+	event.ID = int64(len(r.events) + 1)
+	copied := *event
+	r.events = append(r.events, &copied)
+	return nil
+}
+
+func (r *fakeSurgeEventRepo) AddSurgeSample(ctx context.Context, id int64, data *entity.RealTimeElectricity) (*entity.SurgeEvent, error) {
+	for _, event := range r.events {
+		if event.ID != id || event.EndedAt != nil {
+			continue
+		}
+		if magnitude := math.Abs(data.Power - event.BaselinePower); magnitude > event.Magnitude {
+			event.Magnitude = magnitude
+			event.PeakPower = data.Power
+		}
+		event.PeakSurge = math.Max(event.PeakSurge, data.PowerSurge)
+		event.PeakPercent = math.Max(event.PeakPercent, data.PSPercent)
+		event.Samples++
+		if data.CreatedAt.Time.After(event.LastSampleAt.Time) {
+			event.LastSampleAt = data.CreatedAt
+		}
+		copied := *event
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeSurgeEventRepo) CloseSurgeEvent(ctx context.Context, id int64, endedAt utils.TimeData) (bool, error) {
+	for _, event := range r.events {
+		if event.ID == id && event.EndedAt == nil && !event.LastSampleAt.Time.After(endedAt.Time) {
+			event.EndedAt = &endedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeSurgeEventRepo) CloseStaleSurgeEvents(ctx context.Context, before utils.TimeData) (int64, error) {
+	var closed int64
+	for _, event := range r.events {
+		if event.EndedAt == nil && event.LastSampleAt.Time.Before(before.Time) {
+			ended := event.LastSampleAt
+			event.EndedAt = &ended
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+func (r *fakeSurgeEventRepo) ListSurgeEvents(ctx context.Context, filter entity.SurgeEventFilter) ([]entity.SurgeEvent, error) {
+	return nil, nil
+}
+
+type surgeStep struct {
+	minute int
+	power  float64
+	// sweep menutup semua event terbuka sebelum reading ini, seperti sweep
+	// cron atau instance lain.
+	sweep bool
+}
+
+type wantSurgeEvent struct {
+	startMinute int
+	endMinute   int // -1 berarti masih terbuka
+	samples     int
+	peakPower   float64
+	magnitude   float64
+}
+
+func TestSurgeDetectorObserve(t *testing.T) {
+	policy := &entity.ThresholdPolicy{SurgeWatts: 100, SurgePercent: 1000}
+
+	tests := []struct {
+		name        string
+		steps       []surgeStep
+		wantEvents  []wantSurgeEvent
+		wantTracked int
+	}{
+		{
+			name:       "no surge",
+			steps:      []surgeStep{{0, 400, false}, {1, 420, false}, {2, 390, false}},
+			wantEvents: nil,
+		},
+		{
+			name:        "surge opens event",
+			steps:       []surgeStep{{0, 400, false}, {1, 900, false}},
+			wantEvents:  []wantSurgeEvent{{1, -1, 1, 900, 500}},
+			wantTracked: 1,
+		},
+		{
+			name:       "settles after consecutive calm readings",
+			steps:      []surgeStep{{0, 400, false}, {1, 900, false}, {2, 910, false}, {3, 905, false}},
+			wantEvents: []wantSurgeEvent{{1, 3, 3, 910, 510}},
+		},
+		{
+			name:        "surge resets calm count",
+			steps:       []surgeStep{{0, 400, false}, {1, 900, false}, {2, 910, false}, {3, 300, false}, {4, 310, false}},
+			wantEvents:  []wantSurgeEvent{{1, -1, 4, 910, 510}},
+			wantTracked: 1,
+		},
+		{
+			name:       "peak keeps largest magnitude in either direction",
+			steps:      []surgeStep{{0, 400, false}, {1, 600, false}, {2, 50, false}, {3, 60, false}, {4, 55, false}},
+			wantEvents: []wantSurgeEvent{{1, 4, 4, 50, 350}},
+		},
+		{
+			name:        "gap closes event at last sample and opens new one",
+			steps:       []surgeStep{{0, 400, false}, {1, 900, false}, {20, 200, false}},
+			wantEvents:  []wantSurgeEvent{{1, 1, 1, 900, 500}, {20, -1, 1, 200, 700}},
+			wantTracked: 1,
+		},
+		{
+			name:       "gap followed by calm reading opens nothing",
+			steps:      []surgeStep{{0, 400, false}, {1, 900, false}, {20, 950, false}},
+			wantEvents: []wantSurgeEvent{{1, 1, 1, 900, 500}},
+		},
+		{
+			name:        "event closed elsewhere is reloaded",
+			steps:       []surgeStep{{0, 400, false}, {1, 900, false}, {2, 200, true}},
+			wantEvents:  []wantSurgeEvent{{1, 1, 1, 900, 500}, {2, -1, 1, 200, 700}},
+			wantTracked: 1,
+		},
+		{
+			name:       "calm reading after event closed elsewhere",
+			steps:      []surgeStep{{0, 400, false}, {1, 900, false}, {2, 910, true}},
+			wantEvents: []wantSurgeEvent{{1, 1, 1, 900, 500}},
+		},
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &fakeSurgeEventRepo{}
+			detector := NewSurgeDetector(repo, 2, 10*time.Minute)
+
+			var previous *entity.RealTimeElectricity
+			for _, step := range tt.steps {
+				if step.sweep {
+					if _, err := repo.CloseStaleSurgeEvents(ctx, utils.NewTimeData(base.Add(24*time.Hour))); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				data := &entity.RealTimeElectricity{
+					DeviceID:  "dev-1",
+					Power:     step.power,
+					CreatedAt: utils.NewTimeData(base.Add(time.Duration(step.minute) * time.Minute)),
+				}
+				if previous != nil {
+					data.PowerSurge = math.Abs(data.Power - previous.Power)
+				}
+
+				detector.Observe(ctx, data, previous, policy)
+				previous = data
+			}
+
+			if len(repo.events) != len(tt.wantEvents) {
+				t.Fatalf("got %d event(s), want %d", len(repo.events), len(tt.wantEvents))
+			}
+			for i, want := range tt.wantEvents {
+				got := repo.events[i]
+
+				if !got.StartedAt.Time.Equal(base.Add(time.Duration(want.startMinute) * time.Minute)) {
+					t.Errorf("event %d started at %s, want minute %d", i, got.StartedAt.Time, want.startMinute)
+				}
+				switch {
+				case want.endMinute < 0 && got.EndedAt != nil:
+					t.Errorf("event %d ended at %s, want open", i, got.EndedAt.Time)
+				case want.endMinute >= 0 && got.EndedAt == nil:
+					t.Errorf("event %d is open, want ended at minute %d", i, want.endMinute)
+				case want.endMinute >= 0 && !got.EndedAt.Time.Equal(base.Add(time.Duration(want.endMinute)*time.Minute)):
+					t.Errorf("event %d ended at %s, want minute %d", i, got.EndedAt.Time, want.endMinute)
+				}
+				if got.Samples != want.samples {
+					t.Errorf("event %d samples = %d, want %d", i, got.Samples, want.samples)
+				}
+				if got.PeakPower != want.peakPower || got.Magnitude != want.magnitude {
+					t.Errorf("event %d peak = %.0f W (magnitude %.0f), want %.0f W (magnitude %.0f)",
+						i, got.PeakPower, got.Magnitude, want.peakPower, want.magnitude)
+				}
+			}
+
+			if len(detector.devices) != tt.wantTracked {
+				t.Errorf("tracked devices = %d, want %d", len(detector.devices), tt.wantTracked)
+			}
+		})
+	}
+}
+
+func TestSurgeDetectorSkipsWithoutBaseline(t *testing.T) {
+	repo := &fakeSurgeEventRepo{}
+	detector := NewSurgeDetector(repo, 2, 10*time.Minute)
+
+	data := &entity.RealTimeElectricity{DeviceID: "dev-1", Power: 5000, PowerSurge: 5000}
+	detector.Observe(context.Background(), data, nil, entity.DefaultThresholdPolicy())
+
+	if len(repo.events) != 0 || len(detector.devices) != 0 {
+		t.Fatalf("events = %d, tracked = %d, want none", len(repo.events), len(detector.devices))
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"metertronik/internal/domain/entity"
+	"metertronik/internal/domain/repository"
+)
+
+const defaultThresholdReload = 30 * time.Second
+
+type policyKey struct {
+	ownerID    int64
+	deviceType string
+}
+
+// ThresholdPolicyCache menyimpan semua threshold policy di memori ingestor
+// dan memuat ulang dari Postgres setiap reload, sehingga perubahan lewat API
+// berlaku tanpa restart.
+type ThresholdPolicyCache struct {
+	repo   repository.ThresholdPolicyRepo
+	reload time.Duration
+
+	mu       sync.Mutex
+	byID     map[int64]*entity.ThresholdPolicy
+	byType   map[policyKey]*entity.ThresholdPolicy
+	loadedAt time.Time
+	loading  bool
+}
+
+func NewThresholdPolicyCache(repo repository.ThresholdPolicyRepo, reload time.Duration) *ThresholdPolicyCache {
+	if reload <= 0 {
+		reload = defaultThresholdReload
+	}
+
+	return &ThresholdPolicyCache{
+		repo:   repo,
+		reload: reload,
+	}
+}
+
+// Resolve memilih policy untuk device dengan urutan: policy yang dipasang
+// di device, policy pemilik untuk tipe device, policy global untuk tipe
+// device, policy global default, lalu entity.DefaultThresholdPolicy.
+func (c *ThresholdPolicyCache) Resolve(ctx context.Context, device *entity.Device) *entity.ThresholdPolicy {
+	if c == nil || device == nil {
+		return entity.DefaultThresholdPolicy()
+	}
+
+	byID, byType := c.snapshot(ctx)
+
+	if device.ThresholdPolicyID != nil {
+		if policy, ok := byID[*device.ThresholdPolicyID]; ok {
+			return policy
+		}
+	}
+
+	if device.DeviceType != "" {
+		if device.OwnerID != nil {
+			if policy, ok := byType[policyKey{*device.OwnerID, device.DeviceType}]; ok {
+				return policy
+			}
+		}
+		if policy, ok := byType[policyKey{0, device.DeviceType}]; ok {
+			return policy
+		}
+	}
+
+	if policy, ok := byType[policyKey{0, ""}]; ok {
+		return policy
+	}
+
+	return entity.DefaultThresholdPolicy()
+}
+
+// snapshot mengembalikan map policy saat ini. Jika sudah waktunya reload,
+// satu pemanggil memuat ulang dari Postgres tanpa memegang lock; pemanggil
+// lain tetap memakai map lama selama reload berjalan.
+func (c *ThresholdPolicyCache) snapshot(ctx context.Context) (map[int64]*entity.ThresholdPolicy, map[policyKey]*entity.ThresholdPolicy) {
+	c.mu.Lock()
+	stale := c.repo != nil && !c.loading && (c.byID == nil || time.Since(c.loadedAt) >= c.reload)
+	if stale {
+		c.loading = true
+	}
+	byID, byType := c.byID, c.byType
+	c.mu.Unlock()
+
+	if !stale {
+		return byID, byType
+	}
+
+	byID, byType, err := c.load(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loading = false
+	c.loadedAt = time.Now()
+	if err != nil {
+		log.Printf("Failed to reload threshold policies, using cached/default policies: %v", err)
+		return c.byID, c.byType
+	}
+
+	c.byID = byID
+	c.byType = byType
+	return byID, byType
+}
+
+func (c *ThresholdPolicyCache) load(ctx context.Context) (map[int64]*entity.ThresholdPolicy, map[policyKey]*entity.ThresholdPolicy, error) {
+	policies, err := c.repo.ListThresholdPolicies(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[int64]*entity.ThresholdPolicy, len(policies))
+	byType := make(map[policyKey]*entity.ThresholdPolicy)
+
+	for i := range policies {
+		policy := &policies[i]
+		byID[policy.ID] = policy
+
+		owner := int64(0)
+		if policy.OwnerID != nil {
+			owner = *policy.OwnerID
+		}
+		// Policy milik user tanpa tipe hanya berlaku bila dipasang langsung.
+		if policy.DeviceType == "" && owner != 0 {
+			continue
+		}
+		byType[policyKey{owner, policy.DeviceType}] = policy
+	}
+
+	return byID, byType, nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -15,6 +16,11 @@ type Config struct {
 	InfluxOrg    string
 	InfluxBucket string
 
+	InfluxBatchSize     int
+	InfluxBufferSize    int
+	InfluxFlushInterval time.Duration
+	InfluxSpillPath     string
+
 	RedisAddr     string
 	RedisPassword string
 	RedisDB       int
@@ -26,6 +32,11 @@ type Config struct {
 	RabbitMQPrefetchCount int
 	RabbitMQRetryDelay    time.Duration
 
+	RabbitMQMaxRetries         int
+	RabbitMQRetryTTL           time.Duration
+	RabbitMQDeadLetterExchange string
+	RabbitMQDeadLetterQueue    string
+
 	Port    string
 	GinMode string
 
@@ -40,11 +51,56 @@ type Config struct {
 	CORSAllowMethods []string
 	CORSAllowHeaders []string
 
+	TrustedProxies []string
+
 	CronHourlyInterval time.Duration
 	CronDailyInterval  time.Duration
+	CronCatchUpWindow  time.Duration
+	CronMaxAttempts    int
+	CronLeaderTTL      time.Duration
+	CronInstanceID     string
+	CronMetricsAddr    string
+
+	PartitionAheadYears     int
+	PartitionRetentionYears int
+	PartitionRetentionDrop  bool
+
+	DefaultTimezone string
+
+	RealtimeHistoryRetention time.Duration
+	IngestDedupTTL           time.Duration
+	IngestClockMaxAhead      time.Duration
+	IngestClockMaxBehind     time.Duration
+	IngestPolicyReload       time.Duration
+	SurgeSettleReadings      int
+	SurgeMaxGap              time.Duration
+	WSHistoryWindow          time.Duration
+	WSAccessRecheck          time.Duration
 
 	ConsumerLogInterval time.Duration
 
+	IngestRequireSignature bool
+	IngestKeyEncryptionKey string
+	IngestKeyGrace         time.Duration
+	IngestMetricsAddr      string
+
+	IngestHTTPAddr         string
+	IngestHTTPMaxBodyBytes int64
+	IngestHTTPMaxBatch     int
+
+	IngestTransport string
+
+	MQTTBrokerURL      string
+	MQTTClientID       string
+	MQTTUsername       string
+	MQTTPassword       string
+	MQTTTopics         []string
+	MQTTCleanSession   bool
+	MQTTMaxRetries     int
+	MQTTMaxInflight    int
+	MQTTRetryDelay     time.Duration
+	MQTTReconnectDelay time.Duration
+
 	SendgridAPIKey string
 	SendgridFromEmail string
 	SendgridFromName string
@@ -53,12 +109,46 @@ type Config struct {
 func Load() (*Config, error) {
 	_ = godotenv.Load(".env")
 
+	influxBatchSize, _ := strconv.Atoi(getEnv("INFLUX_BATCH_SIZE", "500"))
+	influxBufferSize, _ := strconv.Atoi(getEnv("INFLUX_BUFFER_SIZE", "5000"))
+	influxFlushIntervalMs, _ := strconv.Atoi(getEnv("INFLUX_FLUSH_INTERVAL_MS", "1000"))
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
-	rabbitMQPrefetchCount, _ := strconv.Atoi(getEnv("RABBITMQ_PREFETCH_COUNT", "50"))
+	rabbitMQPrefetchCount, _ := strconv.Atoi(getEnv("RABBITMQ_PREFETCH_COUNT", "500"))
 	rabbitMQRetryDelaySeconds, _ := strconv.Atoi(getEnv("RABBITMQ_RETRY_DELAY_SECONDS", "5"))
+	rabbitMQMaxRetries, _ := strconv.Atoi(getEnv("RABBITMQ_MAX_RETRIES", "5"))
+	rabbitMQRetryTTLSeconds, _ := strconv.Atoi(getEnv("RABBITMQ_RETRY_TTL_SECONDS", "10"))
 	cronHourlyIntervalHours, _ := strconv.Atoi(getEnv("CRON_HOURLY_INTERVAL_HOURS", "1"))
 	cronDailyIntervalHours, _ := strconv.Atoi(getEnv("CRON_DAILY_INTERVAL_HOURS", "24"))
+	cronCatchUpHours, _ := strconv.Atoi(getEnv("CRON_CATCHUP_HOURS", "72"))
+	cronMaxAttempts, _ := strconv.Atoi(getEnv("CRON_MAX_ATTEMPTS", "5"))
+	cronLeaderTTLSeconds, _ := strconv.Atoi(getEnv("CRON_LEADER_TTL_SECONDS", "30"))
+	realtimeHistoryRetentionMinutes, _ := strconv.Atoi(getEnv("REALTIME_HISTORY_RETENTION_MINUTES", "60"))
+	ingestDedupTTLHours, _ := strconv.Atoi(getEnv("INGEST_DEDUP_TTL_HOURS", "24"))
+	ingestClockMaxAheadSeconds, _ := strconv.Atoi(getEnv("INGEST_CLOCK_MAX_AHEAD_SECONDS", "300"))
+	ingestClockMaxBehindHours, _ := strconv.Atoi(getEnv("INGEST_CLOCK_MAX_BEHIND_HOURS", "72"))
+	ingestPolicyReloadSeconds, _ := strconv.Atoi(getEnv("INGEST_POLICY_RELOAD_SECONDS", "30"))
+	surgeSettleReadings, _ := strconv.Atoi(getEnv("SURGE_SETTLE_READINGS", "2"))
+	surgeMaxGapMinutes, _ := strconv.Atoi(getEnv("SURGE_MAX_GAP_MINUTES", "10"))
+	wsHistoryWindowMinutes, _ := strconv.Atoi(getEnv("WS_HISTORY_WINDOW_MINUTES", "15"))
+	wsAccessRecheckSeconds, _ := strconv.Atoi(getEnv("WS_ACCESS_RECHECK_SECONDS", "30"))
+	partitionAheadYears, _ := strconv.Atoi(getEnv("PARTITION_AHEAD_YEARS", "1"))
+	partitionRetentionYears, _ := strconv.Atoi(getEnv("PARTITION_RETENTION_YEARS", "0"))
+	partitionRetentionDrop, _ := strconv.ParseBool(getEnv("PARTITION_RETENTION_DROP", "false"))
 	consumerLogIntervalSeconds, _ := strconv.Atoi(getEnv("CONSUMER_LOG_INTERVAL_SECONDS", "10"))
+	ingestRequireSignature, _ := strconv.ParseBool(getEnv("INGEST_REQUIRE_SIGNATURE", "true"))
+	ingestKeyGraceHours, _ := strconv.Atoi(getEnv("INGEST_KEY_GRACE_HOURS", "24"))
+	ingestHTTPMaxBodyBytes, _ := strconv.ParseInt(getEnv("INGEST_HTTP_MAX_BODY_BYTES", "65536"), 10, 64)
+	ingestHTTPMaxBatch, _ := strconv.Atoi(getEnv("INGEST_HTTP_MAX_BATCH", "500"))
+	mqttCleanSession, _ := strconv.ParseBool(getEnv("MQTT_CLEAN_SESSION", "false"))
+	mqttMaxRetries, _ := strconv.Atoi(getEnv("MQTT_MAX_RETRIES", "3"))
+	mqttMaxInflight, _ := strconv.Atoi(getEnv("MQTT_MAX_INFLIGHT", "500"))
+	mqttRetryDelaySeconds, _ := strconv.Atoi(getEnv("MQTT_RETRY_DELAY_SECONDS", "2"))
+	mqttReconnectDelaySeconds, _ := strconv.Atoi(getEnv("MQTT_RECONNECT_DELAY_SECONDS", "5"))
+
+	defaultTimezone := getEnv("DEFAULT_TIMEZONE", "UTC")
+	if _, err := time.LoadLocation(defaultTimezone); err != nil {
+		return nil, fmt.Errorf("invalid DEFAULT_TIMEZONE %q: %w", defaultTimezone, err)
+	}
 
 	return &Config{
 		InfluxURL:    getEnv("INFLUX_URL", ""),
@@ -66,6 +156,11 @@ func Load() (*Config, error) {
 		InfluxOrg:    getEnv("INFLUX_ORG", ""),
 		InfluxBucket: getEnv("INFLUX_BUCKET", ""),
 
+		InfluxBatchSize:     influxBatchSize,
+		InfluxBufferSize:    influxBufferSize,
+		InfluxFlushInterval: time.Duration(influxFlushIntervalMs) * time.Millisecond,
+		InfluxSpillPath:     getEnv("INFLUX_SPILL_PATH", "data/influx-spill.lp"),
+
 		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       redisDB,
@@ -77,6 +172,11 @@ func Load() (*Config, error) {
 		RabbitMQPrefetchCount: rabbitMQPrefetchCount,
 		RabbitMQRetryDelay:    time.Duration(rabbitMQRetryDelaySeconds) * time.Second,
 
+		RabbitMQMaxRetries:         rabbitMQMaxRetries,
+		RabbitMQRetryTTL:           time.Duration(rabbitMQRetryTTLSeconds) * time.Second,
+		RabbitMQDeadLetterExchange: getEnv("RABBITMQ_DLX", "electricity_dlx"),
+		RabbitMQDeadLetterQueue:    getEnv("RABBITMQ_DLQ", "electricity_queue.dlq"),
+
 		Port:    getEnv("PORT", "8080"),
 		GinMode: getEnv("GIN_MODE", "debug"),
 
@@ -91,11 +191,56 @@ func Load() (*Config, error) {
 		CORSAllowMethods: parseStringSlice(getEnv("CORS_ALLOW_METHODS", "GET,POST,PUT,DELETE,OPTIONS")),
 		CORSAllowHeaders: parseStringSlice(getEnv("CORS_ALLOW_HEADERS", "Content-Type,Authorization")),
 
+		TrustedProxies: parseStringSlice(getEnv("TRUSTED_PROXIES", "")),
+
 		CronHourlyInterval: time.Duration(cronHourlyIntervalHours) * time.Hour,
 		CronDailyInterval:  time.Duration(cronDailyIntervalHours) * time.Hour,
+		CronCatchUpWindow:  time.Duration(cronCatchUpHours) * time.Hour,
+		CronMaxAttempts:    cronMaxAttempts,
+		CronLeaderTTL:      time.Duration(cronLeaderTTLSeconds) * time.Second,
+		CronInstanceID:     getEnv("CRON_INSTANCE_ID", ""),
+		CronMetricsAddr:    getEnv("CRON_METRICS_ADDR", ""),
+
+		PartitionAheadYears:     partitionAheadYears,
+		PartitionRetentionYears: partitionRetentionYears,
+		PartitionRetentionDrop:  partitionRetentionDrop,
+
+		DefaultTimezone: defaultTimezone,
+
+		RealtimeHistoryRetention: time.Duration(realtimeHistoryRetentionMinutes) * time.Minute,
+		IngestDedupTTL:           time.Duration(ingestDedupTTLHours) * time.Hour,
+		IngestClockMaxAhead:      time.Duration(ingestClockMaxAheadSeconds) * time.Second,
+		IngestClockMaxBehind:     time.Duration(ingestClockMaxBehindHours) * time.Hour,
+		IngestPolicyReload:       time.Duration(ingestPolicyReloadSeconds) * time.Second,
+		SurgeSettleReadings:      surgeSettleReadings,
+		SurgeMaxGap:              time.Duration(surgeMaxGapMinutes) * time.Minute,
+		WSHistoryWindow:          time.Duration(wsHistoryWindowMinutes) * time.Minute,
+		WSAccessRecheck:          time.Duration(wsAccessRecheckSeconds) * time.Second,
 
 		ConsumerLogInterval: time.Duration(consumerLogIntervalSeconds) * time.Second,
 
+		IngestRequireSignature: ingestRequireSignature,
+		IngestKeyEncryptionKey: getEnv("INGEST_KEY_ENCRYPTION_KEY", ""),
+		IngestKeyGrace:         time.Duration(ingestKeyGraceHours) * time.Hour,
+		IngestMetricsAddr:      getEnv("INGEST_METRICS_ADDR", ""),
+
+		IngestHTTPAddr:         getEnv("INGEST_HTTP_ADDR", ""),
+		IngestHTTPMaxBodyBytes: ingestHTTPMaxBodyBytes,
+		IngestHTTPMaxBatch:     ingestHTTPMaxBatch,
+
+		IngestTransport: getEnv("INGEST_TRANSPORT", "amqp"),
+
+		MQTTBrokerURL:      getEnv("MQTT_BROKER_URL", "tcp://localhost:1883"),
+		MQTTClientID:       getEnv("MQTT_CLIENT_ID", ""),
+		MQTTUsername:       getEnv("MQTT_USERNAME", ""),
+		MQTTPassword:       getEnv("MQTT_PASSWORD", ""),
+		MQTTTopics:         parseStringSlice(getEnv("MQTT_TOPICS", "metertronik/{device_id}/electricity")),
+		MQTTCleanSession:   mqttCleanSession,
+		MQTTMaxRetries:     mqttMaxRetries,
+		MQTTMaxInflight:    mqttMaxInflight,
+		MQTTRetryDelay:     time.Duration(mqttRetryDelaySeconds) * time.Second,
+		MQTTReconnectDelay: time.Duration(mqttReconnectDelaySeconds) * time.Second,
+
 		SendgridAPIKey: getEnv("SENDGRID_API_KEY", ""),
 		SendgridFromEmail: getEnv("SENDGRID_FROM_EMAIL", ""),
 		SendgridFromName: getEnv("SENDGRID_FROM_NAME", ""),
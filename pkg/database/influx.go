@@ -18,10 +18,33 @@ func SetupInfluxDB(cfg *config.Config) (repository.InfluxRepo, func()) {
 		log.Fatalf("InfluxDB health check failed: %v", err)
 	}
 
-	repo := repoInflux.NewElectricityRepo(client, cfg.InfluxOrg, cfg.InfluxBucket)
+	writer := repoInflux.NewBatchWriter(client.WriteAPIBlocking(cfg.InfluxOrg, cfg.InfluxBucket), repoInflux.BatchWriterConfig{
+		BatchSize:     cfg.InfluxBatchSize,
+		BufferSize:    cfg.InfluxBufferSize,
+		FlushInterval: cfg.InfluxFlushInterval,
+		SpillPath:     cfg.InfluxSpillPath,
+	})
+
+	repo := repoInflux.NewElectricityRepo(client, cfg.InfluxOrg, cfg.InfluxBucket, writer)
 	cleanup := func() {
+		writer.Close()
 		client.Close()
 	}
 
 	return repo, cleanup
 }
+
+// SetupInfluxReader membuat repo InfluxDB tanpa batch writer, untuk proses
+// yang hanya membaca (API, cron) sehingga tidak ikut memutar ulang atau
+// menghapus spill file milik ingestor.
+func SetupInfluxReader(cfg *config.Config) (repository.InfluxRepo, func()) {
+	client := influxdb2.NewClient(cfg.InfluxURL, cfg.InfluxToken)
+
+	if _, err := client.Health(context.Background()); err != nil {
+		log.Fatalf("InfluxDB health check failed: %v", err)
+	}
+
+	repo := repoInflux.NewElectricityRepo(client, cfg.InfluxOrg, cfg.InfluxBucket, nil)
+
+	return repo, client.Close
+}
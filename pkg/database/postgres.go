@@ -14,20 +14,31 @@ import (
 
 var DB *gorm.DB
 
-func SetupPostgres(cfg *config.Config) (repository.PostgresRepo, repository.UsersRepoPostgres, func()) {
+func connectPostgres(cfg *config.Config) *gorm.DB {
+	if DB != nil {
+		return DB
+	}
 
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.PGHOST, cfg.PGPORT, cfg.PGUSER, cfg.PGPASSWORD, cfg.PGDATABASE, cfg.PGSSLMODE,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError mengubah pelanggaran unique index menjadi
+	// gorm.ErrDuplicatedKey agar service bisa membalas 409.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
 	DB = db
 
+	return db
+}
+
+func SetupPostgres(cfg *config.Config) (repository.PostgresRepo, repository.UsersRepoPostgres, func()) {
+	db := connectPostgres(cfg)
+
 	electricityRepo := repoPostgres.NewElectricityRepoPostgres(db)
 	usersRepo := repoPostgres.NewUsersRepoPostgres(db)
 
@@ -40,7 +51,54 @@ func SetupPostgres(cfg *config.Config) (repository.PostgresRepo, repository.User
 		if err := sqlDB.Close(); err != nil {
 			log.Printf("Error closing database connection: %v", err)
 		}
+		DB = nil
 	}
 
 	return electricityRepo, usersRepo, cleanup
 }
+
+// SetupPartitionRepo memakai koneksi yang sama dengan SetupPostgres,
+// sehingga koneksi tersebut ditutup oleh cleanup dari SetupPostgres.
+func SetupPartitionRepo(cfg *config.Config) repository.PartitionRepo {
+	return repoPostgres.NewPartitionRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupAggregationJobRepo(cfg *config.Config) repository.AggregationJobRepo {
+	return repoPostgres.NewAggregationJobRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupDeviceRepo(cfg *config.Config) repository.DeviceRepo {
+	return repoPostgres.NewDeviceRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupDeviceMemberRepo(cfg *config.Config) repository.DeviceMemberRepo {
+	return repoPostgres.NewDeviceMemberRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupAuditRepo(cfg *config.Config) repository.AuditRepo {
+	return repoPostgres.NewAuditRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupDeviceCredentialRepo(cfg *config.Config) repository.DeviceCredentialRepo {
+	return repoPostgres.NewDeviceCredentialRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupDeviceIngestKeyRepo(cfg *config.Config) repository.DeviceIngestKeyRepo {
+	return repoPostgres.NewDeviceIngestKeyRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupReadingRuleRepo(cfg *config.Config) repository.ReadingRuleRepo {
+	return repoPostgres.NewReadingRuleRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupQuarantineRepo(cfg *config.Config) repository.QuarantineRepo {
+	return repoPostgres.NewQuarantineRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupThresholdPolicyRepo(cfg *config.Config) repository.ThresholdPolicyRepo {
+	return repoPostgres.NewThresholdPolicyRepoPostgres(connectPostgres(cfg))
+}
+
+func SetupSurgeEventRepo(cfg *config.Config) repository.SurgeEventRepo {
+	return repoPostgres.NewSurgeEventRepoPostgres(connectPostgres(cfg))
+}
@@ -60,3 +60,54 @@ func SetupRedisRealtimeBatch(cfg *config.Config) (repository.RedisBatchRepo, fun
 
 	return RedisBatchRepo, cleanup
 }
+
+func SetupRedisElectricitySubscriber(cfg *config.Config) (repository.ElectricitySubscriber, func()) {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("Warning: Redis Stream is not available: %v. Realtime streaming will be disabled.", err)
+		client.Close()
+		return nil, func() {}
+	}
+
+	log.Println("Redis Stream connected successfully")
+	subscriber := repoRedis.NewElectricitySubscriberRedis(ctx, client)
+
+	cleanup := func() {
+		subscriber.Close()
+		client.Close()
+	}
+
+	return subscriber, cleanup
+}
+
+func SetupRedisIngestKeyRevocation(cfg *config.Config) (repository.IngestKeyRevocationRepo, func()) {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("Warning: Redis is not available: %v. Ingest key revocations will only apply after the key cache expires.", err)
+		client.Close()
+		return nil, func() {}
+	}
+
+	log.Println("Redis ingest key revocation connected successfully")
+	revocations := repoRedis.NewIngestKeyRevocationRedis(client)
+
+	cleanup := func() {
+		client.Close()
+	}
+
+	return revocations, cleanup
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"log"
+
+	"metertronik/internal/domain/repository"
+	repoRedis "metertronik/internal/repository/redis/cron"
+	"metertronik/pkg/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const cronLeaderKey = "cron:leader"
+
+// SetupRedisLeaderLease selalu mengembalikan lease. Jika Redis belum bisa
+// dijangkau, elector gagal mengambil lease dan job tetap diam sampai Redis
+// kembali, sehingga tidak ada replika yang berjalan tanpa lease.
+func SetupRedisLeaderLease(cfg *config.Config) (repository.LeaderLease, func()) {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("Warning: Redis Lease is not available: %v. Aggregation stays on standby until a lease is held.", err)
+	} else {
+		log.Println("Redis Lease connected successfully")
+	}
+
+	leaderLease := repoRedis.NewLeaderLeaseRedis(client, cronLeaderKey)
+
+	cleanup := func() {
+		client.Close()
+	}
+
+	return leaderLease, cleanup
+}
@@ -8,6 +8,8 @@ CREATE TABLE IF NOT EXISTS hourly_data (
     avg_power     DECIMAL(10,2),
     min_power     DECIMAL(10,2),
     max_power     DECIMAL(10,2),
+    coverage_percent DECIMAL(5,2),
+    sample_count  INTEGER,
     created_at    TIMESTAMPTZ DEFAULT NOW(),
 
     PRIMARY KEY (device_id, ts)
@@ -23,11 +25,15 @@ CREATE TABLE IF NOT EXISTS daily_data (
     avg_power    DECIMAL(10,2),
     min_power    DECIMAL(10,2),
     max_power    DECIMAL(10,2),
+    coverage_percent DECIMAL(5,2),
+    sample_count INTEGER,
     created_at   TIMESTAMPTZ DEFAULT NOW(),
 
     PRIMARY KEY (device_id, day)
 ) PARTITION BY RANGE (day);
 
+-- Partisi tahun-tahun berikutnya dibuat oleh `cron partitions ensure`
+-- (juga dijalankan otomatis saat cron start dan setiap hari).
 CREATE TABLE hourly_data_2025
 PARTITION OF hourly_data
 FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
@@ -71,4 +77,206 @@ PARTITION OF monthly_data
 FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
 
 CREATE INDEX idx_monthly_2025_device ON monthly_data_2025(device_id);
-CREATE INDEX idx_monthly_2025_month ON monthly_data_2025(month);
\ No newline at end of file
+CREATE INDEX idx_monthly_2025_month ON monthly_data_2025(month);
+
+CREATE TABLE IF NOT EXISTS aggregation_jobs (
+    job_kind     VARCHAR(20) NOT NULL,
+    device_id    VARCHAR(50) NOT NULL,
+    period       TIMESTAMPTZ NOT NULL,
+    status       VARCHAR(20) NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    error        TEXT,
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (job_kind, device_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_aggregation_jobs_status ON aggregation_jobs(status);
+
+CREATE TABLE IF NOT EXISTS yearly_data (
+    device_id    VARCHAR(50) NOT NULL,
+    year         DATE NOT NULL,
+    energy       DECIMAL(12,3) NOT NULL,
+    total_cost   DECIMAL(17,2) NOT NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (device_id, year)
+);
+
+ALTER TABLE hourly_data ADD COLUMN IF NOT EXISTS coverage_percent DECIMAL(5,2);
+ALTER TABLE hourly_data ADD COLUMN IF NOT EXISTS sample_count INTEGER;
+ALTER TABLE daily_data ADD COLUMN IF NOT EXISTS coverage_percent DECIMAL(5,2);
+ALTER TABLE daily_data ADD COLUMN IF NOT EXISTS sample_count INTEGER;
+
+CREATE TABLE IF NOT EXISTS devices (
+    id                   BIGSERIAL PRIMARY KEY,
+    device_id            VARCHAR(64) NOT NULL UNIQUE,
+    device_name          VARCHAR(100) NOT NULL DEFAULT '',
+    device_type          VARCHAR(50) NOT NULL DEFAULT '',
+    device_status        VARCHAR(20) NOT NULL DEFAULT 'active',
+    device_location      VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- delta: setiap pesan membawa energi sejak pesan sebelumnya
+    -- cumulative: pesan membawa register kWh kumulatif (mis. PZEM)
+    energy_mode          VARCHAR(20) NOT NULL DEFAULT 'delta',
+    energy_register_max  DECIMAL(15,3) NOT NULL DEFAULT 0,
+
+    device_created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Zona waktu IANA (mis. Asia/Jakarta) untuk batas hari/bulan lokal;
+-- kosong berarti memakai DEFAULT_TIMEZONE.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Zona waktu yang dipakai saat baris agregat dibangun. Kolom day/month/year
+-- adalah tanggal kalender lokal di zona tersebut.
+ALTER TABLE daily_data ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE monthly_data ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE yearly_data ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Pemilik device (users.id); device tanpa pemilik tidak bisa di-stream.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS owner_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_devices_owner ON devices(owner_id);
+
+-- Golongan tarif (tarrifs.type_tarrif) untuk biaya per device; kosong berarti
+-- memakai tarif yang berlaku saat ini.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS tariff_class VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS device_updated_at TIMESTAMPTZ DEFAULT NOW();
+CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(device_status);
+
+-- Batas ukuran body ingest HTTP per device (byte); 0 memakai default config.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS ingest_max_body_bytes INTEGER NOT NULL DEFAULT 0;
+
+-- User lain yang diberi akses baca ke device oleh pemiliknya.
+CREATE TABLE IF NOT EXISTS device_members (
+    id          BIGSERIAL PRIMARY KEY,
+    device_id   VARCHAR(64) NOT NULL REFERENCES devices(device_id) ON DELETE CASCADE,
+    user_id     BIGINT NOT NULL,
+    role        VARCHAR(20) NOT NULL DEFAULT 'viewer',
+    created_at  TIMESTAMPTZ DEFAULT NOW(),
+
+    UNIQUE (device_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_members_user ON device_members(user_id);
+
+-- Secret klaim per device yang dibuat saat provisioning (cron provision).
+CREATE TABLE IF NOT EXISTS device_credentials (
+    device_id          VARCHAR(64) PRIMARY KEY REFERENCES devices(device_id) ON DELETE CASCADE,
+    claim_secret_hash  VARCHAR(100) NOT NULL,
+    provisioned_at     TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Kunci HMAC per device untuk menandatangani pesan ingest. expires_at NULL
+-- berarti kunci aktif; kunci lama diberi masa tenggang setelah rotasi.
+CREATE TABLE IF NOT EXISTS device_ingest_keys (
+    id                 BIGSERIAL PRIMARY KEY,
+    device_id          VARCHAR(64) NOT NULL REFERENCES devices(device_id) ON DELETE CASCADE,
+    key_id             INTEGER NOT NULL,
+    secret_ciphertext  TEXT NOT NULL,
+    created_at         TIMESTAMPTZ DEFAULT NOW(),
+    expires_at         TIMESTAMPTZ,
+
+    UNIQUE (device_id, key_id)
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL,
+    action      VARCHAR(50) NOT NULL,
+    device_id   VARCHAR(64),
+    resource    VARCHAR(255),
+    ip_address  VARCHAR(64),
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_time ON audit_logs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_device_time ON audit_logs(device_id, created_at DESC);
+
+-- Batas fisik reading per tipe device (device_type kosong = semua tipe).
+-- min_value/max_value NULL berarti tanpa batas di sisi tersebut.
+CREATE TABLE IF NOT EXISTS reading_rules (
+    device_type  VARCHAR(50) NOT NULL DEFAULT '',
+    field        VARCHAR(30) NOT NULL,
+    min_value    DOUBLE PRECISION,
+    max_value    DOUBLE PRECISION,
+
+    PRIMARY KEY (device_type, field)
+);
+
+INSERT INTO reading_rules (device_type, field, min_value, max_value) VALUES
+    ('', 'voltage', 0, 500),
+    ('', 'current', 0, 200),
+    ('', 'power', 0, 100000),
+    ('', 'energy', 0, NULL),
+    ('', 'power_factor', 0, 1),
+    ('', 'frequency', 45, 65)
+ON CONFLICT (device_type, field) DO NOTHING;
+
+-- Reading yang ditolak validasi; tidak masuk InfluxDB maupun agregasi.
+CREATE TABLE IF NOT EXISTS quarantined_readings (
+    id              BIGSERIAL PRIMARY KEY,
+    device_id       VARCHAR(64) NOT NULL DEFAULT '',
+    schema_version  INTEGER NOT NULL DEFAULT 0,
+    reason          VARCHAR(30) NOT NULL,
+    detail          TEXT,
+    payload         TEXT NOT NULL,
+    received_at     TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantined_readings_device_time ON quarantined_readings(device_id, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_quarantined_readings_reason_time ON quarantined_readings(reason, received_at DESC);
+
+-- Policy sensitivitas deteksi perubahan dan surge. owner_id NULL berarti
+-- policy global; device_type kosong berarti hanya dipakai bila dipasang
+-- langsung ke device (kecuali policy global tanpa tipe = default semua device).
+CREATE TABLE IF NOT EXISTS threshold_policies (
+    id                    BIGSERIAL PRIMARY KEY,
+    owner_id              BIGINT,
+    name                  VARCHAR(100) NOT NULL,
+    device_type           VARCHAR(50) NOT NULL DEFAULT '',
+    voltage_percent       DOUBLE PRECISION NOT NULL DEFAULT 10,
+    current_percent       DOUBLE PRECISION NOT NULL DEFAULT 10,
+    power_percent         DOUBLE PRECISION NOT NULL DEFAULT 10,
+    energy_percent        DOUBLE PRECISION NOT NULL DEFAULT 10,
+    power_factor_percent  DOUBLE PRECISION NOT NULL DEFAULT 10,
+    frequency_percent     DOUBLE PRECISION NOT NULL DEFAULT 10,
+    surge_watts           DOUBLE PRECISION NOT NULL DEFAULT 500,
+    surge_percent         DOUBLE PRECISION NOT NULL DEFAULT 15,
+    surge_baseline_watts  DOUBLE PRECISION NOT NULL DEFAULT 50,
+    created_at            TIMESTAMPTZ DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Satu policy per tipe device untuk tiap pemilik (dan satu policy global per tipe).
+CREATE UNIQUE INDEX IF NOT EXISTS idx_threshold_policies_owner_type
+    ON threshold_policies (COALESCE(owner_id, 0), device_type)
+    WHERE device_type <> '' OR owner_id IS NULL;
+
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS threshold_policy_id BIGINT REFERENCES threshold_policies(id) ON DELETE SET NULL;
+
+-- Event lonjakan daya per device. ended_at NULL berarti event masih terbuka;
+-- paling banyak satu event terbuka per device.
+CREATE TABLE IF NOT EXISTS surge_events (
+    id              BIGSERIAL PRIMARY KEY,
+    device_id       VARCHAR(64) NOT NULL,
+    started_at      TIMESTAMPTZ NOT NULL,
+    ended_at        TIMESTAMPTZ,
+    baseline_power  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    peak_power      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    magnitude       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    peak_surge      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    peak_percent    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    samples         INTEGER NOT NULL DEFAULT 0,
+    last_sample_at  TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_surge_events_device_start ON surge_events(device_id, started_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_surge_events_open ON surge_events(device_id) WHERE ended_at IS NULL;
+
+-- API key ingest HTTP terpisah dari secret HMAC; hanya hash SHA-256 yang
+-- disimpan. Kunci yang diterbitkan sebelum kolom ini ada perlu dirotasi
+-- agar bisa dipakai untuk ingest HTTP.
+ALTER TABLE device_ingest_keys ADD COLUMN IF NOT EXISTS api_key_hash VARCHAR(64);
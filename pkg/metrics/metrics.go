@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// Serve mengekspos metrik expvar di /debug/vars pada addr di background.
+// addr kosong menonaktifkan endpoint.
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		log.Printf("Metrics available at http://%s/debug/vars", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("[ERROR] Metrics server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,30 @@
+package utils
+
+// rolloverZone adalah porsi atas register; penurunan nilai dari zona ini
+// dianggap rollover, bukan reset counter.
+const rolloverZone = 0.9
+
+// RegisterDelta menghitung energi di antara dua pembacaan register kumulatif.
+// Jika register turun, dianggap rollover saat nilai sebelumnya sudah dekat
+// registerMax, selain itu dianggap reset sehingga energi dihitung dari nol.
+func RegisterDelta(previous, current, registerMax float64) float64 {
+	if current >= previous {
+		return current - previous
+	}
+
+	if registerMax > 0 && previous >= registerMax*rolloverZone {
+		return (registerMax - previous) + current
+	}
+
+	return current
+}
+
+// RegisterEnergy menjumlahkan RegisterDelta untuk deretan pembacaan register
+// yang sudah terurut waktu.
+func RegisterEnergy(registers []float64, registerMax float64) float64 {
+	var energy float64
+	for i := 1; i < len(registers); i++ {
+		energy += RegisterDelta(registers[i-1], registers[i], registerMax)
+	}
+	return energy
+}
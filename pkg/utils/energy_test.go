@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegisterDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		previous    float64
+		current     float64
+		registerMax float64
+		want        float64
+	}{
+		{name: "increase", previous: 100, current: 102.5, registerMax: 99999.9, want: 2.5},
+		{name: "unchanged", previous: 100, current: 100, registerMax: 99999.9, want: 0},
+		{name: "rollover near max", previous: 99999, current: 1, registerMax: 99999.9, want: 1.9},
+		{name: "rollover at zone boundary", previous: 90, current: 5, registerMax: 100, want: 15},
+		{name: "reset below rollover zone", previous: 500, current: 3, registerMax: 99999.9, want: 3},
+		{name: "reset without register max", previous: 99999, current: 1, registerMax: 0, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RegisterDelta(tt.previous, tt.current, tt.registerMax)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RegisterDelta(%v, %v, %v) = %v, want %v", tt.previous, tt.current, tt.registerMax, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterEnergy(t *testing.T) {
+	tests := []struct {
+		name        string
+		registers   []float64
+		registerMax float64
+		want        float64
+	}{
+		{name: "empty", registers: nil, registerMax: 100, want: 0},
+		{name: "single reading", registers: []float64{42}, registerMax: 100, want: 0},
+		{name: "monotonic", registers: []float64{10, 12, 15}, registerMax: 100, want: 5},
+		{name: "with rollover", registers: []float64{95, 99, 2, 4}, registerMax: 100, want: 9},
+		{name: "with reset", registers: []float64{50, 55, 1, 3}, registerMax: 100, want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RegisterEnergy(tt.registers, tt.registerMax)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RegisterEnergy(%v, %v) = %v, want %v", tt.registers, tt.registerMax, got, tt.want)
+			}
+		})
+	}
+}
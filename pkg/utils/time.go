@@ -4,6 +4,8 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"log"
+	"sync"
 	"time"
 )
 
@@ -159,6 +161,12 @@ func (t TimeData) StartOfMonth() TimeData {
 	return TimeData{Time: startUTC}
 }
 
+func (t TimeData) StartOfYear() TimeData {
+	utcTime := t.Time.UTC()
+	startUTC := time.Date(utcTime.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	return TimeData{Time: startUTC}
+}
+
 func TimeNow() TimeData {
 	nowUTC := time.Now().UTC()
 	return TimeData{Time: nowUTC}
@@ -198,6 +206,15 @@ func ParseDate(dateStr string) (TimeData, error) {
 	return TimeData{Time: parsedTime.UTC()}, nil
 }
 
+func ParseLayout(layout string, value string) (TimeData, error) {
+	parsedTime, err := time.Parse(layout, value)
+	if err != nil {
+		return TimeData{}, err
+	}
+
+	return TimeData{Time: parsedTime.UTC()}, nil
+}
+
 func NewTimeData(t time.Time) TimeData {
 	return TimeData{Time: t.UTC()}
 }
@@ -243,3 +260,86 @@ func TimeSince(t TimeData) time.Duration {
 	now := time.Now().UTC()
 	return now.Sub(t.Time.UTC())
 }
+
+var locationCache sync.Map
+
+// ParseLocation memuat zona waktu IANA (mis. "Asia/Jakarta"). Nama kosong
+// berarti UTC; nama yang tidak dikenal menghasilkan error.
+func ParseLocation(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+var invalidLocations sync.Map
+
+// LoadLocation seperti ParseLocation, tetapi zona yang tidak dikenal
+// menghasilkan UTC dan dicatat di log (sekali per nama). Input device dan
+// konfigurasi divalidasi dengan ParseLocation sehingga fallback ini hanya
+// terjadi untuk data lama atau tzdata yang tidak lengkap.
+func LoadLocation(name string) *time.Location {
+	loc, err := ParseLocation(name)
+	if err != nil {
+		if _, logged := invalidLocations.LoadOrStore(name, struct{}{}); !logged {
+			log.Printf("[ERROR] Unknown timezone %q, falling back to UTC: %v", name, err)
+		}
+		return time.UTC
+	}
+
+	return loc
+}
+
+// DateIn mengembalikan tanggal kalender t menurut zona loc, dalam bentuk
+// tengah malam UTC seperti yang disimpan di kolom DATE.
+func (t TimeData) DateIn(loc *time.Location) TimeData {
+	year, month, day := t.Time.In(loc).Date()
+	return TimeData{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// StartIn mengembalikan instant tengah malam lokal di zona loc untuk
+// tanggal kalender t (hasil DateIn atau ParseDateIn).
+func (t TimeData) StartIn(loc *time.Location) TimeData {
+	year, month, day := t.Time.UTC().Date()
+	return NewTimeData(time.Date(year, month, day, 0, 0, 0, 0, loc))
+}
+
+// StartOfDayIn mengembalikan instant awal hari lokal yang memuat t.
+func (t TimeData) StartOfDayIn(loc *time.Location) TimeData {
+	return t.DateIn(loc).StartIn(loc)
+}
+
+// TimeNowDailyIn mengembalikan tanggal kalender hari ini di zona loc.
+func TimeNowDailyIn(loc *time.Location) TimeData {
+	return TimeNow().DateIn(loc)
+}
+
+// ParseDateIn seperti ParseDate, tetapi nilai RFC3339 dikonversi ke tanggal
+// kalender di zona loc. Nilai "2006-01-02" dipakai apa adanya.
+func ParseDateIn(dateStr string, loc *time.Location) (TimeData, error) {
+	if dateStr == "" {
+		return TimeData{}, errors.New("date string is empty")
+	}
+
+	if parsedTime, err := time.Parse(time.RFC3339, dateStr); err == nil {
+		return NewTimeData(parsedTime).DateIn(loc), nil
+	}
+
+	parsedTime, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return TimeData{}, err
+	}
+
+	return TimeData{Time: parsedTime}, nil
+}
@@ -0,0 +1,51 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type AccessClaims struct {
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+// ParseAccessToken memvalidasi access token dari GenerateAccessToken dan
+// mengembalikan user id serta waktu kedaluwarsanya.
+func ParseAccessToken(tokenStr string) (*AccessClaims, error) {
+	parsed, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return nil, errors.New("token has no uid")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, errors.New("token has no expiry")
+	}
+
+	return &AccessClaims{
+		UserID:    int64(uid),
+		ExpiresAt: exp.Time,
+	}, nil
+}